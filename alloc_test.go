@@ -0,0 +1,26 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !sessdebug
+
+package sess_test
+
+import "testing"
+
+// newPairBytes is what New allocates: the pair in the 1792-byte size
+// class, two rings of four values and two of four labels. State that not
+// every endpoint needs belongs in the endpoint's out-of-line extension, so
+// that adding it leaves this unchanged.
+const newPairBytes = 1792 + 2*64 + 2*16
+
+func TestNewAllocation(t *testing.T) {
+	skipRace(t)
+	r := testing.Benchmark(BenchmarkNew)
+	if got := r.AllocedBytesPerOp(); got > newPairBytes {
+		t.Fatalf("New allocates %d bytes, want at most %d", got, newPairBytes)
+	}
+	if got := r.AllocsPerOp(); got != 5 {
+		t.Fatalf("New makes %d allocations, want 5", got)
+	}
+}
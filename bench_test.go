@@ -69,6 +69,19 @@ func BenchmarkExprSelectOffer(b *testing.B) {
 	}
 }
 
+// BenchmarkExprSelectOfferCases measures Expr-world labeled select/offer
+// round-trip with a dispatch table built once.
+func BenchmarkExprSelectOfferCases(b *testing.B) {
+	skipRace(b)
+	done := func() kont.Expr[struct{}] { return sess.ExprCloseDone(struct{}{}) }
+	cases := map[sess.Label]func() kont.Expr[struct{}]{0: done, 1: done, 2: done, 3: done, 4: done}
+	b.ReportAllocs()
+	for b.Loop() {
+		selector := sess.ExprSelectThen(3, sess.ExprCloseDone(struct{}{}))
+		sess.RunExpr[struct{}, struct{}](selector, sess.ExprOfferCases(cases))
+	}
+}
+
 // BenchmarkExprSendRecv measures Expr-world send/recv round-trip.
 func BenchmarkExprSendRecv(b *testing.B) {
 	skipRace(b)
@@ -82,6 +95,22 @@ func BenchmarkExprSendRecv(b *testing.B) {
 	}
 }
 
+// BenchmarkExprSendRecvTraced measures Expr-world send/recv with a global
+// tracer installed.
+func BenchmarkExprSendRecvTraced(b *testing.B) {
+	skipRace(b)
+	sess.SetTracer(&countingTracer{})
+	defer sess.SetTracer(nil)
+	b.ReportAllocs()
+	for b.Loop() {
+		sender := sess.ExprSendThen(42, sess.ExprCloseDone(struct{}{}))
+		receiver := sess.ExprRecvBind(func(n int) kont.Expr[int] {
+			return sess.ExprCloseDone(n)
+		})
+		sess.RunExpr[struct{}, int](sender, receiver)
+	}
+}
+
 // BenchmarkExprProtocol3Step measures Expr-world 3-step protocol.
 func BenchmarkExprProtocol3Step(b *testing.B) {
 	skipRace(b)
@@ -252,3 +281,15 @@ func BenchmarkStepAdvance(b *testing.B) {
 		_ = result
 	}
 }
+
+var sinkA, sinkB *sess.Endpoint
+
+// BenchmarkNew measures creating a pair: one allocation for the endpoints,
+// queues and state, and one for each ring (see TestNewAllocation).
+func BenchmarkNew(b *testing.B) {
+	skipRace(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		sinkA, sinkB = sess.New()
+	}
+}
@@ -0,0 +1,147 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"code.hybscloud.com/iox"
+)
+
+// Clock is the time source of an endpoint: timed operations read
+// deadlines from Now, and the blocking paths (Exec, ExecContext and the
+// network transport) wait between attempts with Sleep. Endpoints use the
+// system clock unless SetClock injects another one.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// SetClock makes ep read the time from c and wait on it; nil restores the
+// system clock. It must not be called while an operation on ep is pending.
+func (ep *Endpoint) SetClock(c Clock) {
+	ep.ctx.extend().clock = c
+}
+
+// WithClock sets the clock of both endpoints; see Endpoint.SetClock.
+// With RunWithOptions it is also the clock the run backs off on, so a
+// VirtualClock runs the whole session in simulated time.
+func WithClock(c Clock) Option {
+	return func(o *options) { o.clock = c }
+}
+
+// now returns the current time of ctx's clock.
+func (ctx *sessionContext) now() time.Time {
+	if ctx.ext.clock != nil {
+		return ctx.ext.clock.Now()
+	}
+	return time.Now()
+}
+
+// VirtualClock is a Clock whose time only moves when told to. Sleep
+// advances it instead of blocking, so a protocol waiting on a virtual
+// clock makes simulated time pass as fast as it polls: timeouts and
+// backoff play out without real delays, and a test observes the same
+// deadlines on every run. A VirtualClock is safe for concurrent use.
+type VirtualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewVirtualClock returns a VirtualClock reading start.
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+// Now returns the simulated time.
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep advances the clock by d and yields the processor, so that peers
+// on other goroutines can progress.
+func (c *VirtualClock) Sleep(d time.Duration) {
+	c.Advance(d)
+	runtime.Gosched()
+}
+
+// Advance moves the clock forward by d. A negative d is ignored.
+func (c *VirtualClock) Advance(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// AdvanceTo moves the clock forward to t, if t is later than its time.
+func (c *VirtualClock) AdvanceTo(t time.Time) {
+	c.mu.Lock()
+	if t.After(c.now) {
+		c.now = t
+	}
+	c.mu.Unlock()
+}
+
+// backoff waits past iox.ErrWouldBlock. On the system clock it is
+// iox.Backoff; on an injected Clock it sleeps on that clock for the
+// same linearly growing durations, without jitter. With metrics set, it
+// counts its sleeps and measures how long the operation stayed blocked.
+type backoff struct {
+	bo      iox.Backoff
+	clock   Clock
+	n, i    int
+	metrics *Metrics
+	since   time.Time // first sleep for the current operation
+}
+
+// Wait sleeps for the next backoff duration.
+func (b *backoff) Wait() {
+	if b.metrics != nil {
+		b.metrics.backoffWaits.Add(1)
+		if b.since.IsZero() {
+			b.since = b.now()
+		}
+	}
+	if b.clock == nil {
+		b.bo.Wait()
+		return
+	}
+	if b.n == 0 {
+		b.n = 1
+	}
+	b.clock.Sleep(min(time.Duration(b.n)*iox.DefaultBackoffBase, iox.DefaultBackoffMax))
+	if b.i++; b.i >= b.n {
+		b.i = 0
+		b.n++
+	}
+}
+
+// done records that the operation waited on has completed.
+func (b *backoff) done() {
+	if b.metrics == nil || b.since.IsZero() {
+		return
+	}
+	b.metrics.observeBlocked(b.now().Sub(b.since))
+	b.since = time.Time{}
+}
+
+func (b *backoff) now() time.Time {
+	if b.clock != nil {
+		return b.clock.Now()
+	}
+	return time.Now()
+}
+
+// Reset restarts the backoff from its shortest duration.
+func (b *backoff) Reset() {
+	b.bo.Reset()
+	b.n, b.i = 0, 0
+}
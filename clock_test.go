@@ -0,0 +1,147 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+func TestVirtualClock(t *testing.T) {
+	start := time.Unix(100, 0)
+	c := sess.NewVirtualClock(start)
+	c.Advance(time.Second)
+	c.Sleep(time.Second)
+	c.Advance(-time.Hour)
+	if got := c.Now(); !got.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("got %v, want start+2s", got)
+	}
+	c.AdvanceTo(start)
+	if got := c.Now(); !got.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("AdvanceTo moved the clock back to %v", got)
+	}
+	c.AdvanceTo(start.Add(time.Minute))
+	if got := c.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Fatalf("got %v, want start+1m", got)
+	}
+}
+
+func TestExecWaitsOnVirtualClock(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := sess.NewVirtualClock(start)
+	_, epB := sess.New()
+	epB.SetClock(clock)
+
+	began := time.Now()
+	got := sess.Exec(epB, sess.RecvWithinBind(time.Hour, func(int) kont.Eff[bool] {
+		return kont.Pure(false)
+	}, func(sess.Timeout) kont.Eff[bool] {
+		return kont.Pure(true)
+	}))
+	if !got {
+		t.Fatal("received from a peer that never sent")
+	}
+	if elapsed := clock.Now().Sub(start); elapsed < time.Hour {
+		t.Fatalf("timed out after %v of simulated time", elapsed)
+	}
+	if real := time.Since(began); real > 10*time.Second {
+		t.Fatalf("waited %v of real time", real)
+	}
+}
+
+func TestRunWithClock(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := sess.NewVirtualClock(start)
+	client := sess.RecvWithinBind(time.Hour, func(s string) kont.Eff[string] {
+		return sess.CloseDone(s)
+	}, func(sess.Timeout) kont.Eff[string] {
+		return sess.SendThen(1, sess.CloseDone("timed out"))
+	})
+	server := sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })
+
+	began := time.Now()
+	a, b := sess.RunWithOptions(client, server, sess.WithClock(clock))
+	if a != "timed out" || b != 1 {
+		t.Fatalf("got %q/%d", a, b)
+	}
+	if elapsed := clock.Now().Sub(start); elapsed < time.Hour {
+		t.Fatalf("timed out after %v of simulated time", elapsed)
+	}
+	if real := time.Since(began); real > 10*time.Second {
+		t.Fatalf("waited %v of real time", real)
+	}
+
+	ra, rb := sess.RunErrorWithOptions[string](client, server, sess.WithClock(clock))
+	if va, _ := ra.GetRight(); va != "timed out" {
+		t.Fatalf("RunErrorWithOptions client got %v", ra)
+	}
+	if vb, _ := rb.GetRight(); vb != 1 {
+		t.Fatalf("RunErrorWithOptions server got %v", rb)
+	}
+	if elapsed := clock.Now().Sub(start); elapsed < 2*time.Hour {
+		t.Fatalf("second run timed out after %v of simulated time", elapsed-time.Hour)
+	}
+}
+
+func TestSimSchedulerTimeout(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := sess.NewVirtualClock(start)
+	s := sess.NewSimScheduler(clock, 1)
+	epA, epB := sess.New()
+	var got string
+	sess.Spawn(s, epA, sess.RecvWithinBind(time.Hour, func(string) kont.Eff[string] {
+		return sess.CloseDone("greeted")
+	}, func(t sess.Timeout) kont.Eff[string] {
+		return sess.SendThen(1, sess.CloseDone(t.String()))
+	}), func(r string, err error) { got = r })
+	sess.Spawn(s, epB, sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) }), nil)
+
+	if pending := s.RunUntilIdle(); pending != 0 {
+		t.Fatalf("RunUntilIdle left %d pending", pending)
+	}
+	if got != "Recv timed out after 1h0m0s" {
+		t.Fatalf("got %q", got)
+	}
+	// The clock jumped to the deadline and no further.
+	if now := clock.Now(); !now.Equal(start.Add(time.Hour)) {
+		t.Fatalf("clock at %v, want start+1h", now.Sub(start))
+	}
+}
+
+// simOrder runs several sessions on a simulation Scheduler and returns
+// the order in which they completed.
+func simOrder(seed uint64) []int {
+	s := sess.NewSimScheduler(sess.NewVirtualClock(time.Unix(0, 0)), seed)
+	var order []int
+	for i := range 8 {
+		epA, epB := sess.New()
+		sess.Spawn(s, epA, sess.SendThen(i, sess.SendThen(i, sess.CloseDone(struct{}{}))), nil)
+		sess.Spawn(s, epB, sess.RecvBind(func(n int) kont.Eff[int] {
+			return sess.RecvBind(func(int) kont.Eff[int] { return sess.CloseDone(n) })
+		}), func(n int, err error) { order = append(order, n) })
+	}
+	s.RunUntilIdle()
+	return order
+}
+
+func TestSimSchedulerSeeded(t *testing.T) {
+	first := simOrder(42)
+	if len(first) != 8 {
+		t.Fatalf("completed %d sessions, want 8", len(first))
+	}
+	if again := simOrder(42); !slices.Equal(first, again) {
+		t.Fatalf("seed 42 gave %v, then %v", first, again)
+	}
+	for seed := range uint64(16) {
+		if !slices.Equal(simOrder(seed), first) {
+			return
+		}
+	}
+	t.Fatalf("every seed gave the order %v", first)
+}
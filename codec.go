@@ -0,0 +1,182 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+// Codec serializes Send payloads for a network endpoint.
+// Encode is called with the value passed to Send; Decode is called with
+// a non-nil *T, where T is the type the receiver asked for in Recv[T].
+// A Codec must be safe for concurrent use.
+type Codec interface {
+	Encode(v any) ([]byte, error)
+	Decode(data []byte, ptr any) error
+}
+
+// GobCodec encodes each value as a self-describing encoding/gob stream.
+// It is the default Codec of network endpoints.
+type GobCodec struct{}
+
+// Encode implements Codec.
+func (GobCodec) Encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode implements Codec.
+func (GobCodec) Decode(data []byte, ptr any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(ptr)
+}
+
+// BinaryCodec encodes fixed-size values (numbers, bools, and arrays or
+// structs of them, as accepted by encoding/binary) and slices of them, which
+// are prefixed with their length as a uvarint. Order defaults to little
+// endian.
+type BinaryCodec struct {
+	Order binary.ByteOrder
+}
+
+func (c BinaryCodec) order() binary.ByteOrder {
+	if c.Order == nil {
+		return binary.LittleEndian
+	}
+	return c.Order
+}
+
+// Encode implements Codec.
+func (c BinaryCodec) Encode(v any) ([]byte, error) {
+	var data []byte
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		data = binary.AppendUvarint(nil, uint64(rv.Len()))
+	}
+	return binary.Append(data, c.order(), v)
+}
+
+// Decode implements Codec. The payload must be consumed exactly.
+func (c BinaryCodec) Decode(data []byte, ptr any) error {
+	if p := reflect.ValueOf(ptr); p.Kind() == reflect.Pointer && p.Elem().Kind() == reflect.Slice {
+		n, k := binary.Uvarint(data)
+		if k <= 0 {
+			return errors.New("sess: malformed slice length in binary payload")
+		}
+		data = data[k:]
+		size := binary.Size(reflect.Zero(p.Type().Elem().Elem()).Interface())
+		if size <= 0 {
+			return errors.New("sess: binary.Decode: invalid type " + p.Type().Elem().String())
+		}
+		if n > uint64(len(data)/size) {
+			return errors.New("sess: slice length " + strconv.FormatUint(n, 10) + " exceeds binary payload")
+		}
+		s := reflect.MakeSlice(p.Type().Elem(), int(n), int(n))
+		if err := c.decode(data, s.Interface()); err != nil {
+			return err
+		}
+		p.Elem().Set(s)
+		return nil
+	}
+	return c.decode(data, ptr)
+}
+
+// decode decodes data into ptr, which may be a slice, consuming it exactly.
+func (c BinaryCodec) decode(data []byte, ptr any) error {
+	n, err := binary.Decode(data, c.order(), ptr)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return errors.New("sess: " + strconv.Itoa(len(data)-n) + " trailing bytes in binary payload")
+	}
+	return nil
+}
+
+// JSONCodec encodes values with encoding/json.
+type JSONCodec struct{}
+
+// Encode implements Codec.
+func (JSONCodec) Encode(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Decode implements Codec.
+func (JSONCodec) Decode(data []byte, ptr any) error {
+	return json.Unmarshal(data, ptr)
+}
+
+// BytesCodec passes []byte payloads through without copying: the sent
+// slice is written to the connection as is, and the receiver gets the
+// frame buffer, which it owns. The sender must not modify a slice after
+// sending it. Any other payload type is rejected.
+type BytesCodec struct{}
+
+// errNotBytes is returned by BytesCodec for payloads other than []byte.
+var errNotBytes = errors.New("sess: BytesCodec payload is not []byte")
+
+// Encode implements Codec.
+func (BytesCodec) Encode(v any) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, errNotBytes
+	}
+	return b, nil
+}
+
+// Decode implements Codec.
+func (BytesCodec) Decode(data []byte, ptr any) error {
+	p, ok := ptr.(*[]byte)
+	if !ok {
+		return errNotBytes
+	}
+	*p = data
+	return nil
+}
+
+// CodecError reports a payload that a network endpoint's Codec failed
+// to encode or decode.
+type CodecError struct {
+	Type reflect.Type
+	Err  error
+}
+
+// Error implements error.
+func (e *CodecError) Error() string {
+	name := "<nil>"
+	if e.Type != nil {
+		name = e.Type.String()
+	}
+	return "sess: codec " + name + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying codec error.
+func (e *CodecError) Unwrap() error { return e.Err }
+
+// TypeIDError is returned by a Registry codec when a payload's type ID
+// does not match the type the receiver asked for. A network endpoint
+// reports it from Recv as a *ProtocolViolation, as an in-process
+// endpoint reports a value of the wrong type.
+type TypeIDError struct {
+	ID       TypeID
+	Expected reflect.Type
+	Actual   reflect.Type // registered type of ID; nil if unknown
+}
+
+// Error implements error.
+func (e *TypeIDError) Error() string {
+	actual := "unknown type"
+	if e.Actual != nil {
+		actual = e.Actual.String()
+	}
+	return "sess: payload type ID " + strconv.FormatUint(uint64(e.ID), 10) + " (" + actual + ") is not " + e.Expected.String()
+}
@@ -0,0 +1,203 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+type codecPoint struct {
+	X, Y int32
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for name, c := range map[string]sess.Codec{
+		"gob":        sess.GobCodec{},
+		"json":       sess.JSONCodec{},
+		"binary":     sess.BinaryCodec{},
+		"binary-big": sess.BinaryCodec{Order: binary.BigEndian},
+	} {
+		data, err := c.Encode(codecPoint{3, -4})
+		if err != nil {
+			t.Fatalf("%s: Encode: %v", name, err)
+		}
+		var p codecPoint
+		if err := c.Decode(data, &p); err != nil {
+			t.Fatalf("%s: Decode: %v", name, err)
+		}
+		if p != (codecPoint{3, -4}) {
+			t.Fatalf("%s: got %+v", name, p)
+		}
+	}
+}
+
+func TestBinaryCodecRejects(t *testing.T) {
+	c := sess.BinaryCodec{}
+	if _, err := c.Encode("not fixed size"); err == nil {
+		t.Fatal("expected Encode error for string")
+	}
+	var n int32
+	if err := c.Decode([]byte{1, 2, 3, 4, 5}, &n); err == nil {
+		t.Fatal("expected Decode error for trailing bytes")
+	}
+}
+
+func TestBinaryCodecSlice(t *testing.T) {
+	c := sess.BinaryCodec{Order: binary.BigEndian}
+	for _, in := range [][]codecPoint{{{1, 2}, {3, -4}}, {}} {
+		data, err := c.Encode(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []codecPoint
+		if err := c.Decode(data, &out); err != nil || !reflect.DeepEqual(out, in) {
+			t.Fatalf("Decode got %v, %v, want %v", out, err, in)
+		}
+	}
+	var out []int32
+	if err := c.Decode([]byte{200, 1, 0, 0, 0, 0}, &out); err == nil {
+		t.Fatal("expected Decode error for a length beyond the payload")
+	}
+
+	// A slice sent under WithCodec arrives whole.
+	_, got := sess.RunWithOptions(
+		sess.SendThen([]int32{1, 2, 3}, sess.CloseDone(struct{}{})),
+		sess.RecvBind(func(v []int32) kont.Eff[[]int32] { return sess.CloseDone(v) }),
+		sess.WithCodec(sess.BinaryCodec{}),
+	)
+	if !reflect.DeepEqual(got, []int32{1, 2, 3}) {
+		t.Fatalf("received %v", got)
+	}
+}
+
+func TestBytesCodec(t *testing.T) {
+	c := sess.BytesCodec{}
+	in := []byte("raw")
+	data, err := c.Encode(in)
+	if err != nil || &data[0] != &in[0] {
+		t.Fatalf("Encode must pass the slice through: %v", err)
+	}
+	var out []byte
+	if err := c.Decode(data, &out); err != nil || !bytes.Equal(out, in) {
+		t.Fatalf("Decode got %q, %v", out, err)
+	}
+	if _, err := c.Encode(1); err == nil {
+		t.Fatal("expected Encode error for int")
+	}
+	var n int
+	if err := c.Decode(data, &n); err == nil {
+		t.Fatal("expected Decode error for *int")
+	}
+}
+
+func TestRegistryCodec(t *testing.T) {
+	reg := sess.NewRegistry()
+	sess.Register[codecPoint](reg, 1)
+	sess.Register[string](reg, 2)
+	c := reg.Codec(sess.JSONCodec{})
+
+	if id, ok := reg.ID(reflect.TypeFor[codecPoint]()); !ok || id != 1 {
+		t.Fatalf("ID got %d, %v", id, ok)
+	}
+	if typ, ok := reg.Type(2); !ok || typ != reflect.TypeFor[string]() {
+		t.Fatalf("Type got %v, %v", typ, ok)
+	}
+
+	data, err := c.Encode("hello")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var p codecPoint
+	var tie *sess.TypeIDError
+	if err := c.Decode(data, &p); !errors.As(err, &tie) || tie.ID != 2 || tie.Actual != reflect.TypeFor[string]() {
+		t.Fatalf("Decode got %v, want *TypeIDError for string", err)
+	}
+	var s string
+	if err := c.Decode(data, &s); err != nil || s != "hello" {
+		t.Fatalf("Decode got %q, %v", s, err)
+	}
+	if _, err := c.Encode(1.5); err == nil {
+		t.Fatal("expected Encode error for unregistered type")
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	reg := sess.NewRegistry()
+	sess.Register[int](reg, 1)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for duplicate type ID")
+		}
+	}()
+	sess.Register[string](reg, 1)
+}
+
+func TestConnRegistryTypeMismatch(t *testing.T) {
+	skipRace(t)
+	reg := sess.NewRegistry()
+	sess.Register[int32](reg, 1)
+	sess.Register[codecPoint](reg, 2)
+	codec := reg.Codec(sess.BinaryCodec{})
+	c1, c2 := net.Pipe()
+	epA, epB := sess.DialConn(c1, codec), sess.AcceptConn(c2, codec)
+
+	go sess.ExecContext(context.Background(), epA, sess.SendThen(int32(7), sess.CloseDone(struct{}{})))
+	_, err := sess.ExecContext(context.Background(), epB, sess.RecvBind(func(p codecPoint) kont.Eff[codecPoint] {
+		return sess.CloseDone(p)
+	}))
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) {
+		t.Fatalf("got %v, want *ProtocolViolation", err)
+	}
+	if pv.ExpectedType != reflect.TypeFor[codecPoint]() || pv.ActualType != reflect.TypeFor[int32]() {
+		t.Fatalf("unexpected violation: %v", pv)
+	}
+}
+
+// codecShape is a payload interface; codecPoint implements it.
+type codecShape interface{ area() int32 }
+
+func (p codecPoint) area() int32 { return p.X * p.Y }
+
+func TestConnRegistryInterface(t *testing.T) {
+	skipRace(t)
+	gob.Register(codecPoint{})
+	reg := sess.NewRegistry()
+	sess.Register[codecShape](reg, 1)
+	codec := reg.Codec(sess.GobCodec{})
+	c1, c2 := net.Pipe()
+	epA, epB := sess.DialConn(c1, codec), sess.AcceptConn(c2, codec)
+
+	// Both sides key the type ID on codecShape, not on codecPoint.
+	go sess.ExecContext(context.Background(), epA, sess.SendThen(codecShape(codecPoint{X: 3, Y: 4}), sess.CloseDone(struct{}{})))
+	got, err := sess.ExecContext(context.Background(), epB, sess.RecvBind(func(s codecShape) kont.Eff[int32] {
+		return sess.CloseDone(s.area())
+	}))
+	if err != nil || got != 12 {
+		t.Fatalf("got %d, %v, want 12", got, err)
+	}
+}
+
+func TestConnBytesCodec(t *testing.T) {
+	skipRace(t)
+	c1, c2 := net.Pipe()
+	epA, epB := sess.DialConn(c1, sess.BytesCodec{}), sess.AcceptConn(c2, sess.BytesCodec{})
+
+	go sess.Exec(epA, sess.SendThen([]byte("payload"), sess.CloseDone(struct{}{})))
+	got := sess.Exec(epB, sess.RecvBind(func(b []byte) kont.Eff[[]byte] { return sess.CloseDone(b) }))
+	if string(got) != "payload" {
+		t.Fatalf("got %q, want payload", got)
+	}
+}
@@ -0,0 +1,537 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"code.hybscloud.com/kont"
+)
+
+// Wire frame kinds. Every frame is a kind byte followed by a 4-byte
+// big-endian payload length and the payload; only data, abort, labeled
+// select, delegation and throw frames carry a payload. A labeled select
+// carries the 4-byte big-endian label; SelectL and SelectR keep their own
+// kinds. A delegation frame carries a JSON wireHandoff and, over a Unix
+// socket, the delegated connection as SCM_RIGHTS ancillary data. A throw
+// frame is the abort frame of a protocol that ended with a thrown error,
+// carrying the error's text and its value encoded with the codec. A hello
+// frame, which the dialing side writes before any other, carries the
+// 8-byte big-endian session ID both sides report in their trace events.
+const (
+	frameData byte = iota + 1
+	frameSelectL
+	frameSelectR
+	frameClose
+	frameAbort
+	frameSelect
+	frameDelegate
+	frameThrow
+	frameHello
+)
+
+// maxFrameSize bounds the payload length accepted from the peer.
+const maxFrameSize = 64 << 20
+
+// abortWriteTimeout bounds how long an aborted endpoint keeps trying
+// to deliver its abort frame before the connection is closed.
+const abortWriteTimeout = time.Second
+
+// wireSignal is a non-data frame queued on a network endpoint's send queue.
+// Queuing choices and Close behind data keeps frames in program order.
+type wireSignal byte
+
+// wireLabel is a labeled choice queued on a network endpoint's send queue.
+type wireLabel Label
+
+// wireSelectL, wireSelectR and wireClose are pre-boxed signal values.
+var (
+	wireSelectL any = wireSignal(frameSelectL)
+	wireSelectR any = wireSignal(frameSelectR)
+	wireClose   any = wireSignal(frameClose)
+)
+
+// wireLink bridges a network endpoint's queues and its connection.
+// The writer goroutine is the consumer of sendQ; the reader goroutine
+// is the producer of recvQ and awaitQ. Operations stay non-blocking:
+// they only touch the queues, as on an in-process endpoint.
+type wireLink struct {
+	conn       net.Conn
+	wake       chan struct{} // kicks the writer after an enqueue
+	aborted    chan struct{} // closed once the session is aborted
+	writerDone chan struct{}
+	readerDone chan struct{}
+	session    atomic.Uint64 // shared session ID; 0 until the hello frame arrives
+	hello      bool          // the writer starts with a hello frame
+	remote     atomic.Bool   // the abort was received from the peer
+	detaching  atomic.Bool   // the endpoint is being delegated (see detach)
+	pending    []byte        // bytes to read before conn, for a delegated endpoint
+	rest       []byte        // bytes read but not delivered when detached
+}
+
+// kick wakes the writer without blocking.
+func (l *wireLink) kick() {
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// wireEndpoint holds a network endpoint, its local queues, and its link
+// in a single allocation.
+type wireEndpoint struct {
+	ep     Endpoint
+	state  sessionState
+	sendQ  queue[any]
+	recvQ  queue[any]
+	awaitQ queue[Label]
+	link   wireLink
+}
+
+// Dial connects to address on the named network and returns the
+// initiating endpoint of a session over the connection. See DialConn.
+func Dial(network, address string, codec Codec) (*Endpoint, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return DialConn(conn, codec), nil
+}
+
+// Accept waits for the next connection on ln and returns the accepting
+// endpoint of a session over it. See AcceptConn.
+func Accept(ln net.Listener, codec Codec) (*Endpoint, error) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return AcceptConn(conn, codec), nil
+}
+
+// DialConn returns the initiating endpoint of a session over conn;
+// the peer must use AcceptConn (or Accept) on the other end. Each
+// operation is framed onto conn, with Send payloads serialized by codec
+// (GobCodec if nil).
+//
+// The endpoint takes ownership of conn: it is closed once both sides have
+// closed the session, or when it is aborted. Operations keep the
+// non-blocking contract of New, returning iox.ErrWouldBlock while the
+// peer has not yet produced or the local queues are full. A connection
+// failure aborts the session with the I/O error as reason. Each side
+// tracks State locally, observing the peer's Close or Abort once its
+// frame has arrived. The dialing side first sends a random session ID,
+// which both sides report as TraceEvent.Session; until it has arrived,
+// operations on the accepting side return iox.ErrWouldBlock.
+//
+// On Unix systems, when conn is a *net.UnixConn, network endpoints can be
+// delegated to the peer process, with Send of a *Endpoint or with
+// DelegateThen: the connection of the delegated endpoint, which must be
+// backed by a file such as a *net.TCPConn or *net.UnixConn, is passed as
+// SCM_RIGHTS ancillary data together with everything received on it but
+// not yet consumed. The receiving process continues the session where it
+// was left, serializing its payloads with its own codec. Other endpoints
+// cannot be delegated over a connection.
+func DialConn(conn net.Conn, codec Codec) *Endpoint {
+	return newWireEndpoint(conn, codec, stateClosedA, nil)
+}
+
+// AcceptConn returns the accepting endpoint of a session over conn.
+// See DialConn.
+func AcceptConn(conn net.Conn, codec Codec) *Endpoint {
+	return newWireEndpoint(conn, codec, stateClosedB, nil)
+}
+
+// newWireEndpoint starts an endpoint over conn. h is non-nil for an
+// endpoint delegated from another process.
+func newWireEndpoint(conn net.Conn, codec Codec, closeBit uint32, h *wireHandoff) *Endpoint {
+	if codec == nil {
+		codec = GobCodec{}
+	}
+	w := &wireEndpoint{}
+	w.sendQ.Init(channelCapacity)
+	w.recvQ.Init(channelCapacity)
+	w.awaitQ.Init(channelCapacity)
+	w.link = wireLink{
+		conn:       conn,
+		wake:       make(chan struct{}, 1),
+		aborted:    make(chan struct{}),
+		writerDone: make(chan struct{}),
+		readerDone: make(chan struct{}),
+	}
+	w.ep = Endpoint{
+		ctx: sessionContext{
+			sendQ:    &w.sendQ,
+			recvQ:    &w.recvQ,
+			awaitQ:   &w.awaitQ,
+			state:    &w.state,
+			serial:   nextSerial(),
+			ext:      &sessionExt{wire: &w.link, codec: codec, owner: newOwnership(linearityChecks.Load())},
+			closeBit: closeBit,
+		},
+	}
+	if h != nil {
+		w.link.pending = h.Pending
+		w.link.session.Store(h.Session)
+		w.ep.ctx.step = h.Step
+	} else if closeBit == stateClosedA {
+		w.link.session.Store(newSessionID())
+		w.link.hello = true
+	}
+	ctx := &w.ep.ctx
+	go w.link.writeLoop(ctx)
+	go w.link.readLoop(ctx)
+	go w.link.closeLoop()
+	return &w.ep
+}
+
+// newSessionID returns a random non-zero session ID.
+func newSessionID() uint64 {
+	for {
+		if id := rand.Uint64(); id != 0 {
+			return id
+		}
+	}
+}
+
+// wireSend encodes v, sent as type t, and queues it as a data frame, or
+// queues the delegation of v if it is an endpoint.
+func wireSend(ctx *sessionContext, t reflect.Type, v any) (kont.Resumed, error) {
+	if ep, ok := v.(*Endpoint); ok {
+		return wireDelegate(ctx, ep, endpointType)
+	}
+	data, err := encodePayload(ctx, t, v)
+	if err != nil {
+		return nil, err
+	}
+	return wireQueue(ctx, data)
+}
+
+// typedEncoder is implemented by codecs that encode a payload according
+// to the type it was sent as rather than its dynamic type.
+type typedEncoder interface {
+	encodeAs(t reflect.Type, v any) ([]byte, error)
+}
+
+// encodePayload encodes a Send payload, sent as type t, with ctx's codec.
+func encodePayload(ctx *sessionContext, t reflect.Type, v any) ([]byte, error) {
+	var data []byte
+	var err error
+	if te, ok := ctx.ext.codec.(typedEncoder); ok {
+		data, err = te.encodeAs(t, v)
+	} else {
+		data, err = ctx.ext.codec.Encode(v)
+	}
+	if err != nil {
+		return nil, &CodecError{Type: t, Err: err}
+	}
+	return data, nil
+}
+
+// wireQueue queues a data payload or signal for the writer.
+func wireQueue(ctx *sessionContext, v any) (kont.Resumed, error) {
+	ctx.sendSlot = v
+	if err := ctx.sendQ.Enqueue(&ctx.sendSlot); err != nil {
+		return nil, err
+	}
+	ctx.ext.wire.kick()
+	return struct{}{}, nil
+}
+
+// decodePayload decodes a received data payload into a T with ctx's
+// codec. A *TypeIDError from the codec is reported as a
+// *ProtocolViolation.
+func decodePayload[T any](ctx *sessionContext, v any) (kont.Resumed, error) {
+	var t T
+	if err := ctx.ext.codec.Decode(v.([]byte), &t); err != nil {
+		var tie *TypeIDError
+		if errors.As(err, &tie) {
+			return nil, &ProtocolViolation{
+				Expected:     OpRecv,
+				Actual:       OpRecv,
+				ExpectedType: tie.Expected,
+				ActualType:   tie.Actual,
+				Step:         ctx.step,
+				Serial:       ctx.serial,
+			}
+		}
+		return nil, &CodecError{Type: reflect.TypeFor[T](), Err: err}
+	}
+	return t, nil
+}
+
+// writeLoop drains sendQ onto the connection in order, flushing whenever
+// the queue runs empty. It returns after writing the close frame, after
+// an abort (sending the reason to the peer unless it came from the peer),
+// on a write error, or once the queue is drained when detaching.
+func (l *wireLink) writeLoop(ctx *sessionContext) {
+	defer close(l.writerDone)
+	bw := bufio.NewWriter(l.conn)
+	if l.hello {
+		var id [8]byte
+		binary.BigEndian.PutUint64(id[:], l.session.Load())
+		err := writeFrame(bw, frameHello, id[:])
+		if err == nil {
+			err = bw.Flush()
+		}
+		if err != nil {
+			// A connection lost this early is better reported by the
+			// reader, which sees why.
+			select {
+			case <-l.readerDone:
+			case <-time.After(abortWriteTimeout):
+			}
+			ctx.abort(err)
+			return
+		}
+	}
+	for {
+		if ctx.state.bits.LoadAcquire()&stateAborted != 0 {
+			if !l.remote.Load() {
+				kind, payload := frameAbort, []byte(nil)
+				switch r := ctx.state.abort.Load().Reason.(type) {
+				case *thrownError:
+					kind, payload = frameThrow, encodeThrown(ctx, r)
+				case nil:
+				default:
+					payload = []byte(r.Error())
+				}
+				if writeFrame(bw, kind, payload) == nil {
+					bw.Flush()
+				}
+			}
+			return
+		}
+		v, err := ctx.sendQ.Dequeue()
+		if err != nil {
+			if bw.Buffered() > 0 {
+				if err := bw.Flush(); err != nil {
+					ctx.abort(err)
+					return
+				}
+			}
+			if l.detaching.Load() {
+				return
+			}
+			select {
+			case <-l.wake:
+			case <-l.aborted:
+			}
+			continue
+		}
+		ctx.ready().notifyWritable()
+		switch v := v.(type) {
+		case []byte:
+			err = writeFrame(bw, frameData, v)
+		case wireSignal:
+			err = writeFrame(bw, byte(v), nil)
+			if err == nil && byte(v) == frameClose {
+				err = bw.Flush()
+				if err == nil {
+					return
+				}
+			}
+		case wireLabel:
+			var label [4]byte
+			binary.BigEndian.PutUint32(label[:], uint32(v))
+			err = writeFrame(bw, frameSelect, label[:])
+		case wireDelegation:
+			err = l.writeDelegation(bw, v)
+		}
+		if err != nil {
+			ctx.abort(err)
+			return
+		}
+	}
+}
+
+// readLoop routes frames from the connection into recvQ and awaitQ.
+// It returns after the peer's close or abort frame, or on a read error,
+// which aborts the session unless it is already aborted. When detaching,
+// it keeps the bytes read but not delivered in rest and returns.
+func (l *wireLink) readLoop(ctx *sessionContext) {
+	defer close(l.readerDone)
+	src := newLinkReader(l.conn)
+	fds, _ := src.(fdSource)
+	if fds != nil {
+		defer fds.closeFDs()
+	}
+	var r io.Reader = src
+	if len(l.pending) > 0 {
+		r = io.MultiReader(bytes.NewReader(l.pending), src)
+		l.pending = nil
+	}
+	br := bufio.NewReader(r)
+	var header [5]byte
+	for {
+		if n, err := io.ReadFull(br, header[:]); err != nil {
+			if l.detaching.Load() {
+				l.keep(br, header[:n])
+				return
+			}
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			ctx.abort(err)
+			return
+		}
+		n := binary.BigEndian.Uint32(header[1:])
+		if n > maxFrameSize {
+			ctx.abort(errors.New("sess: frame of " + strconv.FormatUint(uint64(n), 10) + " bytes exceeds limit"))
+			return
+		}
+		var payload []byte
+		if n > 0 {
+			payload = make([]byte, n)
+			if m, err := io.ReadFull(br, payload); err != nil {
+				if l.detaching.Load() {
+					l.keep(br, header[:], payload[:m])
+					return
+				}
+				ctx.abort(err)
+				return
+			}
+		}
+		switch header[0] {
+		case frameData:
+			if !deliver(ctx, ctx.recvQ, any(payload)) {
+				l.keep(br, header[:], payload)
+				return
+			}
+			ctx.ready().notifyReadable()
+		case frameSelectL:
+			if !deliver(ctx, ctx.awaitQ, LabelLeft) {
+				l.keep(br, header[:])
+				return
+			}
+			ctx.ready().notifyReadable()
+		case frameSelectR:
+			if !deliver(ctx, ctx.awaitQ, LabelRight) {
+				l.keep(br, header[:])
+				return
+			}
+			ctx.ready().notifyReadable()
+		case frameSelect:
+			if len(payload) != 4 {
+				ctx.abort(errors.New("sess: malformed select frame"))
+				return
+			}
+			if !deliver(ctx, ctx.awaitQ, Label(binary.BigEndian.Uint32(payload))) {
+				l.keep(br, header[:], payload)
+				return
+			}
+			ctx.ready().notifyReadable()
+		case frameHello:
+			if len(payload) != 8 || binary.BigEndian.Uint64(payload) == 0 {
+				ctx.abort(errors.New("sess: malformed hello frame"))
+				return
+			}
+			l.session.Store(binary.BigEndian.Uint64(payload))
+			ctx.ready().notifyAll()
+		case frameClose:
+			ctx.state.bits.Or(ctx.peerCloseBit())
+			ctx.ready().notifyAll()
+			return
+		case frameAbort:
+			var reason error
+			if len(payload) > 0 {
+				reason = errors.New(string(payload))
+			}
+			l.remote.Store(true)
+			ctx.abort(reason)
+			return
+		case frameThrow:
+			t, err := decodeThrown(payload)
+			if err != nil {
+				ctx.abort(err)
+				return
+			}
+			l.remote.Store(true)
+			ctx.abort(t)
+			return
+		case frameDelegate:
+			d, err := adopt(ctx, fds, payload)
+			if err != nil {
+				ctx.abort(err)
+				return
+			}
+			if !deliver(ctx, ctx.recvQ, any(d)) {
+				// The descriptor cannot be framed again; the delegated
+				// session is lost with the endpoint carrying it.
+				d.ep.Abort(ErrEndpointMoved)
+				l.keep(br)
+				return
+			}
+			ctx.ready().notifyReadable()
+		default:
+			ctx.abort(errors.New("sess: unknown frame kind " + strconv.Itoa(int(header[0]))))
+			return
+		}
+	}
+}
+
+// closeLoop closes the connection once both loops have finished, or once
+// the session is aborted and the writer has had a chance to report it.
+func (l *wireLink) closeLoop() {
+	select {
+	case <-l.writerDone:
+	case <-l.aborted:
+	}
+	select {
+	case <-l.readerDone:
+	case <-l.aborted:
+	}
+	select {
+	case <-l.aborted:
+		l.conn.SetWriteDeadline(time.Now().Add(abortWriteTimeout))
+		<-l.writerDone
+	default:
+		if l.detaching.Load() {
+			return // the connection now belongs to detach
+		}
+	}
+	l.conn.Close()
+}
+
+// keep saves parts and the bytes buffered in br as rest. It is called by
+// the reader on its way out; rest is only used when detaching.
+func (l *wireLink) keep(br *bufio.Reader, parts ...[]byte) {
+	buffered, _ := br.Peek(br.Buffered())
+	l.rest = slices.Concat(append(parts, buffered)...)
+}
+
+// deliver enqueues v on q, backing off while q is full.
+// Returns false if the session is aborted or detached first.
+func deliver[T any](ctx *sessionContext, q *queue[T], v T) bool {
+	bo := backoff{clock: ctx.ext.clock}
+	for q.Enqueue(&v) != nil {
+		if ctx.state.bits.LoadAcquire()&stateAborted != 0 || ctx.ext.wire.detaching.Load() {
+			return false
+		}
+		bo.Wait()
+	}
+	return true
+}
+
+func writeFrame(w *bufio.Writer, kind byte, payload []byte) error {
+	var header [5]byte
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
@@ -0,0 +1,218 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"time"
+
+	"code.hybscloud.com/kont"
+)
+
+// endpointType is the payload type of an untyped delegation.
+var endpointType = reflect.TypeFor[*Endpoint]()
+
+// wireDelegation is the delegation of a network endpoint queued on the
+// send queue of the network endpoint carrying it.
+type wireDelegation struct {
+	ep   *Endpoint
+	name string // type the endpoint is delegated as
+}
+
+// wireHandoff is the payload of a delegation frame: the side the
+// delegated endpoint plays, its position in the protocol, and what it had
+// received but not consumed, framed again. An endpoint that was aborted
+// before it could be detached is handed off as aborted, without a
+// connection.
+type wireHandoff struct {
+	Type    string
+	Side    uint8
+	Step    int
+	Session uint64 `json:",omitempty"`
+	Pending []byte `json:",omitempty"`
+	Aborted bool   `json:",omitempty"`
+	Reason  string `json:",omitempty"`
+}
+
+// fileConn is implemented by connections backed by a file descriptor.
+type fileConn interface {
+	File() (*os.File, error)
+}
+
+// wireDelegate queues the delegation of ep, delegated as typ, on the
+// network endpoint ctx. ep is retired at once; the writer detaches it
+// from its connection and passes the connection to the peer process.
+func wireDelegate(ctx *sessionContext, ep *Endpoint, typ reflect.Type) (kont.Resumed, error) {
+	if ep.ctx.ext.wire == nil || !canPassFD(ctx.ext.wire.conn) {
+		return nil, &CodecError{Type: typ, Err: errNotDelegable}
+	}
+	if _, ok := ep.ctx.ext.wire.conn.(fileConn); !ok {
+		return nil, &CodecError{Type: typ, Err: errNotDelegable}
+	}
+	if ep.ctx.ext.moved {
+		return nil, ErrEndpointMoved
+	}
+	st := ep.ctx.state.bits.LoadAcquire()
+	if st&stateAborted != 0 {
+		return nil, ep.ctx.state.abort.Load()
+	}
+	if st&ep.ctx.closeBit != 0 {
+		return nil, ErrEndpointClosed
+	}
+	o := ep.ctx.ext.owner
+	if o != nil {
+		if err := o.hand(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := wireQueue(ctx, wireDelegation{ep: ep, name: typ.String()}); err != nil {
+		if o != nil {
+			o.release()
+		}
+		return nil, err
+	}
+	ep.ctx.ext.moved = true
+	return struct{}{}, nil
+}
+
+// writeDelegation detaches the endpoint of d and writes its delegation
+// frame, passing its connection along.
+func (l *wireLink) writeDelegation(bw *bufio.Writer, d wireDelegation) error {
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	h, f := d.ep.ctx.ext.wire.detach(&d.ep.ctx)
+	h.Type = d.name
+	payload, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, 5, 5+len(payload))
+	frame[0] = frameDelegate
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	frame = append(frame, payload...)
+	if f == nil {
+		_, err = l.conn.Write(frame)
+		return err
+	}
+	defer f.Close()
+	return passFD(l.conn, frame, f)
+}
+
+// detach stops the loops of a network endpoint being delegated, once
+// everything it sent has been written, and returns its handoff with a
+// file holding its connection. The endpoint's own connection is closed.
+func (l *wireLink) detach(ctx *sessionContext) (*wireHandoff, *os.File) {
+	l.detaching.Store(true)
+	l.kick()
+	l.conn.SetReadDeadline(time.Unix(1, 0))
+	<-l.writerDone
+	<-l.readerDone
+
+	h := &wireHandoff{Side: ctx.side(), Step: ctx.step, Session: l.session.Load()}
+	fail := func(reason error) (*wireHandoff, *os.File) {
+		h.Aborted = true
+		if reason != nil {
+			h.Reason = reason.Error()
+		}
+		return h, nil
+	}
+	if ctx.state.bits.LoadAcquire()&stateAborted != 0 {
+		return fail(ctx.state.abort.Load().Reason)
+	}
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	for {
+		v, err := ctx.recvQ.Dequeue()
+		if err != nil {
+			break
+		}
+		data, ok := v.([]byte)
+		if !ok {
+			l.conn.Close()
+			return fail(errors.New("sess: delegated endpoint holds an endpoint not yet received"))
+		}
+		writeFrame(bw, frameData, data)
+	}
+	for {
+		label, err := ctx.awaitQ.Dequeue()
+		if err != nil {
+			break
+		}
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(label))
+		writeFrame(bw, frameSelect, b[:])
+	}
+	bw.Write(l.rest)
+	if ctx.state.bits.LoadAcquire()&ctx.peerCloseBit() != 0 {
+		writeFrame(bw, frameClose, nil)
+	}
+	bw.Flush()
+	h.Pending = buf.Bytes()
+
+	f, err := l.conn.(fileConn).File()
+	l.conn.Close()
+	if err != nil {
+		return fail(err)
+	}
+	return h, f
+}
+
+// adopt creates the endpoint delegated by a delegation frame received on
+// ctx, over the next descriptor from fds. It uses ctx's codec.
+func adopt(ctx *sessionContext, fds fdSource, payload []byte) (delegation, error) {
+	var h wireHandoff
+	if err := json.Unmarshal(payload, &h); err != nil {
+		return delegation{}, err
+	}
+	d := delegation{name: h.Type}
+	if h.Type == endpointType.String() {
+		d.typ = endpointType
+	}
+	if h.Aborted {
+		var reason error
+		if h.Reason != "" {
+			reason = errors.New(h.Reason)
+		}
+		d.ep, _ = newPair(nextSerial(), channelCapacity, channelCapacity)
+		d.ep.Abort(reason)
+		return d, nil
+	}
+	fd, ok := -1, false
+	if fds != nil {
+		fd, ok = fds.takeFD()
+	}
+	if !ok {
+		return delegation{}, errors.New("sess: delegation frame without a file descriptor")
+	}
+	f := os.NewFile(uintptr(fd), "sess-delegated")
+	conn, err := net.FileConn(f)
+	f.Close()
+	if err != nil {
+		return delegation{}, err
+	}
+	closeBit := stateClosedA
+	if h.Side == 1 {
+		closeBit = stateClosedB
+	}
+	d.ep = newWireEndpoint(conn, ctx.ext.codec, closeBit, &h)
+	return d, nil
+}
+
+// acceptEndpoint completes a Recv[T] on a network endpoint that received
+// the delegation d.
+func acceptEndpoint[T any](ctx *sessionContext, d delegation) (kont.Resumed, error) {
+	if err := d.check(ctx, reflect.TypeFor[T]()); err != nil {
+		return nil, err
+	}
+	return d.ep, nil
+}
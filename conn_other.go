@@ -0,0 +1,30 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package sess
+
+import (
+	"io"
+	"net"
+	"os"
+)
+
+// fdSource is implemented by connection readers that receive file
+// descriptors alongside the stream. Descriptors cannot be passed on this
+// system.
+type fdSource interface {
+	takeFD() (int, bool)
+	closeFDs()
+}
+
+// newLinkReader returns the reader of a network endpoint's connection.
+func newLinkReader(conn net.Conn) io.Reader { return conn }
+
+// canPassFD reports whether endpoints can be delegated over conn.
+func canPassFD(net.Conn) bool { return false }
+
+// passFD is never called on this system.
+func passFD(net.Conn, []byte, *os.File) error { return errNotDelegable }
@@ -0,0 +1,223 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+func pipeEndpoints() (*sess.Endpoint, *sess.Endpoint) {
+	c1, c2 := net.Pipe()
+	return sess.DialConn(c1, nil), sess.AcceptConn(c2, nil)
+}
+
+func TestConnSendRecv(t *testing.T) {
+	skipRace(t)
+	epA, epB := pipeEndpoints()
+
+	done := make(chan int)
+	go func() {
+		done <- sess.Exec(epB, sess.RecvBind(func(n int) kont.Eff[int] {
+			return sess.SendThen("pong", sess.CloseDone(n))
+		}))
+	}()
+	got := sess.Exec(epA, sess.SendThen(42, sess.RecvBind(func(s string) kont.Eff[string] {
+		return sess.CloseDone(s)
+	})))
+	if got != "pong" {
+		t.Fatalf("client got %q, want pong", got)
+	}
+	if n := <-done; n != 42 {
+		t.Fatalf("server got %d, want 42", n)
+	}
+}
+
+func TestConnLoopWithChoice(t *testing.T) {
+	skipRace(t)
+	epA, epB := pipeEndpoints()
+	const n = 100
+
+	type point struct{ X, Y int }
+	sender := sess.ExprLoop(0, func(i int) kont.Expr[kont.Either[int, struct{}]] {
+		if i == n {
+			return sess.ExprSelectRThen(sess.ExprCloseDone(kont.Right[int, struct{}](struct{}{})))
+		}
+		return sess.ExprSelectLThen(sess.ExprSendThen(point{i, -i}, kont.ExprReturn(kont.Left[int, struct{}](i+1))))
+	})
+	receiver := sess.ExprLoop(0, func(sum int) kont.Expr[kont.Either[int, int]] {
+		return sess.ExprOfferBranch(
+			func() kont.Expr[kont.Either[int, int]] {
+				return sess.ExprRecvBind(func(p point) kont.Expr[kont.Either[int, int]] {
+					return kont.ExprReturn(kont.Left[int, int](sum + p.X - p.Y))
+				})
+			},
+			func() kont.Expr[kont.Either[int, int]] {
+				return sess.ExprCloseDone(kont.Right[int, int](sum))
+			},
+		)
+	})
+
+	go sess.ExecExpr(epA, sender)
+	if got, want := sess.ExecExpr(epB, receiver), n*(n-1); got != want {
+		t.Fatalf("receiver got %d, want %d", got, want)
+	}
+}
+
+func TestConnAdvanceWouldBlock(t *testing.T) {
+	skipRace(t)
+	epA, epB := pipeEndpoints()
+	defer epA.Abort(nil)
+
+	_, susp := sess.Step[int](sess.ExprRecvBind(func(n int) kont.Expr[int] { return kont.ExprReturn(n) }))
+	if _, _, err := sess.Advance(epB, susp); err != iox.ErrWouldBlock {
+		t.Fatalf("Advance got %v, want ErrWouldBlock", err)
+	}
+	susp.Discard()
+}
+
+func TestConnPeerClosed(t *testing.T) {
+	skipRace(t)
+	epA, epB := pipeEndpoints()
+
+	sess.Exec(epA, sess.SendThen(1, sess.CloseDone(struct{}{})))
+	_, err := sess.ExecContext(context.Background(), epB, sess.RecvBind(func(n int) kont.Eff[int] {
+		return sess.RecvBind(func(m int) kont.Eff[int] { return sess.CloseDone(n + m) })
+	}))
+	if err != sess.ErrPeerClosed {
+		t.Fatalf("got %v, want ErrPeerClosed", err)
+	}
+}
+
+func TestConnAbortPropagates(t *testing.T) {
+	skipRace(t)
+	epA, epB := pipeEndpoints()
+
+	done := make(chan error)
+	go func() {
+		_, err := sess.ExecContext(context.Background(), epB, sess.RecvBind(func(n int) kont.Eff[int] {
+			return sess.CloseDone(n)
+		}))
+		done <- err
+	}()
+	epA.Abort(errors.New("going away"))
+
+	err := <-done
+	if !errors.Is(err, sess.ErrSessionAborted) || !strings.HasSuffix(err.Error(), "aborted: going away") {
+		t.Fatalf("got %v, want abort with peer reason", err)
+	}
+}
+
+func TestConnLostAborts(t *testing.T) {
+	skipRace(t)
+	c1, c2 := net.Pipe()
+	ep := sess.DialConn(c1, nil)
+	c2.Close()
+
+	_, err := sess.ExecContext(context.Background(), ep, sess.RecvBind(func(n int) kont.Eff[int] {
+		return sess.CloseDone(n)
+	}))
+	if !errors.Is(err, sess.ErrSessionAborted) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("got %v, want abort wrapping io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestConnEncodeError(t *testing.T) {
+	skipRace(t)
+	epA, _ := pipeEndpoints()
+	defer epA.Abort(nil)
+
+	_, susp := sess.Step[struct{}](sess.ExprSendThen(func() {}, kont.ExprReturn(struct{}{})))
+	_, _, err := sess.Advance(epA, susp)
+	var ce *sess.CodecError
+	if !errors.As(err, &ce) {
+		t.Fatalf("got %v, want *CodecError", err)
+	}
+	susp.Discard()
+}
+
+func TestDialAccept(t *testing.T) {
+	skipRace(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	done := make(chan string)
+	go func() {
+		ep, err := sess.Accept(ln, nil)
+		if err != nil {
+			done <- err.Error()
+			return
+		}
+		done <- sess.Exec(ep, sess.RecvBind(func(s string) kont.Eff[string] { return sess.CloseDone(s) }))
+	}()
+
+	ep, err := sess.Dial("tcp", ln.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	sess.Exec(ep, sess.SendThen("over tcp", sess.CloseDone(struct{}{})))
+	if got := <-done; got != "over tcp" {
+		t.Fatalf("got %q, want %q", got, "over tcp")
+	}
+}
+
+func TestConnLabeledChoice(t *testing.T) {
+	skipRace(t)
+	epA, epB := pipeEndpoints()
+
+	done := make(chan int)
+	go func() {
+		done <- sess.Exec(epB, sess.OfferCases(map[sess.Label]func() kont.Eff[int]{
+			sess.LabelLeft: func() kont.Eff[int] { return sess.CloseDone(-1) },
+			1000:           func() kont.Eff[int] { return sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) }) },
+		}))
+	}()
+	sess.Exec(epA, sess.SelectThen(1000, sess.SendThen(5, sess.CloseDone(struct{}{}))))
+	if got := <-done; got != 5 {
+		t.Fatalf("server got %d, want 5", got)
+	}
+}
+
+func TestConnDelegateInProcess(t *testing.T) {
+	skipRace(t)
+	netA, netB := pipeEndpoints()
+	subA := sess.ChanOf[subP, sess.SideA](netA)
+	epA, epB := sess.New()
+	_, accepted := advancePair(t, epA, epB,
+		sess.ExprDelegateThen(subA, sess.ExprCloseDone("delegated")),
+		sess.ExprAcceptBind(func(c sess.Chan[subP, sess.SideA]) kont.Expr[sess.Chan[subP, sess.SideA]] {
+			return sess.ExprCloseDone(c)
+		}),
+	)
+
+	var woken atomic.Int32
+	accepted.Endpoint().OnReadable(func() { woken.Add(1) })
+
+	done := make(chan int)
+	go func() {
+		done <- sess.Exec(netB, subServer(sess.ChanOf[subP, sess.SideB](netB)))
+	}()
+	if got := sess.Exec(accepted.Endpoint(), subClient(accepted)); got != 42 {
+		t.Fatalf("delegated network endpoint got %d, want 42", got)
+	}
+	if n := <-done; n != 21 {
+		t.Fatalf("server got %d, want 21", n)
+	}
+	if woken.Load() == 0 {
+		t.Fatal("readable callback of the accepted handle never fired")
+	}
+}
@@ -0,0 +1,89 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package sess
+
+import (
+	"io"
+	"net"
+	"os"
+	"syscall"
+)
+
+// maxFDsPerRead bounds the descriptors accepted with a single read.
+const maxFDsPerRead = 16
+
+// fdSource is implemented by connection readers that receive file
+// descriptors alongside the stream.
+type fdSource interface {
+	takeFD() (int, bool)
+	closeFDs()
+}
+
+// fdReader reads a Unix socket, collecting descriptors passed with
+// SCM_RIGHTS in the order they arrive. Delegation frames take them in
+// the same order.
+type fdReader struct {
+	conn *net.UnixConn
+	oob  []byte
+	fds  []int
+}
+
+func (r *fdReader) Read(p []byte) (int, error) {
+	n, oobn, _, _, err := r.conn.ReadMsgUnix(p, r.oob)
+	if oobn > 0 {
+		msgs, perr := syscall.ParseSocketControlMessage(r.oob[:oobn])
+		if perr == nil {
+			for i := range msgs {
+				if fds, err := syscall.ParseUnixRights(&msgs[i]); err == nil {
+					r.fds = append(r.fds, fds...)
+				}
+			}
+		}
+	}
+	return n, err
+}
+
+func (r *fdReader) takeFD() (int, bool) {
+	if len(r.fds) == 0 {
+		return -1, false
+	}
+	fd := r.fds[0]
+	r.fds = r.fds[1:]
+	return fd, true
+}
+
+// closeFDs closes descriptors that no delegation frame took.
+func (r *fdReader) closeFDs() {
+	for _, fd := range r.fds {
+		syscall.Close(fd)
+	}
+	r.fds = nil
+}
+
+// newLinkReader returns the reader of a network endpoint's connection.
+func newLinkReader(conn net.Conn) io.Reader {
+	if uc, ok := conn.(*net.UnixConn); ok {
+		return &fdReader{conn: uc, oob: make([]byte, syscall.CmsgSpace(4*maxFDsPerRead))}
+	}
+	return conn
+}
+
+// canPassFD reports whether endpoints can be delegated over conn.
+func canPassFD(conn net.Conn) bool {
+	_, ok := conn.(*net.UnixConn)
+	return ok
+}
+
+// passFD writes frame to conn with f's descriptor as SCM_RIGHTS data.
+func passFD(conn net.Conn, frame []byte, f *os.File) error {
+	uc := conn.(*net.UnixConn)
+	n, _, err := uc.WriteMsgUnix(frame, syscall.UnixRights(int(f.Fd())), nil)
+	if err == nil && n < len(frame) {
+		_, err = uc.Write(frame[n:])
+	}
+	return err
+}
@@ -0,0 +1,195 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package sess_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+// unixPair returns the ends of a connected Unix socket pair.
+func unixPair(t *testing.T) (*net.UnixConn, *net.UnixConn) {
+	t.Helper()
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Skipf("socketpair: %v", err)
+	}
+	conn := func(fd int) *net.UnixConn {
+		f := os.NewFile(uintptr(fd), "unixpair")
+		defer f.Close()
+		c, err := net.FileConn(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.(*net.UnixConn)
+	}
+	return conn(fds[0]), conn(fds[1])
+}
+
+// controlPair returns the two endpoints of a control session over a Unix
+// socket, standing in for a front-end and a worker process.
+func controlPair(t *testing.T) (front, worker *sess.Endpoint) {
+	a, b := unixPair(t)
+	return sess.DialConn(a, nil), sess.AcceptConn(b, nil)
+}
+
+// tcpPair returns a client endpoint and the server endpoint of a session
+// over loopback TCP.
+func tcpPair(t *testing.T) (remote, served *sess.Endpoint) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen: %v", err)
+	}
+	defer ln.Close()
+	accepted := make(chan *sess.Endpoint)
+	go func() {
+		ep, _ := sess.Accept(ln, nil)
+		accepted <- ep
+	}()
+	remote, err = sess.Dial("tcp", ln.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return remote, <-accepted
+}
+
+func TestConnDelegateAcrossUnixSocket(t *testing.T) {
+	skipRace(t)
+	remote, served := tcpPair(t)
+	front, worker := controlPair(t)
+
+	// The remote client sends two factors and expects their product.
+	remoteDone := make(chan int)
+	go func() {
+		remoteDone <- sess.Exec(remote, sess.SendThen(21, sess.SendThen(5,
+			sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) }))))
+	}()
+
+	// The worker accepts the session mid-protocol, with the first factor
+	// forwarded over the control session.
+	workerDone := make(chan int)
+	go func() {
+		workerDone <- sess.Exec(worker, sess.RecvBind(func(ep *sess.Endpoint) kont.Eff[int] {
+			return sess.RecvBind(func(a int) kont.Eff[int] {
+				b := sess.Exec(ep, sess.RecvBind(func(b int) kont.Eff[int] {
+					return sess.SendThen(a*b, sess.CloseDone(b))
+				}))
+				return sess.CloseDone(b)
+			})
+		}))
+	}()
+
+	// The front end reads the first factor, then hands the session off.
+	a := sess.Exec(served, sess.RecvBind(func(a int) kont.Eff[int] { return kont.Pure(a) }))
+	sess.Exec(front, sess.SendThen(served, sess.SendThen(a, sess.CloseDone(struct{}{}))))
+
+	if got := <-remoteDone; got != 105 {
+		t.Fatalf("remote got %d, want 105", got)
+	}
+	if got := <-workerDone; got != 5 {
+		t.Fatalf("worker got %d, want 5", got)
+	}
+	_, _, err := sess.Advance(served, stepSusp(sess.ExprSendThen(1, kont.ExprReturn(struct{}{}))))
+	if err != sess.ErrEndpointMoved {
+		t.Fatalf("delegated endpoint got %v, want ErrEndpointMoved", err)
+	}
+}
+
+func TestConnDelegatePeerClosed(t *testing.T) {
+	skipRace(t)
+	remote, served := tcpPair(t)
+	front, worker := controlPair(t)
+	sess.Exec(remote, sess.SendThen("last", sess.CloseDone(struct{}{})))
+	// Wait for the close frame without consuming the value, so both are
+	// handed off.
+	for served.State() != sess.StateHalfClosed {
+		time.Sleep(time.Millisecond)
+	}
+
+	go sess.Exec(front, sess.SendThen(served, sess.CloseDone(struct{}{})))
+	got, err := sess.ExecContext(context.Background(), worker, sess.RecvBind(func(ep *sess.Endpoint) kont.Eff[string] {
+		s := sess.Exec(ep, sess.RecvBind(func(s string) kont.Eff[string] { return kont.Pure(s) }))
+		_, _, err := sess.Advance(ep, stepSusp(sess.ExprRecvBind(func(s string) kont.Expr[string] { return kont.ExprReturn(s) })))
+		if !errors.Is(err, sess.ErrPeerClosed) {
+			t.Errorf("receive past the peer's close got %v, want ErrPeerClosed", err)
+		}
+		return sess.CloseDone(s)
+	}))
+	if err != nil || got != "last" {
+		t.Fatalf("worker got %q, %v, want last", got, err)
+	}
+}
+
+type wireSub = sess.RecvP[string, sess.EndP]
+
+func TestConnDelegateTyped(t *testing.T) {
+	skipRace(t)
+	remote, served := tcpPair(t)
+	front, worker := controlPair(t)
+	go sess.Exec(remote, sess.SendThen("typed", sess.CloseDone(struct{}{})))
+	go sess.Exec(front, sess.DelegateThen(sess.ChanOf[wireSub, sess.SideA](served), sess.CloseDone(struct{}{})))
+
+	got, err := sess.ExecContext(context.Background(), worker, sess.AcceptBind(func(c sess.Chan[wireSub, sess.SideA]) kont.Eff[string] {
+		s := sess.ExecChan(c, func(c sess.Chan[wireSub, sess.SideA]) kont.Eff[string] {
+			return sess.ChanRecvBind(c, func(s string, c sess.Chan[sess.EndP, sess.SideA]) kont.Eff[string] {
+				return sess.ChanCloseDone(c, s)
+			})
+		})
+		return sess.CloseDone(s)
+	}))
+	if err != nil || got != "typed" {
+		t.Fatalf("worker got %q, %v, want typed", got, err)
+	}
+}
+
+func TestConnDelegateTypeMismatch(t *testing.T) {
+	skipRace(t)
+	_, served := tcpPair(t)
+	front, worker := controlPair(t)
+	go sess.Exec(front, sess.DelegateThen(sess.ChanOf[wireSub, sess.SideA](served), sess.CloseDone(struct{}{})))
+
+	_, err := sess.ExecContext(context.Background(), worker, sess.AcceptBind(func(c sess.Chan[wireSub, sess.SideB]) kont.Eff[struct{}] {
+		return sess.CloseDone(struct{}{})
+	}))
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) {
+		t.Fatalf("accepting the wrong side got %v, want *ProtocolViolation", err)
+	}
+}
+
+func TestConnDelegateUnsupported(t *testing.T) {
+	skipRace(t)
+	delegate := func(carrier, ep *sess.Endpoint) error {
+		_, _, err := sess.Advance(carrier, stepSusp(sess.ExprSendThen(ep, kont.ExprReturn(struct{}{}))))
+		return err
+	}
+	front, _ := controlPair(t)
+	local, _ := sess.New()
+	piped, _ := pipeEndpoints()
+	_, served := tcpPair(t)
+	overTCP, _ := tcpPair(t)
+
+	var ce *sess.CodecError
+	for name, err := range map[string]error{
+		"in-process endpoint":  delegate(front, local),
+		"endpoint over a pipe": delegate(front, piped),
+		"over TCP":             delegate(overTCP, served),
+	} {
+		if !errors.As(err, &ce) {
+			t.Errorf("%s: got %v, want *CodecError", name, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"context"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+)
+
+// waitContext carries the context of a context-aware execution
+// and the error that ended it, if any.
+type waitContext struct {
+	ctx  context.Context
+	done <-chan struct{}
+	err  error
+}
+
+func newWaitContext(ctx context.Context) *waitContext {
+	return &waitContext{ctx: ctx, done: ctx.Done()}
+}
+
+// canceled reports whether the context is done. On cancellation it
+// records ctx.Err() and aborts the session on ep.
+func (w *waitContext) canceled(ep *sessionContext) bool {
+	if !isDone(w.done) {
+		return false
+	}
+	w.err = w.ctx.Err()
+	ep.abort(w.err)
+	return true
+}
+
+// dispatch is dispatchWait observing cancellation before each attempt.
+// Returns false with w.err set if the context is done or sop fails
+// terminally. A terminal failure other than ErrPeerClosed aborts the
+// session so that the peer observes it, as Exec does.
+func (w *waitContext) dispatch(ctx *sessionContext, sop sessionDispatcher) (kont.Resumed, bool) {
+	bo := backoff{clock: ctx.ext.clock, metrics: ctx.ext.metrics}
+	for {
+		if w.canceled(ctx) {
+			return nil, false
+		}
+		v, err := ctx.dispatch(sop)
+		if err == nil {
+			bo.done()
+			return v, true
+		}
+		if err != iox.ErrWouldBlock {
+			if err != ErrPeerClosed {
+				ctx.abort(err)
+			}
+			w.err = err
+			return nil, false
+		}
+		bo.Wait()
+	}
+}
+
+// isDone polls done without blocking. A nil channel is never done.
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExecContext runs a Cont-world session protocol on a pre-created endpoint
+// like Exec, observing ctx between backoff waits. When ctx is done it stops,
+// marks the session aborted (see Endpoint.Aborted), and returns ctx.Err().
+// A terminal operation failure is returned instead of panicking.
+func ExecContext[R any](ctx context.Context, ep *Endpoint, protocol kont.Eff[R]) (R, error) {
+	w := newWaitContext(ctx)
+	defer abortOnPanic(&ep.ctx)
+	h := sessionHandler[R]{ctx: &ep.ctx, wait: w}
+	r := kont.Handle(protocol, h)
+	return r, w.err
+}
+
+// ExecExprContext runs an Expr-world session protocol on a pre-created
+// endpoint like ExecExpr, observing ctx as ExecContext does.
+func ExecExprContext[R any](ctx context.Context, ep *Endpoint, protocol kont.Expr[R]) (R, error) {
+	w := newWaitContext(ctx)
+	defer abortOnPanic(&ep.ctx)
+	h := sessionHandler[R]{ctx: &ep.ctx, wait: w}
+	r := kont.HandleExpr(protocol, h)
+	return r, w.err
+}
+
+// ExecErrorContext runs a Cont-world session protocol with error handling
+// like ExecError, observing ctx as ExecContext does.
+func ExecErrorContext[E, R any](ctx context.Context, ep *Endpoint, protocol kont.Eff[R]) (kont.Either[E, R], error) {
+	wrapped := kont.Map[kont.Resumed, R, kont.Either[E, R]](protocol, func(r R) kont.Either[E, R] {
+		return kont.Right[E, R](r)
+	})
+	w := newWaitContext(ctx)
+	var errCtx kont.ErrorContext[E]
+	defer abortOnPanic(&ep.ctx)
+	h := sessionErrorHandler[E, R]{ctx: &ep.ctx, errCtx: &errCtx, wait: w}
+	r := kont.Handle(wrapped, h)
+	if w.err == nil {
+		failed(&ep.ctx, r)
+	}
+	return r, w.err
+}
+
+// ExecErrorExprContext runs an Expr-world session protocol with error
+// handling like ExecErrorExpr, observing ctx as ExecContext does.
+func ExecErrorExprContext[E, R any](ctx context.Context, ep *Endpoint, protocol kont.Expr[R]) (kont.Either[E, R], error) {
+	wrapped := wrapRight[E, R](protocol)
+	w := newWaitContext(ctx)
+	var errCtx kont.ErrorContext[E]
+	defer abortOnPanic(&ep.ctx)
+	h := sessionErrorHandler[E, R]{ctx: &ep.ctx, errCtx: &errCtx, wait: w}
+	r := kont.HandleExpr(wrapped, h)
+	if w.err == nil {
+		failed(&ep.ctx, r)
+	}
+	return r, w.err
+}
+
+// RunContext is TryRun observing ctx between steps. When ctx is done it
+// stops, marks the session aborted, and returns ctx.Err().
+func RunContext[A, B any](ctx context.Context, a kont.Eff[A], b kont.Eff[B]) (A, B, error) {
+	return RunExprContext(ctx, Reify(a), Reify(b))
+}
+
+// RunExprContext is TryRunExpr observing ctx between steps.
+func RunExprContext[A, B any](ctx context.Context, a kont.Expr[A], b kont.Expr[B]) (A, B, error) {
+	epA, epB := New()
+	return tryRunExpr(ctx, epA, epB, a, b)
+}
+
+// RunErrorContext is TryRunError observing ctx between steps.
+func RunErrorContext[E, A, B any](ctx context.Context, a kont.Eff[A], b kont.Eff[B]) (kont.Either[E, A], kont.Either[E, B], error) {
+	return RunErrorExprContext[E](ctx, Reify(a), Reify(b))
+}
+
+// RunErrorExprContext is TryRunErrorExpr observing ctx between steps.
+func RunErrorExprContext[E, A, B any](ctx context.Context, a kont.Expr[A], b kont.Expr[B]) (kont.Either[E, A], kont.Either[E, B], error) {
+	epA, epB := New()
+	return tryRunErrorExpr[E](ctx, epA, epB, a, b)
+}
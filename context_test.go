@@ -0,0 +1,207 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+// pingForever sends and receives integers until canceled.
+func pingForever() kont.Eff[struct{}] {
+	return sess.Loop(0, func(i int) kont.Eff[kont.Either[int, struct{}]] {
+		return sess.SendThen(i, sess.RecvBind(func(n int) kont.Eff[kont.Either[int, struct{}]] {
+			return kont.Pure(kont.Left[int, struct{}](n + 1))
+		}))
+	})
+}
+
+// pongForever echoes integers until canceled.
+func pongForever() kont.Eff[struct{}] {
+	return sess.Loop(0, func(int) kont.Eff[kont.Either[int, struct{}]] {
+		return sess.RecvBind(func(n int) kont.Eff[kont.Either[int, struct{}]] {
+			return sess.SendThen(n, kont.Pure(kont.Left[int, struct{}](n)))
+		})
+	})
+}
+
+func TestExecContextDeadline(t *testing.T) {
+	epA, epB := sess.New()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// No peer ever sends: Recv would wait forever without the deadline.
+	_, err := sess.ExecContext(ctx, epA, sess.RecvBind(func(n int) kont.Eff[int] {
+		return sess.CloseDone(n)
+	}))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+	if !epA.Aborted() || !epB.Aborted() {
+		t.Fatal("both endpoints must observe the aborted session")
+	}
+}
+
+func TestExecExprContextAlreadyCanceled(t *testing.T) {
+	epA, _ := sess.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r, err := sess.ExecExprContext(ctx, epA, sess.ExprSendThen(1, sess.ExprCloseDone("sent")))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want Canceled", err)
+	}
+	if r != "" {
+		t.Fatalf("got %q, want zero result", r)
+	}
+}
+
+func TestExecContextSuccess(t *testing.T) {
+	skipRace(t)
+	epA, epB := sess.New()
+	ctx := context.Background()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := sess.ExecContext(ctx, epB, sess.RecvBind(func(n int) kont.Eff[int] {
+			return sess.SendThen(n*2, sess.CloseDone(n))
+		}))
+		done <- err
+	}()
+
+	r, err := sess.ExecContext(ctx, epA, sess.SendThen(21, sess.RecvBind(func(n int) kont.Eff[int] {
+		return sess.CloseDone(n)
+	})))
+	if err != nil {
+		t.Fatalf("ExecContext: %v", err)
+	}
+	if r != 42 {
+		t.Fatalf("got %d, want 42", r)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("peer ExecContext: %v", err)
+	}
+	if epA.Aborted() {
+		t.Fatal("completed session must not be aborted")
+	}
+}
+
+func TestExecContextViolationReturned(t *testing.T) {
+	epA, _ := sess.NewMonitored(sess.SpecEnd())
+
+	_, err := sess.ExecContext(context.Background(), epA, sess.SendThen(1, sess.CloseDone(struct{}{})))
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) {
+		t.Fatalf("expected *ProtocolViolation, got %v", err)
+	}
+}
+
+func TestExecContextViolationAborts(t *testing.T) {
+	skipRace(t)
+	epA, epB := sess.New()
+	peer := make(chan error, 1)
+	go func() {
+		_, err := sess.ExecContext(context.Background(), epB, sess.SendThen("one", sess.RecvBind(func(n int) kont.Eff[int] {
+			return sess.CloseDone(n)
+		})))
+		peer <- err
+	}()
+
+	_, err := sess.ExecContext(context.Background(), epA, sess.RecvBind(func(n int) kont.Eff[int] {
+		return sess.SendThen(n, sess.CloseDone(n))
+	}))
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) || !epA.Aborted() {
+		t.Fatalf("got %v, aborted %v, want an aborting *ProtocolViolation", err, epA.Aborted())
+	}
+	select {
+	case err := <-peer:
+		if !errors.Is(err, sess.ErrSessionAborted) {
+			t.Fatalf("peer got %v, want ErrSessionAborted", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("peer still blocked in Recv")
+	}
+}
+
+func TestExecErrorContextDeadline(t *testing.T) {
+	epA, _ := sess.New()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := sess.ExecErrorContext[string](ctx, epA, sess.RecvBind(func(n int) kont.Eff[int] {
+		return sess.CloseDone(n)
+	}))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestExecErrorExprContextThrow(t *testing.T) {
+	epA, _ := sess.New()
+
+	r, err := sess.ExecErrorExprContext[string](context.Background(), epA,
+		sess.ExprSendThen(1, kont.ExprThrowError[string, int]("boom")))
+	if err != nil {
+		t.Fatalf("ExecErrorExprContext: %v", err)
+	}
+	if e, ok := r.GetLeft(); !ok || e != "boom" {
+		t.Fatalf("got %v, want Left(boom)", r)
+	}
+}
+
+func TestRunContextCancel(t *testing.T) {
+	skipRace(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, _, err := sess.RunContext(ctx, pingForever(), pongForever())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestRunErrorContextCancel(t *testing.T) {
+	skipRace(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, _, err := sess.RunErrorContext[string](ctx, pingForever(), pongForever())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestRunExprContextSuccess(t *testing.T) {
+	skipRace(t)
+	a := sess.ExprSendThen(5, sess.ExprCloseDone("sent"))
+	b := sess.ExprRecvBind(func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) })
+
+	ra, rb, err := sess.RunExprContext(context.Background(), a, b)
+	if err != nil {
+		t.Fatalf("RunExprContext: %v", err)
+	}
+	if ra != "sent" || rb != 5 {
+		t.Fatalf("got %q/%d, want sent/5", ra, rb)
+	}
+
+	a = sess.ExprSendThen(5, sess.ExprCloseDone("sent"))
+	b = sess.ExprRecvBind(func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) })
+	ea, eb, err := sess.RunErrorExprContext[string](context.Background(), a, b)
+	if err != nil {
+		t.Fatalf("RunErrorExprContext: %v", err)
+	}
+	if v, _ := ea.GetRight(); v != "sent" {
+		t.Fatalf("A got %v", ea)
+	}
+	if v, _ := eb.GetRight(); v != 5 {
+		t.Fatalf("B got %v", eb)
+	}
+}
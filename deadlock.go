@@ -0,0 +1,57 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"reflect"
+	"strconv"
+
+	"code.hybscloud.com/kont"
+)
+
+// PendingOp describes the operation one side of a session is blocked on.
+// Kind is OpNone if that side has already completed.
+type PendingOp struct {
+	Kind OpKind
+	Type reflect.Type // payload type; nil if none
+	Step int          // number of operations completed on the endpoint
+	Peer Role         // addressed role of a multiparty operation; empty otherwise
+}
+
+// String renders the pending operation, e.g. "Recv[int] at step 2".
+func (p PendingOp) String() string {
+	if p.Kind == OpNone {
+		return "completed after step " + strconv.Itoa(p.Step)
+	}
+	return peerOpString(p.Kind, p.Type, p.Peer) + " at step " + strconv.Itoa(p.Step)
+}
+
+// pendingOf describes the operation susp is suspended on, if any.
+func pendingOf[R any](ctx *sessionContext, susp *kont.Suspension[R]) PendingOp {
+	p := PendingOp{Step: ctx.step}
+	if susp == nil {
+		return p
+	}
+	if sop, ok := ctx.pendingOp(susp).(sessionDispatcher); ok {
+		p.Kind, p.Type = sop.opInfo()
+	}
+	return p
+}
+
+// DeadlockError reports that neither side of a session run by the Run
+// family can ever make progress: for example both sides wait in Recv or
+// Offer on empty queues, both are blocked on full queues, or one side
+// has completed while the other still waits for it.
+type DeadlockError struct {
+	Serial Serial
+	A      PendingOp
+	B      PendingOp
+}
+
+// Error implements error.
+func (e *DeadlockError) Error() string {
+	return "sess: deadlock in session " + strconv.FormatUint(uint64(e.Serial), 10) +
+		": A " + e.A.String() + ", B " + e.B.String()
+}
@@ -5,31 +5,148 @@
 package sess_test
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
-	"time"
 
 	"code.hybscloud.com/kont"
 	"code.hybscloud.com/sess"
 )
 
 func TestRunExprDeadlockCoverage(t *testing.T) {
+	skipRace(t)
 	a := sess.ExprRecvBind(func(n int) kont.Expr[struct{}] { return sess.ExprCloseDone(struct{}{}) })
 	b := sess.ExprRecvBind(func(n int) kont.Expr[struct{}] { return sess.ExprCloseDone(struct{}{}) })
 
-	go func() {
-		sess.RunExpr[struct{}, struct{}](a, b)
+	defer func() {
+		err, _ := recover().(error)
+		var de *sess.DeadlockError
+		if !errors.As(err, &de) {
+			t.Fatalf("expected *DeadlockError panic, got %v", err)
+		}
 	}()
-
-	time.Sleep(50 * time.Millisecond) // Give it time to hit bo.Wait()
+	sess.RunExpr[struct{}, struct{}](a, b)
 }
 
 func TestRunErrorExprDeadlockCoverage(t *testing.T) {
+	skipRace(t)
 	a := sess.ExprRecvBind(func(n int) kont.Expr[struct{}] { return sess.ExprCloseDone(struct{}{}) })
 	b := sess.ExprRecvBind(func(n int) kont.Expr[struct{}] { return sess.ExprCloseDone(struct{}{}) })
 
-	go func() {
-		sess.RunErrorExpr[string, struct{}, struct{}](a, b)
+	defer func() {
+		err, _ := recover().(error)
+		var de *sess.DeadlockError
+		if !errors.As(err, &de) {
+			t.Fatalf("expected *DeadlockError panic, got %v", err)
+		}
 	}()
+	sess.RunErrorExpr[string, struct{}, struct{}](a, b)
+}
+
+func TestTryRunExprDeadlockBothRecv(t *testing.T) {
+	skipRace(t)
+	a := sess.ExprRecvBind(func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) })
+	b := sess.ExprSelectLThen(sess.ExprOfferBranch(
+		func() kont.Expr[int] { return sess.ExprCloseDone(1) },
+		func() kont.Expr[int] { return sess.ExprCloseDone(2) },
+	))
+
+	_, _, err := sess.TryRunExpr(a, b)
+	var de *sess.DeadlockError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected *DeadlockError, got %v", err)
+	}
+	if de.A.Kind != sess.OpRecv || de.A.Type != reflect.TypeFor[int]() || de.A.Step != 0 {
+		t.Fatalf("A pending got %v, want Recv[int] at step 0", de.A)
+	}
+	if de.B.Kind != sess.OpOffer || de.B.Step != 1 {
+		t.Fatalf("B pending got %v, want Offer at step 1", de.B)
+	}
+	if !strings.Contains(err.Error(), "A Recv[int] at step 0, B Offer at step 1") {
+		t.Fatalf("unexpected message: %v", err)
+	}
+}
+
+func TestTryRunDeadlockBothFull(t *testing.T) {
+	skipRace(t)
+	// Both sides only send: once both queues are full, neither can progress.
+	flood := func() kont.Eff[struct{}] {
+		return sess.Loop(0, func(i int) kont.Eff[kont.Either[int, struct{}]] {
+			return sess.SendThen(i, kont.Pure(kont.Left[int, struct{}](i+1)))
+		})
+	}
+
+	_, _, err := sess.TryRun(flood(), flood())
+	var de *sess.DeadlockError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected *DeadlockError, got %v", err)
+	}
+	if de.A.Kind != sess.OpSend || de.B.Kind != sess.OpSend {
+		t.Fatalf("pending got %v / %v, want Send / Send", de.A, de.B)
+	}
+}
+
+func TestTryRunDeadlockPeerCompleted(t *testing.T) {
+	skipRace(t)
+	// A returns without closing, so B's Recv can never be satisfied.
+	a := kont.Pure(1)
+	b := sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })
+
+	_, _, err := sess.TryRun(a, b)
+	var de *sess.DeadlockError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected *DeadlockError, got %v", err)
+	}
+	if de.A.Kind != sess.OpNone || de.A.Step != 0 || de.B.Kind != sess.OpRecv {
+		t.Fatalf("pending got %v / %v", de.A, de.B)
+	}
+}
+
+func TestTryRunPeerClosed(t *testing.T) {
+	skipRace(t)
+	a := sess.CloseDone(1)
+	b := sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })
+
+	if _, _, err := sess.TryRun(a, b); err != sess.ErrPeerClosed {
+		t.Fatalf("expected ErrPeerClosed, got %v", err)
+	}
+}
+
+func TestTryRunSuccess(t *testing.T) {
+	skipRace(t)
+	a := sess.SendThen(3, sess.CloseDone("sent"))
+	b := sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })
+
+	ra, rb, err := sess.TryRun(a, b)
+	if err != nil {
+		t.Fatalf("TryRun: %v", err)
+	}
+	if ra != "sent" || rb != 3 {
+		t.Fatalf("got %q/%d, want sent/3", ra, rb)
+	}
+}
+
+func TestTryRunErrorDeadlock(t *testing.T) {
+	skipRace(t)
+	a := sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })
+	b := sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })
+
+	_, _, err := sess.TryRunError[string](a, b)
+	var de *sess.DeadlockError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected *DeadlockError, got %v", err)
+	}
+}
+
+func TestTryRunProtocolViolation(t *testing.T) {
+	skipRace(t)
+	a := sess.SendThen("x", sess.CloseDone(struct{}{}))
+	b := sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })
 
-	time.Sleep(50 * time.Millisecond) // Give it time to hit bo.Wait()
+	_, _, err := sess.TryRun(a, b)
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) {
+		t.Fatalf("expected *ProtocolViolation, got %v", err)
+	}
 }
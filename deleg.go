@@ -0,0 +1,256 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+
+	"code.hybscloud.com/kont"
+)
+
+// ErrEndpointMoved is returned by operations on an endpoint handle that
+// was delegated away with DelegateChan. The session lives on in the handle
+// the receiver accepted.
+var ErrEndpointMoved = errors.New("sess: endpoint moved by delegation")
+
+// errNotDelegable is wrapped in the *CodecError of a delegation the
+// session cannot carry.
+var errNotDelegable = errors.New("endpoint cannot be delegated over this session")
+
+// delegation is the payload of DelegateChan: a fresh handle for the
+// delegated endpoint and the type it was delegated as. A delegation
+// received over a connection carries the type's name, and the type only
+// if it is *Endpoint.
+type delegation struct {
+	ep   *Endpoint
+	typ  reflect.Type
+	name string
+}
+
+// check reports a *ProtocolViolation unless d was delegated as want.
+func (d delegation) check(ctx *sessionContext, want reflect.Type) error {
+	if d.typ == want || d.typ == nil && d.name == want.String() {
+		return nil
+	}
+	return &ProtocolViolation{
+		Expected:     OpRecv,
+		Actual:       OpRecv,
+		ExpectedType: want,
+		ActualType:   d.typ,
+		Step:         ctx.step,
+		Serial:       ctx.serial,
+	}
+}
+
+// DelegateChan is the effect operation for delegating a typed endpoint.
+// Perform(DelegateChan[P, S]{Chan: c}) sends c to the peer, which must
+// accept it with AcceptChan as the same Chan[P, S]. Once the operation
+// completes, every operation on c's endpoint fails with ErrEndpointMoved;
+// the session continues through the handle the peer accepts.
+//
+// DelegateChan is a Send of Chan[P, S] for monitors and tracers. It fails
+// with a *DelegationError if c's endpoint is monitored and its residual
+// Spec is not the Spec of side S of P. Over a connection it passes c's
+// connection to the peer process as described at DialConn; on other
+// sessions that serialize payloads it fails with a *CodecError.
+type DelegateChan[P any, S Side] struct {
+	kont.Phantom[struct{}]
+	Chan Chan[P, S]
+}
+
+// DispatchSession handles DelegateChan on the session transport.
+// Non-blocking: returns iox.ErrWouldBlock if the bounded SPSC queue is full.
+func (d DelegateChan[P, S]) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
+	ep := d.Chan.ep
+	if ep.ctx.ext.moved {
+		return nil, ErrEndpointMoved
+	}
+	if m := ep.ctx.ext.mon; m != nil {
+		if want := SpecOf[P, S](); !m.cur.equal(want) {
+			return nil, &DelegationError{Serial: ctx.serial, Step: ctx.step, Declared: want, Residual: m.cur}
+		}
+	}
+	typ := reflect.TypeFor[Chan[P, S]]()
+	if ctx.ext.wire != nil {
+		return wireDelegate(ctx, ep, typ)
+	}
+	if ctx.ext.codec != nil {
+		return nil, &CodecError{Type: typ, Err: errNotDelegable}
+	}
+	o := ep.ctx.ext.owner
+	if o != nil {
+		if err := o.hand(); err != nil {
+			return nil, err
+		}
+	}
+	rd, wr := ep.ctx.ready().readable.Load(), ep.ctx.ready().writable.Load()
+	ctx.sendSlot = delegation{ep: ep.ctx.handoff(), typ: typ}
+	if err := ctx.sendQ.Enqueue(&ctx.sendSlot); err != nil {
+		if o != nil {
+			o.release()
+		}
+		return nil, err
+	}
+	ep.ctx.retire(rd, wr)
+	return struct{}{}, nil
+}
+
+// retire marks ctx's handle as moved once its handoff has been queued.
+// Its readiness callbacks rd and wr stay behind, unless the receiver has
+// already replaced them; the dead handle cannot register others.
+func (ctx *sessionContext) retire(rd, wr *func()) {
+	ctx.ready().readable.CompareAndSwap(rd, nil)
+	ctx.ready().writable.CompareAndSwap(wr, nil)
+	ctx.extend().moved = true
+}
+
+// handoff returns the handle that a delegation of ctx's endpoint carries.
+// It takes over the session at ctx's position in the protocol, with its
+// own monitor and ownership token; the tracer, metrics and recorder set on
+// the delegator's handle stay behind.
+func (ctx *sessionContext) handoff() *Endpoint {
+	h := &Endpoint{ctx: sessionContext{
+		sendQ:    ctx.sendQ,
+		recvQ:    ctx.recvQ,
+		signalQ:  ctx.signalQ,
+		awaitQ:   ctx.awaitQ,
+		state:    ctx.state,
+		serial:   ctx.serial,
+		step:     ctx.step,
+		ext:      &noExt,
+		closeBit: ctx.closeBit,
+	}}
+	if ctx.ext.wire != nil {
+		h.ctx.extend().wire = ctx.ext.wire
+	}
+	if ctx.ext.mon != nil {
+		h.ctx.extend().mon = &monitor{cur: ctx.ext.mon.cur}
+	}
+	if ctx.ext.clock != nil {
+		h.ctx.extend().clock = ctx.ext.clock
+	}
+	if ctx.ext.codec != nil {
+		h.ctx.extend().codec = ctx.ext.codec
+	}
+	if ctx.ext.owner != nil {
+		h.ctx.extend().owner = &ownership{}
+	}
+	return h
+}
+
+func (DelegateChan[P, S]) opInfo() (OpKind, reflect.Type) {
+	return OpSend, reflect.TypeFor[Chan[P, S]]()
+}
+
+// AcceptChan is the effect operation for accepting a delegated endpoint.
+// Perform(AcceptChan[P, S]{}) receives the Chan[P, S] the peer delegated.
+type AcceptChan[P any, S Side] struct {
+	kont.Phantom[Chan[P, S]]
+}
+
+// DispatchSession handles AcceptChan on the session transport.
+// Non-blocking: returns iox.ErrWouldBlock if the bounded SPSC queue is empty.
+// Anything but an endpoint delegated as Chan[P, S] is reported as a
+// *ProtocolViolation.
+func (AcceptChan[P, S]) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
+	v, err := ctx.recvQ.Dequeue()
+	if err != nil {
+		return nil, err
+	}
+	want := reflect.TypeFor[Chan[P, S]]()
+	d, ok := v.(delegation)
+	if !ok {
+		return nil, &ProtocolViolation{
+			Expected:     OpRecv,
+			Actual:       OpRecv,
+			ExpectedType: want,
+			ActualType:   reflect.TypeOf(v),
+			Step:         ctx.step,
+			Serial:       ctx.serial,
+		}
+	}
+	if err := d.check(ctx, want); err != nil {
+		return nil, err
+	}
+	return Chan[P, S]{ep: d.ep}, nil
+}
+
+func (AcceptChan[P, S]) opInfo() (OpKind, reflect.Type) { return OpRecv, reflect.TypeFor[Chan[P, S]]() }
+
+// DelegationError reports a DelegateChan of a monitored endpoint whose
+// residual Spec differs from the Spec of the Chan it was delegated as.
+type DelegationError struct {
+	Serial   Serial // session performing the DelegateChan
+	Step     int
+	Declared *Spec // Spec of the delegated Chan type
+	Residual *Spec // remaining Spec of the delegated endpoint's monitor
+}
+
+// Error implements error.
+func (e *DelegationError) Error() string {
+	return "sess: delegation in session " + strconv.FormatUint(uint64(e.Serial), 10) +
+		" at step " + strconv.Itoa(e.Step) +
+		": endpoint declared as " + e.Declared.String() +
+		" has residual protocol " + e.Residual.String()
+}
+
+// DelegateThen delegates c to the peer and continues with next.
+// Fuses Perform(DelegateChan[P, S]{Chan: c}) + Then.
+func DelegateThen[P any, S Side, B any](c Chan[P, S], next kont.Eff[B]) kont.Eff[B] {
+	return kont.Then(kont.Perform(DelegateChan[P, S]{Chan: c}), next)
+}
+
+// AcceptBind accepts a delegated Chan[P, S] and passes it to f.
+// Fuses Perform(AcceptChan[P, S]{}) + Bind.
+func AcceptBind[P any, S Side, B any](f func(Chan[P, S]) kont.Eff[B]) kont.Eff[B] {
+	return kont.Bind(kont.Perform(AcceptChan[P, S]{}), f)
+}
+
+// ExprDelegateThen delegates c to the peer and continues with next.
+// Fuses ExprPerform(DelegateChan[P, S]{Chan: c}) + ExprThen.
+func ExprDelegateThen[P any, S Side, B any](c Chan[P, S], next kont.Expr[B]) kont.Expr[B] {
+	tf := kont.AcquireThenFrame()
+	tf.Second = kont.Expr[kont.Erased]{Value: kont.Erased(next.Value), Frame: next.Frame}
+	tf.Next = exprReturnFrame
+	ef := kont.AcquireEffectFrame()
+	ef.Operation = DelegateChan[P, S]{Chan: c}
+	ef.Resume = identityResume
+	ef.Next = tf
+	return kont.ExprSuspend[B](ef)
+}
+
+// ExprAcceptBind accepts a delegated Chan[P, S] and passes it to f.
+// Fuses ExprPerform(AcceptChan[P, S]{}) + ExprBind.
+func ExprAcceptBind[P any, S Side, B any](f func(Chan[P, S]) kont.Expr[B]) kont.Expr[B] {
+	bf := kont.AcquireUnwindFrame()
+	bf.Data1 = f
+	bf.Unwind = recvBindUnwind[Chan[P, S], B]
+	ef := kont.AcquireEffectFrame()
+	ef.Operation = AcceptChan[P, S]{}
+	ef.Resume = identityResume
+	ef.Next = bf
+	return kont.ExprSuspend[B](ef)
+}
+
+// ChanDelegateThen delegates d to the peer and continues with the Chan of
+// the next step. Type-checks only when the holder of c is the sender of
+// the current step and that step carries exactly d's type, so the
+// acceptor's residual protocol is checked at compile time.
+func ChanDelegateThen[S Side, P any, DS Side, N, B any](c Chan[Msg[S, Chan[P, DS], N], S], d Chan[P, DS], next func(Chan[N, S]) kont.Eff[B]) kont.Eff[B] {
+	return kont.Bind(kont.Perform(DelegateChan[P, DS]{Chan: d}), func(struct{}) kont.Eff[B] {
+		return next(Chan[N, S]{ep: c.ep})
+	})
+}
+
+// ChanAcceptBind accepts the Chan delegated in the current step and passes
+// it to f with the Chan of the next step.
+// Type-checks only when the peer of c is the sender of the current step.
+func ChanAcceptBind[S Dual[O], O Side, P any, DS Side, N, B any](c Chan[Msg[O, Chan[P, DS], N], S], f func(Chan[P, DS], Chan[N, S]) kont.Eff[B]) kont.Eff[B] {
+	return AcceptBind(func(d Chan[P, DS]) kont.Eff[B] {
+		return f(d, Chan[N, S]{ep: c.ep})
+	})
+}
@@ -5,6 +5,10 @@
 package sess_test
 
 import (
+	"errors"
+	randv1 "math/rand"
+	randv2 "math/rand/v2"
+	"reflect"
 	"testing"
 
 	"code.hybscloud.com/kont"
@@ -186,3 +190,179 @@ func TestDelegStepping(t *testing.T) {
 		t.Fatalf("C got %d, want 99", cResult)
 	}
 }
+
+// subP is the protocol of the delegated sessions: SideA sends an int and
+// receives it doubled.
+type subP = sess.SendP[int, sess.RecvP[int, sess.EndP]]
+
+func subClient(c sess.Chan[subP, sess.SideA]) kont.Eff[int] {
+	return sess.ChanSendThen(c, 21, func(c sess.Chan[sess.RecvP[int, sess.EndP], sess.SideA]) kont.Eff[int] {
+		return sess.ChanRecvBind(c, func(n int, c sess.Chan[sess.EndP, sess.SideA]) kont.Eff[int] {
+			return sess.ChanCloseDone(c, n)
+		})
+	})
+}
+
+func subServer(c sess.Chan[subP, sess.SideB]) kont.Eff[int] {
+	return sess.ChanRecvBind(c, func(n int, c sess.Chan[sess.RecvP[int, sess.EndP], sess.SideB]) kont.Eff[int] {
+		return sess.ChanSendThen(c, n*2, func(c sess.Chan[sess.EndP, sess.SideB]) kont.Eff[int] {
+			return sess.ChanCloseDone(c, n)
+		})
+	})
+}
+
+func TestTypedDelegation(t *testing.T) {
+	subA, subB := sess.NewChan[subP]()
+	epA, epB := sess.New()
+	_, accepted := advancePair(t, epA, epB,
+		sess.ExprDelegateThen(subA, sess.ExprCloseDone("delegated")),
+		sess.ExprAcceptBind(func(c sess.Chan[subP, sess.SideA]) kont.Expr[sess.Chan[subP, sess.SideA]] {
+			return sess.ExprCloseDone(c)
+		}),
+	)
+	if accepted.Endpoint() == subA.Endpoint() {
+		t.Fatal("accepted the delegator's handle")
+	}
+	if accepted.Endpoint().Serial() != subA.Endpoint().Serial() {
+		t.Fatal("accepted handle belongs to another session")
+	}
+
+	_, _, err := sess.Advance(subA.Endpoint(), stepSusp(sess.ExprSendThen(1, kont.ExprReturn(struct{}{}))))
+	if err != sess.ErrEndpointMoved {
+		t.Fatalf("delegated handle got %v, want ErrEndpointMoved", err)
+	}
+
+	got, n := advancePair(t, accepted.Endpoint(), subB.Endpoint(),
+		sess.Reify(subClient(accepted)), sess.Reify(subServer(subB)))
+	if got != 42 || n != 21 {
+		t.Fatalf("delegated session got %d and %d, want 42 and 21", got, n)
+	}
+}
+
+func TestTypedDelegationHandoff(t *testing.T) {
+	subA, subB := sess.NewChan[subP]()
+	m := sess.NewMetrics()
+	subA.Endpoint().SetMetrics(m)
+	stale := 0
+	subA.Endpoint().OnWritable(func() { stale++ })
+
+	epA, epB := sess.New()
+	_, accepted := advancePair(t, epA, epB,
+		sess.ExprDelegateThen(subA, sess.ExprCloseDone("delegated")),
+		sess.ExprAcceptBind(func(c sess.Chan[subP, sess.SideA]) kont.Expr[sess.Chan[subP, sess.SideA]] {
+			return sess.ExprCloseDone(c)
+		}),
+	)
+	woken := 0
+	accepted.Endpoint().OnWritable(func() { woken++ })
+	// Registering on the dead handle leaves the accepted one alone.
+	subA.Endpoint().OnWritable(nil)
+
+	got, _ := advancePair(t, accepted.Endpoint(), subB.Endpoint(),
+		sess.Reify(subClient(accepted)), sess.Reify(subServer(subB)))
+	if got != 42 {
+		t.Fatalf("delegated session got %d, want 42", got)
+	}
+	if stale != 0 || woken == 0 {
+		t.Fatalf("delegator's callback fired %d times, accepted's %d, want 0 and some", stale, woken)
+	}
+	if s := m.Snapshot(); s.Sent != 0 || s.Received != 0 {
+		t.Fatalf("delegator's metrics counted %+v", s)
+	}
+}
+
+func TestTypedDelegationChan(t *testing.T) {
+	type outerP = sess.SendP[sess.Chan[subP, sess.SideA], sess.EndP]
+	subA, subB := sess.NewChan[subP]()
+	_, accepted := sess.RunChan(
+		func(c sess.Chan[outerP, sess.SideA]) kont.Eff[struct{}] {
+			return sess.ChanDelegateThen(c, subA, func(c sess.Chan[sess.EndP, sess.SideA]) kont.Eff[struct{}] {
+				return sess.ChanCloseDone(c, struct{}{})
+			})
+		},
+		func(c sess.Chan[outerP, sess.SideB]) kont.Eff[sess.Chan[subP, sess.SideA]] {
+			return sess.ChanAcceptBind(c, func(d sess.Chan[subP, sess.SideA], c sess.Chan[sess.EndP, sess.SideB]) kont.Eff[sess.Chan[subP, sess.SideA]] {
+				return sess.ChanCloseDone(c, d)
+			})
+		},
+	)
+	got, _ := advancePair(t, accepted.Endpoint(), subB.Endpoint(),
+		sess.Reify(subClient(accepted)), sess.Reify(subServer(subB)))
+	if got != 42 {
+		t.Fatalf("delegated session got %d, want 42", got)
+	}
+}
+
+func TestTypedDelegationMismatch(t *testing.T) {
+	subA, _ := sess.NewChan[subP]()
+	epA, epB := sess.New()
+	if _, _, err := sess.Advance(epA, stepSusp(sess.ExprDelegateThen(subA, kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := sess.Advance(epB, stepSusp(sess.ExprAcceptBind(func(c sess.Chan[subP, sess.SideB]) kont.Expr[struct{}] {
+		return kont.ExprReturn(struct{}{})
+	})))
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) || pv.ExpectedType != reflect.TypeFor[sess.Chan[subP, sess.SideB]]() ||
+		pv.ActualType != reflect.TypeFor[sess.Chan[subP, sess.SideA]]() {
+		t.Fatalf("accepting the wrong side got %v", err)
+	}
+
+	epA, epB = sess.New()
+	untyped, _ := sess.New()
+	sess.Advance(epA, stepSusp(sess.ExprSendThen(untyped, kont.ExprReturn(struct{}{}))))
+	_, _, err = sess.Advance(epB, stepSusp(sess.ExprAcceptBind(func(c sess.Chan[subP, sess.SideA]) kont.Expr[struct{}] {
+		return kont.ExprReturn(struct{}{})
+	})))
+	if !errors.As(err, &pv) || pv.ActualType != reflect.TypeFor[*sess.Endpoint]() {
+		t.Fatalf("accepting an untyped endpoint got %v", err)
+	}
+}
+
+func TestTypedDelegationResidual(t *testing.T) {
+	sub, _ := sess.NewMonitored(sess.SpecOf[subP, sess.SideA]())
+	if _, _, err := sess.Advance(sub, stepSusp(sess.ExprSendThen(1, kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatal(err)
+	}
+
+	epA, _ := sess.New()
+	_, _, err := sess.Advance(epA, stepSusp(sess.ExprDelegateThen(sess.ChanOf[subP, sess.SideA](sub), kont.ExprReturn(struct{}{}))))
+	var de *sess.DelegationError
+	if !errors.As(err, &de) || de.Residual.String() != "?int.end" {
+		t.Fatalf("delegating past the first step got %v", err)
+	}
+
+	rest := sess.ChanOf[sess.RecvP[int, sess.EndP], sess.SideA](sub)
+	if _, _, err := sess.Advance(epA, stepSusp(sess.ExprDelegateThen(rest, kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatalf("delegating the residual protocol got %v", err)
+	}
+	_, _, err = sess.Advance(epA, stepSusp(sess.ExprDelegateThen(rest, kont.ExprReturn(struct{}{}))))
+	if err != sess.ErrEndpointMoved {
+		t.Fatalf("delegating twice got %v, want ErrEndpointMoved", err)
+	}
+}
+
+func TestTypedDelegationSameName(t *testing.T) {
+	// Both types print as "rand.Rand".
+	sub, _ := sess.NewMonitored(sess.SpecSend[randv1.Rand](sess.SpecEnd()))
+	epA, _ := sess.New()
+	c := sess.ChanOf[sess.SendP[randv2.Rand, sess.EndP], sess.SideA](sub)
+	_, _, err := sess.Advance(epA, stepSusp(sess.ExprDelegateThen(c, kont.ExprReturn(struct{}{}))))
+	var de *sess.DelegationError
+	if !errors.As(err, &de) {
+		t.Fatalf("delegating as another package's type got %v, want *DelegationError", err)
+	}
+}
+
+func TestTypedDelegationCodec(t *testing.T) {
+	subA, _ := sess.NewChan[subP]()
+	epA, _ := sess.NewWithOptions(sess.WithCodec(sess.GobCodec{}))
+	_, _, err := sess.Advance(epA, stepSusp(sess.ExprDelegateThen(subA, kont.ExprReturn(struct{}{}))))
+	var ce *sess.CodecError
+	if !errors.As(err, &ce) {
+		t.Fatalf("got %v, want *CodecError", err)
+	}
+	if _, _, err := sess.Advance(subA.Endpoint(), stepSusp(sess.ExprSendThen(1, kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatalf("failed delegation moved the endpoint: %v", err)
+	}
+}
@@ -10,21 +10,62 @@
 // # Architecture
 //
 //   - Transport: Lock-free bounded SPSC queues via [code.hybscloud.com/lfq]. [New] creates an [Endpoint] pair.
+//     [NewWithOptions] configures queue capacities ([WithDataCapacity], [WithChoiceCapacity]), the serial,
+//     tracer, codec, monitor and recorder of a session.
+//   - Network: [Dial], [Accept], [DialConn] and [AcceptConn] frame the same operations over a [net.Conn],
+//     serializing payloads with a [Codec] ([GobCodec], [JSONCodec], [BinaryCodec], [BytesCodec]).
+//     A [Registry] tags payloads with stable [TypeID]s so type mismatches are detected before decoding.
+//     Over a Unix socket, network endpoints are delegated across processes by passing their connection
+//     with SCM_RIGHTS.
 //   - Non-blocking: Operations return [code.hybscloud.com/iox.ErrWouldBlock] on backpressure.
 //   - Execution: Dual-world API supporting closure-based (Cont-world) and defunctionalized (Expr-world) evaluation.
 //   - Error Handling: Session operations are non-blocking, while error operations short-circuit returning [code.hybscloud.com/kont.Either].
+//     A thrown error aborts the session, and the peer's pending operation resolves to Left([PeerFailed]);
+//     [CatchError] runs compensation logic that may use the same endpoint.
 //
 // # API Topologies
 //
-//   - Operations: [Send], [Recv], [Close], [SelectL], [SelectR], [Offer]. Endpoint delegation is [Send]/[Recv] of [*Endpoint].
+//   - Operations: [Send], [Recv], [Close], [SelectL], [SelectR], [Offer]. Endpoint delegation is [Send]/[Recv] of [*Endpoint];
+//     typed delegation ([DelegateThen], [AcceptBind]) transfers a [Chan] with its protocol and retires the
+//     sender's handle ([ErrEndpointMoved]).
+//     Labeled choice: [Select] sends a [Label]; [OfferLabel] receives it and [OfferCases] dispatches on it.
 //   - Cont-world: [SendThen], [RecvBind], [CloseDone], [SelectLThen], [SelectRThen], [OfferBranch].
 //   - Expr-world: Zero-allocation variants like [ExprSendThen], [ExprRecvBind], etc. Bridge via [Reify] and [Reflect].
+//   - Multiparty: [NewMulti] wires a channel between every pair of roles; [SendTo], [RecvFrom], [SelectTo] and [OfferFrom]
+//     address a [Role], and [RunMulti] interleaves all roles on one goroutine.
+//   - Global: a [Global] choreography is projected to each role's [Spec]; [NewMultiMonitored] enforces the projections.
 //   - Recursive: [Loop] and [ExprLoop] for trampoline-based iterative protocols.
+//   - Typed: [NewChan] and [RunChan] type endpoints by a protocol descriptor ([SendP], [RecvP], [ChooseP], [OfferP], [EndP]).
+//     [ChanSendThen], [ChanRecvBind], etc. accept only the next legal operation, so non-dual pairs fail to compile.
+//   - Monitoring: [NewMonitored] validates every dispatched operation against a runtime [Spec] (see [SpecOf]),
+//     reporting mismatches as [*ProtocolViolation].
 //
 // # Integration
 //
 //   - Stepping: [Step] and [Advance] (or [StepError]/[AdvanceError]) evaluate computations one effect at a time, making them easy to integrate with a proactor loop.
-//   - Blocking: [Exec], [Run] (and Error/Expr variants) wait past boundaries using adaptive backoff.
+//     [StepEff]/[StepEffError] step Cont-world protocols, and a [Stepper] or [ErrorStepper] embedded in event-loop state polls one to completion.
+//     [Endpoint.OnReadable] and [Endpoint.OnWritable] report when a blocked operation may progress.
+//   - Scheduling: a [Scheduler] multiplexes many sessions on one goroutine, parking those that would block
+//     until their peer progresses. An [Executor] spreads sessions over worker goroutines with work stealing.
+//   - Timeouts: [SendWithin], [RecvWithin] and [OfferWithin] yield a [Timeout] if the operation stays blocked
+//     for their duration; [Endpoint.SetClock] injects the [Clock] they and the blocking paths read and wait on.
+//     A [VirtualClock] with [NewSimScheduler] runs sessions in simulated time with a seeded interleaving order.
+//   - Tracing: a [Tracer] installed by [SetTracer] or [Endpoint.SetTracer] receives a [TraceEvent] for every
+//     dispatched operation; disabled tracing costs a nil check and an atomic load. Package sessotel exports
+//     traces as OpenTelemetry-shaped spans, one per endpoint, linked across the pair.
+//   - Metrics: [Endpoint.SetMetrics] counts operations, would-block attempts and backoff waits in a [Metrics],
+//     with a histogram of blocked time; [Metrics.Snapshot] copies them, and a Metrics is an expvar.Var.
+//   - Replay: a [Recorder] keeps a [Transcript] of every operation of a session; [NewReplayEndpoint] replays
+//     one side of it deterministically and reports the first mismatch as a [*DivergenceError].
+//   - Blocking: [Exec] (and Error/Expr variants) waits past boundaries using adaptive backoff.
+//     [Run] interleaves both sides and reports a stuck pair as [*DeadlockError]; [TryRun] returns it as an error.
+//   - Cancellation: [ExecContext], [RunContext] (and Error/Expr variants) observe a [context.Context], returning
+//     ctx.Err() and leaving the session [Endpoint.Aborted].
+//   - Lifecycle: After the peer closes, sends fail with [ErrPeerClosed], and receives fail with it once drained.
+//     [Endpoint.Abort] fails every later operation with an [*AbortError] matching [ErrSessionAborted];
+//     [Endpoint.State] reports the session's state.
+//   - Linearity: [SetLinearityChecks] or the sessdebug build tag give each endpoint an ownership token, so
+//     misuse fails with [ErrConcurrentUse], [ErrEndpointClosed] or [ErrEndpointMoved].
 //
 // # Example
 //
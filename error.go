@@ -5,6 +5,8 @@
 package sess
 
 import (
+	"context"
+
 	"code.hybscloud.com/iox"
 	"code.hybscloud.com/kont"
 )
@@ -15,13 +17,48 @@ import (
 type sessionErrorHandler[E, A any] struct {
 	ctx    *sessionContext
 	errCtx *kont.ErrorContext[E]
+	wait   *waitContext
 }
 
 // Dispatch implements kont.Handler for the composed Session+Error handler.
-// Dispatch order: Session → Error.
+// Dispatch order: Session → Catch → Error. A session operation that fails
+// because the peer threw resolves to Left as described at PeerFailed.
 func (h sessionErrorHandler[E, A]) Dispatch(op kont.Operation) (kont.Resumed, bool) {
 	if sop, ok := op.(sessionDispatcher); ok {
-		return dispatchWait(h.ctx, sop), true
+		var err error
+		if h.wait != nil {
+			v, ok := h.wait.dispatch(h.ctx, sop)
+			if ok {
+				return v, true
+			}
+			err = h.wait.err
+		} else {
+			v, e := waitDispatch(h.ctx, sop)
+			if e == nil {
+				return v, true
+			}
+			err = e
+		}
+		if e, ok := peerFailed[E](h.ctx, err); ok {
+			if h.wait != nil {
+				h.wait.err = nil
+			}
+			return kont.Left[E, A](e), false
+		}
+		if h.wait != nil {
+			return kont.Either[E, A]{}, false
+		}
+		panic(err)
+	}
+	if cop, ok := op.(catcher[E]); ok {
+		v, e, ok := cop.catchSession(h.ctx, h.wait)
+		if ok {
+			return v, true
+		}
+		if h.wait != nil && h.wait.err != nil {
+			return kont.Either[E, A]{}, false
+		}
+		return kont.Left[E, A](e), false
 	}
 	if eop, ok := op.(interface {
 		DispatchError(ctx *kont.ErrorContext[E]) (kont.Resumed, bool)
@@ -36,7 +73,9 @@ func (h sessionErrorHandler[E, A]) Dispatch(op kont.Operation) (kont.Resumed, bo
 }
 
 // ExecError runs a Cont-world session protocol with error handling on a pre-created endpoint.
-// Returns Either[E, R] — Right on success, Left on Throw.
+// Returns Either[E, R] — Right on success, Left on Throw or when the peer
+// failed (see PeerFailed). On Left the session is aborted with the error,
+// which the peer observes as PeerFailed.
 // Blocks on iox.ErrWouldBlock via adaptive backoff (iox.Backoff),
 // without spawning goroutines or creating channels.
 func ExecError[E, R any](ep *Endpoint, protocol kont.Eff[R]) kont.Either[E, R] {
@@ -44,8 +83,9 @@ func ExecError[E, R any](ep *Endpoint, protocol kont.Eff[R]) kont.Either[E, R] {
 		return kont.Right[E, R](r)
 	})
 	var errCtx kont.ErrorContext[E]
+	defer abortOnPanic(&ep.ctx)
 	h := sessionErrorHandler[E, R]{ctx: &ep.ctx, errCtx: &errCtx}
-	return kont.Handle(wrapped, h)
+	return failed(&ep.ctx, kont.Handle(wrapped, h))
 }
 
 func rightUnwind[E, R any](_, _, _, current kont.Erased) (kont.Erased, kont.Frame) {
@@ -63,56 +103,146 @@ func wrapRight[E, R any](protocol kont.Expr[R]) kont.Expr[kont.Either[E, R]] {
 }
 
 // ExecErrorExpr runs an Expr-world session protocol with error handling on a pre-created endpoint.
-// Returns Either[E, R] — Right on success, Left on Throw.
+// Returns Either[E, R] — Right on success, Left on Throw or when the peer
+// failed, aborting the session as ExecError does.
 // Blocks on iox.ErrWouldBlock via adaptive backoff (iox.Backoff),
 // without spawning goroutines or creating channels.
 func ExecErrorExpr[E, R any](ep *Endpoint, protocol kont.Expr[R]) kont.Either[E, R] {
 	wrapped := wrapRight[E, R](protocol)
 	var errCtx kont.ErrorContext[E]
+	defer abortOnPanic(&ep.ctx)
 	h := sessionErrorHandler[E, R]{ctx: &ep.ctx, errCtx: &errCtx}
-	return kont.HandleExpr(wrapped, h)
+	return failed(&ep.ctx, kont.HandleExpr(wrapped, h))
 }
 
 // RunError creates a session pair, runs both Cont-world protocols with error
-// handling, and returns both results as Either values. Interleaves execution
-// of both sides on the calling goroutine using adaptive backoff (iox.Backoff).
-// Does not spawn goroutines or create channels.
+// handling, and returns both results as Either values. A side that throws
+// aborts the session once the other side has received what it sent before
+// the Throw, and the other side's pending operation then fails as described
+// at PeerFailed. Interleaves execution
+// of both sides on the calling goroutine. Does not spawn goroutines or create
+// channels. Panics with a *DeadlockError if neither side can make progress.
 func RunError[E, A, B any](a kont.Eff[A], b kont.Eff[B]) (kont.Either[E, A], kont.Either[E, B]) {
 	return RunErrorExpr[E](Reify(a), Reify(b))
 }
 
 // RunErrorExpr creates a session pair, runs both Expr-world protocols with
 // error handling, and returns both results as Either values. Interleaves
-// execution of both sides on the calling goroutine using adaptive backoff
-// (iox.Backoff). Does not spawn goroutines or create channels.
+// execution of both sides on the calling goroutine. Does not spawn goroutines
+// or create channels. Panics with a *DeadlockError if neither side can make
+// progress.
 func RunErrorExpr[E, A, B any](a kont.Expr[A], b kont.Expr[B]) (kont.Either[E, A], kont.Either[E, B]) {
+	resultA, resultB, err := TryRunErrorExpr[E](a, b)
+	if err != nil {
+		panic(err)
+	}
+	return resultA, resultB
+}
+
+// TryRunError is like RunError but returns a deadlock or terminal operation
+// failure as an error instead of panicking.
+func TryRunError[E, A, B any](a kont.Eff[A], b kont.Eff[B]) (kont.Either[E, A], kont.Either[E, B], error) {
+	return TryRunErrorExpr[E](Reify(a), Reify(b))
+}
+
+// TryRunErrorExpr is like RunErrorExpr but returns a deadlock or terminal
+// operation failure as an error instead of panicking.
+func TryRunErrorExpr[E, A, B any](a kont.Expr[A], b kont.Expr[B]) (kont.Either[E, A], kont.Either[E, B], error) {
 	epA, epB := New()
+	return tryRunErrorExpr[E](context.Background(), epA, epB, a, b)
+}
+
+// RunErrorWithOptions is RunError on a pair created by
+// NewWithOptions(opts...).
+func RunErrorWithOptions[E, A, B any](a kont.Eff[A], b kont.Eff[B], opts ...Option) (kont.Either[E, A], kont.Either[E, B]) {
+	resultA, resultB, err := TryRunErrorWithOptions[E](a, b, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return resultA, resultB
+}
+
+// TryRunErrorWithOptions is TryRunError on a pair created by
+// NewWithOptions(opts...).
+func TryRunErrorWithOptions[E, A, B any](a kont.Eff[A], b kont.Eff[B], opts ...Option) (kont.Either[E, A], kont.Either[E, B], error) {
+	epA, epB := NewWithOptions(opts...)
+	return tryRunErrorExpr[E](context.Background(), epA, epB, Reify(a), Reify(b))
+}
+
+// tryRunErrorExpr interleaves a on epA and b on epB with error handling,
+// observing ctx between rounds. Deadlocks are detected, and timed
+// operations waited for, as in tryRunExpr. A side that ends with Left
+// aborts the session only once the other side waits for it or is done,
+// as an abort frame follows the data sent before it over a connection.
+func tryRunErrorExpr[E, A, B any](ctx context.Context, epA, epB *Endpoint, a kont.Expr[A], b kont.Expr[B]) (kont.Either[E, A], kont.Either[E, B], error) {
+	done := ctx.Done()
 	resultA, suspA := StepError[E, A](a)
 	resultB, suspB := StepError[E, B](b)
-	var bo iox.Backoff
+	failA, failB := suspA == nil && resultA.IsLeft(), suspB == nil && resultB.IsLeft()
+	var err error
+	bo := backoff{clock: epA.ctx.ext.clock}
 	for suspA != nil || suspB != nil {
+		if isDone(done) {
+			err = ctx.Err()
+			epA.ctx.abort(err)
+			break
+		}
 		progress := false
 		if suspA != nil {
-			var err error
-			resultA, suspA, err = AdvanceError[E](epA, suspA)
+			resultA, suspA, err = advanceError[E](&epA.ctx, suspA, 0)
 			if err == nil {
 				progress = true
+				failA = suspA == nil && resultA.IsLeft()
+			} else if err != iox.ErrWouldBlock {
+				break
 			}
 		}
 		if suspB != nil {
-			var err error
-			resultB, suspB, err = AdvanceError[E](epB, suspB)
+			resultB, suspB, err = advanceError[E](&epB.ctx, suspB, 0)
 			if err == nil {
 				progress = true
+				failB = suspB == nil && resultB.IsLeft()
+			} else if err != iox.ErrWouldBlock {
+				break
+			}
+		}
+		err = nil
+		if progress {
+			continue
+		}
+		if failA || failB {
+			// The side left waiting observes the failure.
+			if failA {
+				failed(&epA.ctx, resultA)
 			}
+			if failB {
+				failed(&epB.ctx, resultB)
+			}
+			failA, failB = false, false
+			continue
 		}
-		if !progress {
+		if timedSusp(&epA.ctx, suspA) || timedSusp(&epB.ctx, suspB) {
+			// A timed operation completes once its deadline passes.
 			bo.Wait()
-		} else {
-			bo.Reset()
+			continue
+		}
+		err = &DeadlockError{
+			Serial: epA.Serial(),
+			A:      pendingOf(&epA.ctx, suspA),
+			B:      pendingOf(&epB.ctx, suspB),
 		}
+		break
 	}
-	return resultA, resultB
+	if err != nil {
+		discard(suspA)
+		discard(suspB)
+		var zeroA kont.Either[E, A]
+		var zeroB kont.Either[E, B]
+		return zeroA, zeroB, err
+	}
+	failed(&epA.ctx, resultA)
+	failed(&epB.ctx, resultB)
+	return resultA, resultB, nil
 }
 
 // StepError evaluates a session protocol with error support until the first
@@ -123,28 +253,62 @@ func StepError[E, R any](protocol kont.Expr[R]) (kont.Either[E, R], *kont.Suspen
 }
 
 // AdvanceError dispatches the suspended operation on the endpoint.
-// Session ops are non-blocking (ErrWouldBlock). Error ops are eager:
-// Throw discards the suspension and returns Left.
+// Session ops are non-blocking (ErrWouldBlock); one that fails because the
+// peer threw discards the suspension and returns Left (see PeerFailed).
+// Error ops are eager: Throw discards the suspension, aborts the session
+// with the error so that the peer observes it, and returns Left. Catch is
+// stepped like the rest of the protocol: each call advances its body or
+// handler by one operation, returning the same suspension until the Catch
+// completes.
 func AdvanceError[E, R any](ep *Endpoint, susp *kont.Suspension[kont.Either[E, R]]) (kont.Either[E, R], *kont.Suspension[kont.Either[E, R]], error) {
+	result, next, err := advanceError[E](&ep.ctx, susp, 0)
+	if err == nil && next == nil {
+		failed(&ep.ctx, result)
+	}
+	return result, next, err
+}
+
+// advanceError is AdvanceError for a suspension inside d Catch operations
+// in progress, which leaves it to the caller to abort the session on Left.
+func advanceError[E, R any](ctx *sessionContext, susp *kont.Suspension[kont.Either[E, R]], d int) (kont.Either[E, R], *kont.Suspension[kont.Either[E, R]], error) {
 	// Session ops: non-blocking dispatch
 	if sop, ok := susp.Op().(sessionDispatcher); ok {
-		v, err := sop.DispatchSession(&ep.ctx)
+		v, err := ctx.dispatch(sop)
 		if err != nil {
+			if e, ok := peerFailed[E](ctx, err); ok {
+				susp.Discard()
+				return kont.Left[E, R](e), nil, nil
+			}
 			var zero kont.Either[E, R]
 			return zero, susp, err
 		}
 		result, next := susp.Resume(v)
 		return result, next, nil
 	}
+	// Catch: one step of its body or handler
+	if cop, ok := susp.Op().(catcher[E]); ok {
+		r, done, err := cop.stepCatch(ctx, susp, d)
+		if err != nil || !done {
+			var zero kont.Either[E, R]
+			return zero, susp, err
+		}
+		if e, ok := r.GetLeft(); ok {
+			susp.Discard()
+			return kont.Left[E, R](e), nil, nil
+		}
+		v, _ := r.GetRight()
+		result, next := susp.Resume(v)
+		return result, next, nil
+	}
 	// Error ops: eager dispatch
 	if eop, ok := susp.Op().(interface {
 		DispatchError(ctx *kont.ErrorContext[E]) (kont.Resumed, bool)
 	}); ok {
-		var ctx kont.ErrorContext[E]
-		v, _ := eop.DispatchError(&ctx)
-		if ctx.HasErr {
+		var errCtx kont.ErrorContext[E]
+		v, _ := eop.DispatchError(&errCtx)
+		if errCtx.HasErr {
 			susp.Discard()
-			return kont.Left[E, R](ctx.Err), nil, nil
+			return kont.Left[E, R](errCtx.Err), nil, nil
 		}
 		result, next := susp.Resume(v)
 		return result, next, nil
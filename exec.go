@@ -11,7 +11,10 @@ import (
 // Exec runs a Cont-world session protocol on a pre-created endpoint.
 // Blocks on iox.ErrWouldBlock via adaptive backoff (iox.Backoff),
 // without spawning goroutines or creating channels.
+// If the protocol panics, the session is aborted before the panic
+// propagates, so the peer fails with ErrSessionAborted.
 func Exec[R any](ep *Endpoint, protocol kont.Eff[R]) R {
+	defer abortOnPanic(&ep.ctx)
 	h := sessionHandler[R]{ctx: &ep.ctx}
 	return kont.Handle(protocol, h)
 }
@@ -20,6 +23,7 @@ func Exec[R any](ep *Endpoint, protocol kont.Eff[R]) R {
 // Blocks on iox.ErrWouldBlock via adaptive backoff (iox.Backoff),
 // without spawning goroutines or creating channels.
 func ExecExpr[R any](ep *Endpoint, protocol kont.Expr[R]) R {
+	defer abortOnPanic(&ep.ctx)
 	h := sessionHandler[R]{ctx: &ep.ctx}
 	return kont.HandleExpr(protocol, h)
 }
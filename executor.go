@@ -0,0 +1,310 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/lfq"
+)
+
+// ErrExecutorClosed is the panic value of Submit after Close.
+var ErrExecutorClosed = errors.New("sess: executor closed")
+
+// workerQueueCapacity bounds each worker's local ready queue; overflow
+// goes to the executor's shared queue.
+const workerQueueCapacity = 256
+
+// execBudget is the number of operations a worker performs on a session
+// before moving on to the next one.
+const execBudget = 32
+
+// Run states of an executor session. A session is in at most one queue,
+// and only the worker that dequeued it advances it, so each endpoint is
+// advanced by one goroutine at a time as its SPSC queues require.
+const (
+	execQueued   uint32 = iota // in a ready queue
+	execRunning                // being advanced by a worker
+	execNotified               // running, and readiness fired meanwhile
+	execParked                 // blocked, waiting for readiness
+)
+
+// Executor runs sessions on a fixed set of worker goroutines. Each worker
+// advances the sessions in its local lock-free ready queue, and steals
+// from the other workers' queues when its own runs empty. A session that
+// would block is parked and rescheduled when its endpoint's readiness
+// callbacks fire (see Endpoint.OnReadable), so it may move to another
+// worker; the Executor owns the readiness callbacks of submitted
+// endpoints.
+//
+// Unlike Scheduler, an Executor suits CPU-heavy protocol bodies: sessions
+// progress in parallel. A session blocked in a timed operation is parked
+// until its endpoint is ready or its deadline is due; on an injected Clock,
+// which does not follow real time, it is retried after a backoff instead.
+// Sessions deadlocked with each other stay parked.
+type Executor struct {
+	workers []*worker
+	mu      sync.Mutex
+	global  []*execTask // shared queue: submissions, wakeups and overflow
+	wake    chan struct{}
+	idle    atomic.Int32
+	quit    chan struct{}
+	closed  atomic.Bool
+	pending sync.WaitGroup
+	done    sync.WaitGroup
+}
+
+// worker is an executor goroutine and its local ready queue.
+type worker struct {
+	id    int
+	local lfq.MPMC[*execTask]
+}
+
+// execTask is a submitted session.
+type execTask struct {
+	t       schedTask
+	state   atomic.Uint32
+	retries int // consecutive parks in a timed operation on an injected clock
+}
+
+// retryIn returns how long x, blocked in a timed operation, stays parked
+// before it is retried: until its deadline on the system clock, or for a
+// growing backoff on an injected clock.
+func (x *execTask) retryIn() time.Duration {
+	ctx := x.t.context()
+	if ctx.ext.clock == nil && ctx.ext.timer.armed && ctx.ext.timer.step == ctx.step {
+		return max(time.Until(ctx.ext.timer.at), 0)
+	}
+	x.retries++
+	return min(time.Duration(x.retries)*iox.DefaultBackoffBase, iox.DefaultBackoffMax)
+}
+
+// NewExecutor starts an Executor with n workers, or runtime.GOMAXPROCS(0)
+// workers if n <= 0. Close stops them.
+func NewExecutor(n int) *Executor {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	e := &Executor{
+		workers: make([]*worker, n),
+		wake:    make(chan struct{}, n),
+		quit:    make(chan struct{}),
+	}
+	for i := range e.workers {
+		w := &worker{id: i}
+		w.local.Init(workerQueueCapacity)
+		e.workers[i] = w
+	}
+	e.done.Add(n)
+	for _, w := range e.workers {
+		go e.work(w)
+	}
+	return e
+}
+
+// Submit runs a Cont-world protocol on ep on the executor. onDone, if
+// non-nil, is called on a worker goroutine with the protocol's result, or
+// with the zero value and the error that failed the session. As with
+// Scheduler, a failed session is aborted unless the peer has closed.
+// Submit panics with ErrExecutorClosed after Close.
+func Submit[R any](e *Executor, ep *Endpoint, protocol kont.Eff[R], onDone func(R, error)) {
+	SubmitExpr(e, ep, Reify(protocol), onDone)
+}
+
+// SubmitExpr runs an Expr-world protocol on ep on the executor.
+// See Submit.
+func SubmitExpr[R any](e *Executor, ep *Endpoint, protocol kont.Expr[R], onDone func(R, error)) {
+	if e.closed.Load() {
+		panic(ErrExecutorClosed)
+	}
+	t := &task[R]{ep: ep, onDone: onDone}
+	t.result, t.susp = Step(protocol)
+	x := &execTask{t: t}
+	notify := func() { e.notify(x) }
+	ep.OnReadable(notify)
+	ep.OnWritable(notify)
+	e.pending.Add(1)
+	e.inject(x)
+}
+
+// Wait blocks until every submitted session has completed or failed.
+func (e *Executor) Wait() {
+	e.pending.Wait()
+}
+
+// Close stops the workers once they finish their current session and
+// waits for them to exit. Sessions still pending are not advanced
+// further, so Wait must not be called for them after Close.
+func (e *Executor) Close() {
+	if e.closed.CompareAndSwap(false, true) {
+		close(e.quit)
+	}
+	e.done.Wait()
+}
+
+// work is the loop of worker w.
+func (e *Executor) work(w *worker) {
+	defer e.done.Done()
+	for {
+		x := e.next(w)
+		if x == nil {
+			if !e.sleep(w) {
+				return
+			}
+			continue
+		}
+		e.run(w, x)
+	}
+}
+
+// next returns the next session for w: from its local queue, the shared
+// queue, or another worker's queue, in that order.
+func (e *Executor) next(w *worker) *execTask {
+	if x, err := w.local.Dequeue(); err == nil {
+		return x
+	}
+	e.mu.Lock()
+	if len(e.global) > 0 {
+		x := e.global[0]
+		e.global[0] = nil
+		e.global = e.global[1:]
+		e.mu.Unlock()
+		return x
+	}
+	e.mu.Unlock()
+	for i := 1; i < len(e.workers); i++ {
+		victim := e.workers[(w.id+i)%len(e.workers)]
+		if x, err := victim.local.Dequeue(); err == nil {
+			return x
+		}
+	}
+	return nil
+}
+
+// sleep waits until work may be available. It returns false once the
+// executor is closed.
+func (e *Executor) sleep(w *worker) bool {
+	e.idle.Add(1)
+	defer e.idle.Add(-1)
+	// A session queued between next and the increment above signaled no
+	// one; look once more before blocking.
+	if x := e.next(w); x != nil {
+		e.run(w, x)
+		return true
+	}
+	select {
+	case <-e.wake:
+		return true
+	case <-e.quit:
+		return false
+	}
+}
+
+// run advances x by up to execBudget operations.
+func (e *Executor) run(w *worker, x *execTask) {
+	x.state.Store(execRunning)
+	for range execBudget {
+		done, err := x.t.advance()
+		switch {
+		case err == iox.ErrWouldBlock && x.t.timed():
+			// No callback fires when a deadline passes: park with a
+			// timer standing in for one.
+			d := x.retryIn()
+			if !x.state.CompareAndSwap(execRunning, execParked) {
+				x.state.Store(execQueued)
+				e.push(w, x)
+				return
+			}
+			time.AfterFunc(d, func() {
+				if !e.closed.Load() {
+					e.notify(x)
+				}
+			})
+			return
+		case err == iox.ErrWouldBlock:
+			if !x.state.CompareAndSwap(execRunning, execParked) {
+				// Readiness fired while running: retry later.
+				x.state.Store(execQueued)
+				e.push(w, x)
+			}
+			return
+		case err != nil:
+			if err != ErrPeerClosed {
+				x.t.context().abort(err)
+			}
+			e.exit(x, err)
+			return
+		case done:
+			e.exit(x, nil)
+			return
+		}
+		x.retries = 0
+	}
+	x.state.Store(execQueued)
+	e.push(w, x)
+}
+
+// exit releases a finished session's endpoint and reports its outcome.
+func (e *Executor) exit(x *execTask, err error) {
+	if ctx := x.t.context(); !ctx.ext.moved {
+		ready := ctx.ready()
+		ready.readable.Store(nil)
+		ready.writable.Store(nil)
+	}
+	x.t.finish(err)
+	e.pending.Done()
+}
+
+// notify is the readiness callback of x: a parked session is queued again,
+// a running one is marked to be retried.
+func (e *Executor) notify(x *execTask) {
+	for {
+		switch x.state.Load() {
+		case execParked:
+			if x.state.CompareAndSwap(execParked, execQueued) {
+				e.inject(x)
+				return
+			}
+		case execRunning:
+			if x.state.CompareAndSwap(execRunning, execNotified) {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// push queues x on w's local queue, or on the shared queue if it is full.
+func (e *Executor) push(w *worker, x *execTask) {
+	if w.local.Enqueue(&x) != nil {
+		e.inject(x)
+		return
+	}
+	if e.idle.Load() > 0 {
+		e.signal()
+	}
+}
+
+// inject queues x on the shared queue and wakes an idle worker.
+func (e *Executor) inject(x *execTask) {
+	e.mu.Lock()
+	e.global = append(e.global, x)
+	e.mu.Unlock()
+	e.signal()
+}
+
+func (e *Executor) signal() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
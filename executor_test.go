@@ -0,0 +1,153 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+func TestExecutorManySessions(t *testing.T) {
+	skipRace(t)
+	const sessions = 500
+	const rounds = 20
+	e := sess.NewExecutor(4)
+	defer e.Close()
+
+	var sum, completed atomic.Int64
+	for i := range sessions {
+		epA, epB := sess.New()
+		client := sess.ExprLoop(0, func(j int) kont.Expr[kont.Either[int, struct{}]] {
+			if j == rounds {
+				return sess.ExprSelectRThen(sess.ExprCloseDone(kont.Right[int, struct{}](struct{}{})))
+			}
+			return sess.ExprSelectLThen(sess.ExprSendThen(i, kont.ExprReturn(kont.Left[int, struct{}](j+1))))
+		})
+		server := sess.ExprLoop(0, func(acc int) kont.Expr[kont.Either[int, int]] {
+			return sess.ExprOfferBranch(
+				func() kont.Expr[kont.Either[int, int]] {
+					return sess.ExprRecvBind(func(n int) kont.Expr[kont.Either[int, int]] {
+						return kont.ExprReturn(kont.Left[int, int](acc + n))
+					})
+				},
+				func() kont.Expr[kont.Either[int, int]] {
+					return sess.ExprCloseDone(kont.Right[int](acc))
+				},
+			)
+		})
+		sess.SubmitExpr(e, epA, client, func(_ struct{}, err error) {
+			if err != nil {
+				t.Errorf("client %d: %v", i, err)
+			}
+			completed.Add(1)
+		})
+		sess.SubmitExpr(e, epB, server, func(acc int, err error) {
+			if err != nil {
+				t.Errorf("server %d: %v", i, err)
+			}
+			sum.Add(int64(acc))
+			completed.Add(1)
+		})
+	}
+	e.Wait()
+	if got := completed.Load(); got != 2*sessions {
+		t.Fatalf("completed %d sessions, want %d", got, 2*sessions)
+	}
+	if got, want := sum.Load(), int64(rounds*sessions*(sessions-1)/2); got != want {
+		t.Fatalf("sum got %d, want %d", got, want)
+	}
+}
+
+func TestExecutorExternalPeer(t *testing.T) {
+	skipRace(t)
+	e := sess.NewExecutor(2)
+	defer e.Close()
+
+	epA, epB := sess.New()
+	var got atomic.Int64
+	sess.Submit(e, epB, sess.RecvBind(func(n int) kont.Eff[int] {
+		return sess.RecvBind(func(m int) kont.Eff[int] { return sess.CloseDone(n + m) })
+	}), func(n int, err error) { got.Store(int64(n)) })
+	sess.Exec(epA, sess.SendThen(20, sess.SendThen(22, sess.CloseDone(struct{}{}))))
+	e.Wait()
+	if got.Load() != 42 {
+		t.Fatalf("got %d, want 42", got.Load())
+	}
+}
+
+func TestExecutorFailure(t *testing.T) {
+	skipRace(t)
+	e := sess.NewExecutor(2)
+	defer e.Close()
+
+	epA, epB := sess.New()
+	errs := make(chan error, 2)
+	sess.Submit(e, epA, sess.SendThen("oops", sess.RecvBind(func(int) kont.Eff[struct{}] {
+		return sess.CloseDone(struct{}{})
+	})), func(_ struct{}, err error) { errs <- err })
+	sess.Submit(e, epB, sess.RecvBind(func(n int) kont.Eff[int] {
+		return sess.RecvBind(func(m int) kont.Eff[int] { return sess.CloseDone(n + m) })
+	}), func(_ int, err error) { errs <- err })
+	e.Wait()
+	var pv *sess.ProtocolViolation
+	var aborted bool
+	for range 2 {
+		// The abort wraps the violation that caused it.
+		if err := <-errs; errors.Is(err, sess.ErrSessionAborted) {
+			aborted = true
+		} else {
+			errors.As(err, &pv)
+		}
+	}
+	if pv == nil || !aborted {
+		t.Fatalf("got violation=%v aborted=%v, want both", pv, aborted)
+	}
+}
+
+func TestExecutorSubmitAfterClose(t *testing.T) {
+	e := sess.NewExecutor(1)
+	e.Close()
+	defer func() {
+		if r := recover(); r != sess.ErrExecutorClosed {
+			t.Fatalf("got panic %v, want ErrExecutorClosed", r)
+		}
+	}()
+	ep, _ := sess.New()
+	sess.Submit(e, ep, sess.CloseDone(0), nil)
+}
+
+func TestExecutorTimedParks(t *testing.T) {
+	skipRace(t)
+	e := sess.NewExecutor(2)
+	defer e.Close()
+	_, ep := sess.New()
+	m := sess.NewMetrics()
+	ep.SetMetrics(m)
+
+	done := make(chan string, 1)
+	start := time.Now()
+	sess.SubmitExpr(e, ep, sess.ExprRecvWithinBind(50*time.Millisecond, func(n int) kont.Expr[string] {
+		return kont.ExprReturn("received")
+	}, func(to sess.Timeout) kont.Expr[string] {
+		return kont.ExprReturn(to.String())
+	}), func(s string, err error) {
+		done <- s
+	})
+	if got := <-done; got != "Recv timed out after 50ms" {
+		t.Fatalf("got %q", got)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("timed out after %v, before the deadline", elapsed)
+	}
+	// Parked until the deadline rather than retried in a loop.
+	if n := m.Snapshot().WouldBlock; n > 4 {
+		t.Fatalf("%d attempts would block, want the session parked", n)
+	}
+}
@@ -0,0 +1,306 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+)
+
+// PeerFailed is the error a pending session operation resolves to under
+// ExecError, AdvanceError and RunError when the peer's protocol ended with
+// a thrown error: the peer's session was aborted with the thrown value, Err.
+//
+// A handler whose error type E is PeerFailed[X] receives the thrown value
+// as an X. A handler whose E is an interface that PeerFailed[E] implements,
+// such as error or any, receives Left(PeerFailed[E]{...}). For any other E
+// the peer's failure cannot be told apart from a local Throw, so it is not
+// a Left: the operation fails with the *AbortError, as it does when the
+// peer calls Endpoint.Abort. Over a connection the value is decoded with
+// the endpoint's codec; a value the codec cannot carry arrives as an error
+// holding its text.
+type PeerFailed[E any] struct {
+	Serial Serial // session the peer failed in
+	Err    E      // value thrown by the peer
+}
+
+// Error implements error.
+func (e PeerFailed[E]) Error() string {
+	return "sess: peer failed in session " + strconv.FormatUint(uint64(e.Serial), 10) + ": " + fmt.Sprint(e.Err)
+}
+
+// Unwrap returns Err if it is an error.
+func (e PeerFailed[E]) Unwrap() error {
+	err, _ := any(e.Err).(error)
+	return err
+}
+
+// peerFailure builds a PeerFailed from a thrown error; implemented by the
+// zero value of each PeerFailed type.
+type peerFailure interface {
+	fromThrown(ctx *sessionContext, t *thrownError) (any, bool)
+}
+
+func (PeerFailed[E]) fromThrown(ctx *sessionContext, t *thrownError) (any, bool) {
+	v, ok := thrownValue[E](ctx, t)
+	if !ok {
+		return nil, false
+	}
+	return PeerFailed[E]{Serial: ctx.serial, Err: v}, true
+}
+
+// thrownError is the abort reason of a session whose protocol ended with a
+// thrown error. In process it holds the thrown value; received over a
+// connection it holds the value as encoded by the peer's codec.
+type thrownError struct {
+	value  any
+	text   string
+	data   []byte
+	remote bool
+}
+
+// Error returns the text of the thrown value.
+func (t *thrownError) Error() string { return t.text }
+
+// Unwrap returns the thrown value if it is an error.
+func (t *thrownError) Unwrap() error {
+	err, _ := t.value.(error)
+	return err
+}
+
+// fail aborts the session on ctx with the value e thrown by its protocol.
+// A session that is already aborted keeps its reason.
+func (ctx *sessionContext) fail(e any) {
+	ctx.abort(&thrownError{value: e, text: fmt.Sprint(e)})
+}
+
+// failed aborts the session on ctx if a protocol run with error handling
+// ended with Left, so that the peer's pending operation observes it.
+func failed[E, R any](ctx *sessionContext, r kont.Either[E, R]) kont.Either[E, R] {
+	if e, ok := r.GetLeft(); ok {
+		ctx.fail(e)
+	}
+	return r
+}
+
+// thrownValue recovers the thrown value of t as an E.
+func thrownValue[E any](ctx *sessionContext, t *thrownError) (E, bool) {
+	var v E
+	if !t.remote {
+		v, ok := t.value.(E)
+		return v, ok
+	}
+	if len(t.data) > 0 && ctx.ext.codec != nil && ctx.ext.codec.Decode(t.data, &v) == nil {
+		return v, true
+	}
+	if err, ok := any(errors.New(t.text)).(E); ok {
+		return err, true
+	}
+	return v, false
+}
+
+// peerFailed converts err, the terminal error of a session operation on
+// ctx, into the Left value of a handler with error type E, if the session
+// was aborted by a value the peer threw. See PeerFailed.
+func peerFailed[E any](ctx *sessionContext, err error) (E, bool) {
+	var zero E
+	ae, ok := err.(*AbortError)
+	if !ok {
+		return zero, false
+	}
+	t, ok := ae.Reason.(*thrownError)
+	if !ok {
+		return zero, false
+	}
+	if pf, ok := any(zero).(peerFailure); ok {
+		v, ok := pf.fromThrown(ctx, t)
+		if !ok {
+			return zero, false
+		}
+		return v.(E), true
+	}
+	v, ok := thrownValue[E](ctx, t)
+	if !ok {
+		return zero, false
+	}
+	e, ok := any(PeerFailed[E]{Serial: ctx.serial, Err: v}).(E)
+	return e, ok
+}
+
+// encodeThrown returns the payload of a throw frame: the 4-byte big-endian
+// length of the text, the text, and the value encoded with ctx's codec,
+// or nothing if the codec cannot encode it.
+func encodeThrown(ctx *sessionContext, t *thrownError) []byte {
+	payload := binary.BigEndian.AppendUint32(nil, uint32(len(t.text)))
+	payload = append(payload, t.text...)
+	if data, err := ctx.ext.codec.Encode(t.value); err == nil {
+		payload = append(payload, data...)
+	}
+	return payload
+}
+
+// decodeThrown parses the payload of a throw frame.
+func decodeThrown(payload []byte) (*thrownError, error) {
+	if len(payload) < 4 || uint64(binary.BigEndian.Uint32(payload)) > uint64(len(payload)-4) {
+		return nil, errors.New("sess: malformed throw frame")
+	}
+	n := 4 + int(binary.BigEndian.Uint32(payload))
+	t := &thrownError{text: string(payload[4:n]), remote: true}
+	if n < len(payload) {
+		t.data = payload[n:]
+	}
+	return t, nil
+}
+
+// Catch is the effect operation for catching errors in a session protocol.
+// Perform(Catch[E, A]{Body: m, Handler: h}) runs m and, if it ends with a
+// thrown E or resolves to a PeerFailed E, runs h with it, for example to
+// undo what m did. Unlike kont.Catch, m and h may perform session
+// operations on the same endpoint. A caught error does not abort the
+// session; an error thrown by h propagates like one thrown outside Catch.
+//
+// Catch is handled by ExecError, ExecErrorExpr and their context-aware
+// variants, and by AdvanceError, which steps m and h one operation at a
+// time.
+type Catch[E, A any] struct {
+	kont.Phantom[A]
+	Body    kont.Expr[A]
+	Handler func(E) kont.Expr[A]
+}
+
+// catcher is implemented by Catch for handlers with error type E.
+type catcher[E any] interface {
+	catchSession(ctx *sessionContext, wait *waitContext) (kont.Resumed, E, bool)
+	stepCatch(ctx *sessionContext, owner any, d int) (kont.Either[E, kont.Resumed], bool, error)
+}
+
+// catchSession runs c on ctx. It returns false with the error if the
+// handler fails, or with a zero error if wait's context is done.
+func (c Catch[E, A]) catchSession(ctx *sessionContext, wait *waitContext) (kont.Resumed, E, bool) {
+	r := handleError[E, A](ctx, wait, c.Body)
+	if e, ok := r.GetLeft(); ok && (wait == nil || wait.err == nil) {
+		r = handleError[E, A](ctx, wait, c.Handler(e))
+	}
+	if e, ok := r.GetLeft(); ok {
+		return nil, e, false
+	}
+	v, _ := r.GetRight()
+	var zero E
+	return v, zero, true
+}
+
+// catchFrame is a Catch in progress under AdvanceError: the suspension of
+// its body, or of its handler once handling.
+type catchFrame struct {
+	owner    any // suspension performing the Catch
+	susp     suspended
+	handling bool
+}
+
+// suspended is implemented by every *kont.Suspension.
+type suspended interface {
+	Op() kont.Operation
+	Discard()
+}
+
+// stepCatch advances c, performed by owner inside d other Catch
+// operations in progress on ctx, by one operation. It returns true with
+// the result once c completes.
+func (c Catch[E, A]) stepCatch(ctx *sessionContext, owner any, d int) (kont.Either[E, kont.Resumed], bool, error) {
+	var zero kont.Either[E, kont.Resumed]
+	ext := ctx.extend()
+	if len(ext.catches) > d && ext.catches[d].owner != owner {
+		ctx.dropCatches(d) // left behind by a discarded suspension
+	}
+	var r kont.Either[E, A]
+	if len(ext.catches) == d {
+		var susp *kont.Suspension[kont.Either[E, A]]
+		r, susp = StepError[E, A](c.Body)
+		ext.catches = append(ext.catches, catchFrame{owner: owner})
+		if susp != nil {
+			ext.catches[d].susp = susp
+		}
+	}
+	if susp, ok := ext.catches[d].susp.(*kont.Suspension[kont.Either[E, A]]); ok {
+		var next *kont.Suspension[kont.Either[E, A]]
+		var err error
+		r, next, err = advanceError[E](ctx, susp, d+1)
+		if err != nil {
+			if err != iox.ErrWouldBlock {
+				ctx.dropCatches(d)
+			}
+			return zero, false, err
+		}
+		if next != nil {
+			ext.catches[d].susp = next
+			return zero, false, nil
+		}
+		ext.catches[d].susp = nil
+	}
+	if e, ok := r.GetLeft(); ok && !ext.catches[d].handling {
+		ext.catches[d].handling = true
+		var susp *kont.Suspension[kont.Either[E, A]]
+		r, susp = StepError[E, A](c.Handler(e))
+		if susp != nil {
+			ext.catches[d].susp = susp
+			return zero, false, nil
+		}
+	}
+	ext.catches = ext.catches[:d]
+	if e, ok := r.GetLeft(); ok {
+		return kont.Left[E, kont.Resumed](e), true, nil
+	}
+	v, _ := r.GetRight()
+	return kont.Right[E, kont.Resumed](v), true, nil
+}
+
+// dropCatches discards the Catch operations in progress on ctx from
+// depth d inward.
+func (ctx *sessionContext) dropCatches(d int) {
+	catches := ctx.ext.catches
+	for i := d; i < len(catches); i++ {
+		if catches[i].susp != nil {
+			catches[i].susp.Discard()
+		}
+		catches[i] = catchFrame{}
+	}
+	ctx.ext.catches = catches[:d]
+}
+
+// pendingOp returns the operation that susp waits for: its own, or if it
+// performs a Catch in progress, the pending operation of the innermost one.
+func (ctx *sessionContext) pendingOp(susp suspended) kont.Operation {
+	catches := ctx.ext.catches
+	if n := len(catches); n > 0 && catches[0].owner == susp && catches[n-1].susp != nil {
+		return catches[n-1].susp.Op()
+	}
+	return susp.Op()
+}
+
+// handleError runs protocol on ctx with a fresh error context.
+func handleError[E, A any](ctx *sessionContext, wait *waitContext, protocol kont.Expr[A]) kont.Either[E, A] {
+	var errCtx kont.ErrorContext[E]
+	h := sessionErrorHandler[E, A]{ctx: ctx, errCtx: &errCtx, wait: wait}
+	return kont.HandleExpr(wrapRight[E, A](protocol), h)
+}
+
+// CatchError runs body, catching a thrown E or a PeerFailed E with handler.
+// Performs Catch[E, A].
+func CatchError[E, A any](body kont.Eff[A], handler func(E) kont.Eff[A]) kont.Eff[A] {
+	return kont.Perform(Catch[E, A]{Body: Reify(body), Handler: func(e E) kont.Expr[A] {
+		return Reify(handler(e))
+	}})
+}
+
+// ExprCatchError runs body, catching a thrown E or a PeerFailed E with
+// handler. Performs Catch[E, A].
+func ExprCatchError[E, A any](body kont.Expr[A], handler func(E) kont.Expr[A]) kont.Expr[A] {
+	return kont.ExprPerform(Catch[E, A]{Body: body, Handler: handler})
+}
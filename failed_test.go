@@ -0,0 +1,330 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+// failure is a thrown error value that the gob codec can carry.
+type failure struct {
+	Code int
+	Msg  string
+}
+
+func TestPeerFailedRunError(t *testing.T) {
+	skipRace(t)
+	boom := errors.New("boom")
+	client := kont.ThrowError[error, string](boom)
+	server := sess.RecvBind(func(n int) kont.Eff[string] {
+		return sess.CloseDone("unreachable")
+	})
+
+	clientResult, serverResult := sess.RunError[error, string, string](client, server)
+	if e, _ := clientResult.GetLeft(); e != boom {
+		t.Fatalf("client got %v, want boom", clientResult)
+	}
+	e, ok := serverResult.GetLeft()
+	if !ok {
+		t.Fatal("server expected Left, got Right")
+	}
+	var pf sess.PeerFailed[error]
+	if !errors.As(e, &pf) || pf.Err != boom || !errors.Is(e, boom) {
+		t.Fatalf("server got %v, want PeerFailed carrying boom", e)
+	}
+}
+
+func TestPeerFailedNotAnE(t *testing.T) {
+	skipRace(t)
+	client := sess.SendThen(1, kont.ThrowError[string, string]("boom"))
+	server := sess.RecvBind(func(n int) kont.Eff[string] {
+		return sess.RecvBind(func(m int) kont.Eff[string] { return sess.CloseDone("unreachable") })
+	})
+
+	// PeerFailed[string] is not a string: the server's Recv fails with the
+	// abort instead of resolving to a Left the client could have thrown.
+	_, _, err := sess.TryRunError[string, string, string](client, server)
+	var ae *sess.AbortError
+	if !errors.Is(err, sess.ErrSessionAborted) || !errors.As(err, &ae) || ae.Reason.Error() != "boom" {
+		t.Fatalf("got %v, want the session aborted with boom", err)
+	}
+
+	// Data sent before the throw is still received.
+	client = sess.SendThen(1, kont.ThrowError[string, string]("boom"))
+	server = sess.RecvBind(func(n int) kont.Eff[string] { return sess.CloseDone("got 1") })
+	clientResult, serverResult := sess.RunError[string, string, string](client, server)
+	if e, _ := clientResult.GetLeft(); e != "boom" {
+		t.Fatalf("client got %v, want Left(boom)", clientResult)
+	}
+	if v, _ := serverResult.GetRight(); v != "got 1" {
+		t.Fatalf("server got %v, want Right(got 1)", serverResult)
+	}
+}
+
+func TestPeerFailedExecError(t *testing.T) {
+	skipRace(t)
+	client, server := sess.New()
+	go sess.ExecError[failure](client, kont.ThrowError[failure, struct{}](failure{Code: 7, Msg: "no stock"}))
+
+	r := sess.ExecError[sess.PeerFailed[failure]](server, sess.RecvBind(func(n int) kont.Eff[int] {
+		return sess.CloseDone(n)
+	}))
+	pf, ok := r.GetLeft()
+	if !ok || pf.Err.Code != 7 || pf.Serial != server.Serial() {
+		t.Fatalf("server got %+v, want PeerFailed with code 7", r)
+	}
+	if !server.Aborted() {
+		t.Fatal("session not aborted after the peer threw")
+	}
+}
+
+func TestPeerFailedAdvanceError(t *testing.T) {
+	skipRace(t)
+	client, server := sess.New()
+	_, susp := sess.StepError[string](kont.ExprThrowError[string, struct{}]("boom"))
+	r, next, err := sess.AdvanceError[string](client, susp)
+	if err != nil || next != nil || !r.IsLeft() {
+		t.Fatalf("AdvanceError got %v, %v, %v, want Left", r, next, err)
+	}
+
+	// A plain Advance sees the abort, unwrapping to the thrown text.
+	_, _, err = sess.Advance(server, stepSusp(sess.ExprRecvBind(func(n int) kont.Expr[int] { return kont.ExprReturn(n) })))
+	if !errors.Is(err, sess.ErrSessionAborted) {
+		t.Fatalf("Advance got %v, want ErrSessionAborted", err)
+	}
+	var ae *sess.AbortError
+	if !errors.As(err, &ae) || ae.Reason.Error() != "boom" {
+		t.Fatalf("abort reason %v, want boom", err)
+	}
+
+	_, susp2 := sess.StepError[sess.PeerFailed[string]](sess.ExprRecvBind(func(n int) kont.Expr[int] { return kont.ExprReturn(n) }))
+	r2, next2, err := sess.AdvanceError[sess.PeerFailed[string]](server, susp2)
+	if pf, _ := r2.GetLeft(); err != nil || next2 != nil || pf.Err != "boom" {
+		t.Fatalf("AdvanceError got %v, %v, %v, want Left(PeerFailed(boom))", r2, next2, err)
+	}
+}
+
+func TestCatchCompensation(t *testing.T) {
+	skipRace(t)
+	// The client reserves, fails, and compensates on the same session.
+	client := sess.CatchError(
+		sess.SendThen("reserve", kont.ThrowError[string, string]("payment declined")),
+		func(e string) kont.Eff[string] {
+			return sess.SendThen("cancel", sess.CloseDone("compensated: "+e))
+		},
+	)
+	server := sess.RecvBind(func(a string) kont.Eff[string] {
+		return sess.RecvBind(func(b string) kont.Eff[string] {
+			return sess.CloseDone(a + "," + b)
+		})
+	})
+
+	clientResult, serverResult := sess.RunError[string, string, string](client, server)
+	if v, _ := clientResult.GetRight(); v != "compensated: payment declined" {
+		t.Fatalf("client got %v", clientResult)
+	}
+	if v, _ := serverResult.GetRight(); v != "reserve,cancel" {
+		t.Fatalf("server got %v", serverResult)
+	}
+}
+
+func TestCatchPeerFailed(t *testing.T) {
+	skipRace(t)
+	client, server := sess.New()
+	go sess.ExecError[string](client, sess.SendThen(1, sess.RecvBind(func(bool) kont.Eff[struct{}] {
+		return kont.ThrowError[string, struct{}]("boom")
+	})))
+
+	// The server holds n until the client's second value, releasing it
+	// when the client fails instead.
+	var released []int
+	r := sess.ExecErrorExpr[sess.PeerFailed[string]](server, sess.ExprRecvBind(func(n int) kont.Expr[int] {
+		return sess.ExprSendThen(true, sess.ExprCatchError(
+			sess.ExprRecvBind(func(m int) kont.Expr[int] { return kont.ExprReturn(n + m) }),
+			func(pf sess.PeerFailed[string]) kont.Expr[int] {
+				released = append(released, n)
+				return kont.ExprReturn(-1)
+			},
+		))
+	}))
+	if v, _ := r.GetRight(); v != -1 || len(released) != 1 || released[0] != 1 {
+		t.Fatalf("server got %v, released %v", r, released)
+	}
+}
+
+func TestCatchRethrow(t *testing.T) {
+	skipRace(t)
+	first := errors.New("first")
+	client := sess.CatchError(
+		kont.ThrowError[error, string](first),
+		func(e error) kont.Eff[string] {
+			return kont.ThrowError[error, string](fmt.Errorf("%w, then second", e))
+		},
+	)
+	server := sess.RecvBind(func(n int) kont.Eff[string] { return sess.CloseDone("unreachable") })
+
+	clientResult, serverResult := sess.RunError[error, string, string](client, server)
+	if e, _ := clientResult.GetLeft(); e == nil || e.Error() != "first, then second" {
+		t.Fatalf("client got %v", clientResult)
+	}
+	e, _ := serverResult.GetLeft()
+	var pf sess.PeerFailed[error]
+	if !errors.As(e, &pf) || !errors.Is(e, first) {
+		t.Fatalf("server got %v, want PeerFailed wrapping first", serverResult)
+	}
+}
+
+func TestCatchRunError(t *testing.T) {
+	skipRace(t)
+	client := sess.CatchError(
+		sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) }),
+		func(error) kont.Eff[int] { return kont.Pure(-1) },
+	)
+	server := sess.SendThen(5, sess.CloseDone(0))
+
+	clientResult, serverResult, err := sess.TryRunError[error, int, int](client, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := clientResult.GetRight(); v != 5 {
+		t.Fatalf("client got %v, want Right(5)", clientResult)
+	}
+	if !serverResult.IsRight() {
+		t.Fatalf("server got %v", serverResult)
+	}
+}
+
+func TestCatchAdvanceError(t *testing.T) {
+	skipRace(t)
+	client, server := sess.New()
+	_, susp := sess.StepError[string](sess.ExprCatchError(
+		sess.ExprRecvBind(func(n int) kont.Expr[int] {
+			return sess.ExprRecvBind(func(m int) kont.Expr[int] { return kont.ExprReturn(n + m) })
+		}),
+		func(e string) kont.Expr[int] {
+			return sess.ExprSendThen(e, kont.ExprReturn(-1))
+		},
+	))
+
+	// The body waits for the peer instead of blocking AdvanceError.
+	r, next, err := sess.AdvanceError[string](client, susp)
+	if err != iox.ErrWouldBlock || next != susp {
+		t.Fatalf("AdvanceError got %v, %v, %v, want the Catch pending", r, next, err)
+	}
+	if _, _, err := sess.Advance(server, stepSusp(sess.ExprSendThen(1, kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatal(err)
+	}
+	if r, next, err = sess.AdvanceError[string](client, next); err != nil || next == nil {
+		t.Fatalf("AdvanceError got %v, %v, %v, want the body to progress", r, next, err)
+	}
+	if _, _, err = sess.AdvanceError[string](client, next); err != iox.ErrWouldBlock {
+		t.Fatalf("AdvanceError got %v, want iox.ErrWouldBlock", err)
+	}
+	if _, _, err := sess.Advance(server, stepSusp(sess.ExprSendThen(2, kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatal(err)
+	}
+	r, next, err = sess.AdvanceError[string](client, next)
+	if v, _ := r.GetRight(); err != nil || next != nil || v != 3 {
+		t.Fatalf("AdvanceError got %v, %v, %v, want Right(3)", r, next, err)
+	}
+}
+
+func TestCatchNested(t *testing.T) {
+	skipRace(t)
+	// The inner Catch rethrows; the outer one compensates.
+	client := sess.CatchError(
+		sess.RecvBind(func(n int) kont.Eff[string] {
+			return sess.CatchError(
+				sess.SendThen(n, kont.ThrowError[string, string]("inner")),
+				func(e string) kont.Eff[string] { return kont.ThrowError[string, string](e + ", outer") },
+			)
+		}),
+		func(e string) kont.Eff[string] { return sess.SendThen(-1, sess.CloseDone(e)) },
+	)
+	server := sess.SendThen(1, sess.RecvBind(func(a int) kont.Eff[int] {
+		return sess.RecvBind(func(b int) kont.Eff[int] { return sess.CloseDone(a + b) })
+	}))
+
+	clientResult, serverResult := sess.RunError[string, string, int](client, server)
+	if v, _ := clientResult.GetRight(); v != "inner, outer" {
+		t.Fatalf("client got %v", clientResult)
+	}
+	if v, _ := serverResult.GetRight(); v != 0 {
+		t.Fatalf("server got %v, want Right(0)", serverResult)
+	}
+}
+
+func TestCatchTimedOperation(t *testing.T) {
+	skipRace(t)
+	client := sess.CatchError(
+		sess.RecvWithinBind(time.Millisecond, func(s string) kont.Eff[int] {
+			return sess.CloseDone(0)
+		}, func(sess.Timeout) kont.Eff[int] {
+			return sess.SendThen(7, sess.CloseDone(-1))
+		}),
+		func(string) kont.Eff[int] { return kont.Pure(-2) },
+	)
+	server := sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })
+
+	a, b, err := sess.TryRunError[string](client, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if va, _ := a.GetRight(); va != -1 {
+		t.Fatalf("client got %v, want Right(-1)", a)
+	}
+	if vb, _ := b.GetRight(); vb != 7 {
+		t.Fatalf("server got %v, want Right(7)", b)
+	}
+}
+
+func TestCatchDeadlock(t *testing.T) {
+	skipRace(t)
+	client := sess.CatchError(
+		sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) }),
+		func(string) kont.Eff[int] { return kont.Pure(-1) },
+	)
+	server := sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })
+
+	_, _, err := sess.TryRunError[string, int, int](client, server)
+	var de *sess.DeadlockError
+	if !errors.As(err, &de) || de.A.Kind != sess.OpRecv {
+		t.Fatalf("got %v, want a deadlock with the client in Recv", err)
+	}
+}
+
+func TestConnPeerFailed(t *testing.T) {
+	skipRace(t)
+	client, server := pipeEndpoints()
+	go sess.ExecError[failure](client, sess.SendThen(1, kont.ThrowError[failure, struct{}](failure{Code: 7, Msg: "no stock"})))
+
+	r := sess.ExecError[sess.PeerFailed[failure]](server, sess.RecvBind(func(n int) kont.Eff[int] {
+		return sess.RecvBind(func(m int) kont.Eff[int] { return sess.CloseDone(n + m) })
+	}))
+	if pf, _ := r.GetLeft(); pf.Err != (failure{Code: 7, Msg: "no stock"}) {
+		t.Fatalf("server got %+v, want the thrown failure", r)
+	}
+}
+
+func TestConnPeerFailedText(t *testing.T) {
+	skipRace(t)
+	client, server := pipeEndpoints()
+	go sess.ExecError[error](client, kont.ThrowError[error, struct{}](errors.New("boom")))
+
+	// errors.New values cannot be encoded; the peer gets their text.
+	r := sess.ExecError[error](server, sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) }))
+	e, _ := r.GetLeft()
+	var pf sess.PeerFailed[error]
+	if !errors.As(e, &pf) || pf.Err == nil || pf.Err.Error() != "boom" {
+		t.Fatalf("server got %v, want PeerFailed carrying boom", r)
+	}
+}
@@ -48,3 +48,18 @@ func OfferBranch[A any](onLeft func() kont.Eff[A], onRight func() kont.Eff[A]) k
 		return onRight()
 	})
 }
+
+// SelectThen selects the branch named l and continues with next.
+// Fuses Perform(Select{Label: l}) + Then.
+func SelectThen[B any](l Label, next kont.Eff[B]) kont.Eff[B] {
+	return kont.Then(kont.Perform(Select{Label: l}), next)
+}
+
+// OfferCases waits for the peer's choice and calls the case for the
+// selected label. A label without a case fails the offer with a
+// *LabelError. cases must not be modified while the offer is pending.
+func OfferCases[A any](cases map[Label]func() kont.Eff[A]) kont.Eff[A] {
+	return kont.Bind(kont.Perform(offerCases[func() kont.Eff[A]]{cases: cases}), func(l Label) kont.Eff[A] {
+		return cases[l]()
+	})
+}
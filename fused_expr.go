@@ -119,3 +119,30 @@ func ExprOfferBranch[A any](onLeft func() kont.Expr[A], onRight func() kont.Expr
 	ef.Next = bf
 	return kont.ExprSuspend[A](ef)
 }
+
+// ExprSelectThen selects the branch named l and continues with next.
+// Fuses ExprPerform(Select{Label: l}) + ExprThen.
+func ExprSelectThen[B any](l Label, next kont.Expr[B]) kont.Expr[B] {
+	return exprThen(Select{Label: l}, next)
+}
+
+func offerCasesUnwind[A any](data, _, _ kont.Erased, current kont.Erased) (kont.Erased, kont.Frame) {
+	cases := data.(map[Label]func() kont.Expr[A])
+	result := cases[current.(Label)]()
+	return kont.Erased(result.Value), result.Frame
+}
+
+// ExprOfferCases waits for the peer's choice and calls the case for the
+// selected label. A label without a case fails the offer with a
+// *LabelError. The cases map is the dispatch table itself, so a table
+// built once costs no allocation over ExprOfferBranch.
+func ExprOfferCases[A any](cases map[Label]func() kont.Expr[A]) kont.Expr[A] {
+	bf := kont.AcquireUnwindFrame()
+	bf.Data1 = cases
+	bf.Unwind = offerCasesUnwind[A]
+	ef := kont.AcquireEffectFrame()
+	ef.Operation = offerCases[func() kont.Expr[A]]{cases: cases}
+	ef.Resume = identityResume
+	ef.Next = bf
+	return kont.ExprSuspend[A](ef)
+}
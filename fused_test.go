@@ -5,7 +5,9 @@
 package sess_test
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"code.hybscloud.com/kont"
@@ -101,3 +103,100 @@ func TestFusedProtocol(t *testing.T) {
 		t.Fatalf("server got %q, want %q", serverResult, "hello:100")
 	}
 }
+
+// Commands of a labeled choice.
+const (
+	cmdGet sess.Label = iota
+	cmdPut
+	cmdDelete
+	cmdQuit
+)
+
+func TestSelectOfferCases(t *testing.T) {
+	skipRace(t)
+	client := sess.SelectThen(cmdPut, sess.SendThen(7, sess.SelectThen(cmdQuit, sess.CloseDone("done"))))
+
+	var server func(sum int) kont.Eff[int]
+	server = func(sum int) kont.Eff[int] {
+		return sess.OfferCases(map[sess.Label]func() kont.Eff[int]{
+			cmdGet:    func() kont.Eff[int] { return server(sum) },
+			cmdPut:    func() kont.Eff[int] { return sess.RecvBind(func(n int) kont.Eff[int] { return server(sum + n) }) },
+			cmdDelete: func() kont.Eff[int] { return server(0) },
+			cmdQuit:   func() kont.Eff[int] { return sess.CloseDone(sum) },
+		})
+	}
+
+	clientResult, serverResult := sess.Run[string, int](client, server(0))
+	if clientResult != "done" || serverResult != 7 {
+		t.Fatalf("got %q/%d, want done/7", clientResult, serverResult)
+	}
+}
+
+func TestExprSelectOfferCases(t *testing.T) {
+	skipRace(t)
+	client := sess.ExprSelectThen(cmdDelete, sess.ExprCloseDone("deleted"))
+	server := sess.ExprOfferCases(map[sess.Label]func() kont.Expr[string]{
+		cmdGet:    func() kont.Expr[string] { return sess.ExprCloseDone("get") },
+		cmdDelete: func() kont.Expr[string] { return sess.ExprCloseDone("delete") },
+	})
+
+	clientResult, serverResult := sess.RunExpr[string, string](client, server)
+	if clientResult != "deleted" || serverResult != "delete" {
+		t.Fatalf("got %q/%q, want deleted/delete", clientResult, serverResult)
+	}
+}
+
+func TestOfferCasesBinaryPeer(t *testing.T) {
+	skipRace(t)
+	client := sess.SelectRThen(sess.CloseDone(struct{}{}))
+	server := sess.OfferCases(map[sess.Label]func() kont.Eff[string]{
+		sess.LabelLeft:  func() kont.Eff[string] { return sess.CloseDone("left") },
+		sess.LabelRight: func() kont.Eff[string] { return sess.CloseDone("right") },
+	})
+
+	if _, got := sess.Run[struct{}, string](client, server); got != "right" {
+		t.Fatalf("server got %q, want right", got)
+	}
+}
+
+func TestOfferCasesUnknownLabel(t *testing.T) {
+	epA, epB := sess.New()
+	if _, _, err := sess.Advance(epA, stepSusp(sess.ExprSelectThen(cmdQuit, sess.ExprCloseDone(0)))); err != nil {
+		t.Fatal(err)
+	}
+	_, susp := sess.Step(sess.ExprOfferCases(map[sess.Label]func() kont.Expr[int]{
+		cmdGet: func() kont.Expr[int] { return sess.ExprCloseDone(1) },
+	}))
+	_, _, err := sess.Advance(epB, susp)
+	var le *sess.LabelError
+	if !errors.As(err, &le) {
+		t.Fatalf("expected *LabelError, got %v", err)
+	}
+	if le.Label != cmdQuit || le.Serial != epB.Serial() || le.Step != 0 {
+		t.Fatalf("got %+v, want label %d at step 0", le, cmdQuit)
+	}
+	if want := "sess: unknown label 3 offered in session"; !strings.HasPrefix(err.Error(), want) {
+		t.Fatalf("got %q, want prefix %q", err.Error(), want)
+	}
+}
+
+func TestOfferBinaryUnknownLabel(t *testing.T) {
+	epA, epB := sess.New()
+	if _, _, err := sess.Advance(epA, stepSusp(sess.ExprSelectThen(cmdDelete, sess.ExprCloseDone(0)))); err != nil {
+		t.Fatal(err)
+	}
+	_, susp := sess.Step(sess.ExprOfferBranch(
+		func() kont.Expr[int] { return sess.ExprCloseDone(0) },
+		func() kont.Expr[int] { return sess.ExprCloseDone(1) },
+	))
+	_, _, err := sess.Advance(epB, susp)
+	var le *sess.LabelError
+	if !errors.As(err, &le) || le.Label != cmdDelete {
+		t.Fatalf("expected *LabelError for label %d, got %v", cmdDelete, err)
+	}
+}
+
+func stepSusp[R any](protocol kont.Expr[R]) *kont.Suspension[R] {
+	_, susp := sess.Step(protocol)
+	return susp
+}
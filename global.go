@@ -0,0 +1,296 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// globalKind is the node kind of a Global.
+type globalKind uint8
+
+const (
+	globalEnd globalKind = iota
+	globalMsg
+	globalChoice
+	globalHole
+)
+
+// Global is a global protocol (choreography): the interactions between
+// all roles of a multiparty session, written once. Project derives the
+// local Spec each role follows. Like Spec, a Global is immutable once
+// built, and GlobalLoop ties recursive protocols.
+type Global struct {
+	kind     globalKind
+	from, to Role
+	typ      reflect.Type
+	loop     bool    // head of a GlobalLoop
+	next     *Global // continuation; left branch for choices
+	alt      *Global // right branch for choices
+}
+
+// GlobalMsg describes from sending a T to to, then continues with next.
+func GlobalMsg[T any](from, to Role, next *Global) *Global {
+	return &Global{kind: globalMsg, from: from, to: to, typ: reflect.TypeFor[T](), next: next}
+}
+
+// GlobalChoice describes from choosing between left and right and
+// telling to which branch it took.
+func GlobalChoice(from, to Role, left, right *Global) *Global {
+	return &Global{kind: globalChoice, from: from, to: to, next: left, alt: right}
+}
+
+// GlobalEnd ends the protocol: every role closes its session.
+func GlobalEnd() *Global {
+	return &Global{kind: globalEnd}
+}
+
+// GlobalLoop builds a recursive Global like SpecLoop: every reference to
+// loop continues at the start of the body again.
+func GlobalLoop(body func(loop *Global) *Global) *Global {
+	hole := &Global{kind: globalHole}
+	g := body(hole)
+	if g == hole || g.kind == globalHole {
+		panic("sess: GlobalLoop body must perform an interaction")
+	}
+	*hole = *g
+	hole.loop = true
+	return hole
+}
+
+// Roles returns the roles of g in order of first appearance.
+func (g *Global) Roles() []Role {
+	var roles []Role
+	seen := make(map[*Global]bool)
+	var walk func(*Global)
+	walk = func(g *Global) {
+		if g == nil || seen[g] {
+			return
+		}
+		seen[g] = true
+		if g.kind == globalMsg || g.kind == globalChoice {
+			for _, r := range [2]Role{g.from, g.to} {
+				if !containsRole(roles, r) {
+					roles = append(roles, r)
+				}
+			}
+		}
+		walk(g.next)
+		walk(g.alt)
+	}
+	walk(g)
+	return roles
+}
+
+func containsRole(roles []Role, r Role) bool {
+	for _, x := range roles {
+		if x == r {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders g, e.g. "C→S:int.S→C{end, end}".
+// A revisited loop head is rendered as "μ".
+func (g *Global) String() string {
+	var sb strings.Builder
+	g.format(&sb, make(map[*Global]bool))
+	return sb.String()
+}
+
+func (g *Global) format(sb *strings.Builder, seen map[*Global]bool) {
+	if g == nil || g.kind == globalEnd {
+		sb.WriteString("end")
+		return
+	}
+	if seen[g] {
+		sb.WriteString("μ")
+		return
+	}
+	seen[g] = true
+	sb.WriteString(string(g.from))
+	sb.WriteString("→")
+	sb.WriteString(string(g.to))
+	if g.kind == globalMsg {
+		sb.WriteByte(':')
+		sb.WriteString(g.typ.String())
+		sb.WriteByte('.')
+		g.next.format(sb, seen)
+		return
+	}
+	sb.WriteByte('{')
+	g.next.format(sb, seen)
+	sb.WriteString(", ")
+	g.alt.format(sb, seen)
+	sb.WriteByte('}')
+}
+
+// Check reports whether g is well-formed, that is, whether every role
+// has a projection. See Project.
+func (g *Global) Check() error {
+	roles := g.Roles()
+	for _, r := range roles {
+		if _, err := g.Project(r); err != nil {
+			return err
+		}
+	}
+	if len(roles) < 2 {
+		return &WellFormednessError{Reason: "a global protocol needs at least two roles"}
+	}
+	return nil
+}
+
+// Project returns the local Spec of role in g. The Spec addresses each
+// operation to a peer role and is enforced by NewMultiMonitored.
+//
+// Projection fails with a *WellFormednessError if a role sends to itself,
+// or if a role that neither makes nor receives a choice behaves
+// differently in its branches: it would either lack knowledge of the
+// choice, or end in one branch while a message is sent to it in the other
+// (an orphan message). A role that takes no part in a loop leaves it.
+func (g *Global) Project(role Role) (*Spec, error) {
+	p := projector{role: role, memo: make(map[*Global]*Spec), tied: make(map[*Spec]bool)}
+	return p.project(g)
+}
+
+type projector struct {
+	role Role
+	memo map[*Global]*Spec // local node of each global node being or already projected
+	tied map[*Spec]bool    // placeholders a loop has been tied to
+}
+
+func (p *projector) project(g *Global) (*Spec, error) {
+	if g == nil || g.kind == globalEnd {
+		return &Spec{kind: specEnd}, nil
+	}
+	if s, ok := p.memo[g]; ok {
+		if s.kind == specHole {
+			p.tied[s] = true
+		}
+		return s, nil
+	}
+	if g.loop && !containsRole(g.Roles(), p.role) {
+		// The role takes no part in the loop.
+		s := &Spec{kind: specEnd}
+		p.memo[g] = s
+		return s, nil
+	}
+	if g.from == g.to {
+		return nil, &WellFormednessError{Global: g, Role: g.from, Reason: "role " + strconv.Quote(string(g.from)) + " interacts with itself"}
+	}
+	// The placeholder stands for g while its continuation is projected,
+	// so that loops back to g are tied to it.
+	hole := &Spec{kind: specHole}
+	p.memo[g] = hole
+	s, err := p.projectNode(g)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case s == hole:
+		// An unguarded loop back to g: the role takes no part in it.
+		*hole = Spec{kind: specEnd}
+	case s.kind == specHole || !p.tied[hole]:
+		// No loop came back to g: share the continuation's node, which
+		// may be the enclosing loop's placeholder.
+		p.memo[g] = s
+		return s, nil
+	default:
+		*hole = *s
+	}
+	return hole, nil
+}
+
+func (p *projector) projectNode(g *Global) (*Spec, error) {
+	left, err := p.project(g.next)
+	if err != nil {
+		return nil, err
+	}
+	if g.kind == globalMsg {
+		switch p.role {
+		case g.from:
+			return &Spec{kind: specSend, typ: g.typ, peer: g.to, next: left}, nil
+		case g.to:
+			return &Spec{kind: specRecv, typ: g.typ, peer: g.from, next: left}, nil
+		}
+		return left, nil
+	}
+	right, err := p.project(g.alt)
+	if err != nil {
+		return nil, err
+	}
+	switch p.role {
+	case g.from:
+		return &Spec{kind: specChoose, peer: g.to, next: left, alt: right}, nil
+	case g.to:
+		return &Spec{kind: specOffer, peer: g.from, next: left, alt: right}, nil
+	}
+	if specEqual(left, right, make(map[[2]*Spec]bool)) {
+		return left, nil
+	}
+	reason := "role " + strconv.Quote(string(p.role)) + " has no knowledge of the choice by " + strconv.Quote(string(g.from))
+	if orphaned(left, right) || orphaned(right, left) {
+		reason = "orphan message: role " + strconv.Quote(string(p.role)) + " ends in one branch of the choice by " +
+			strconv.Quote(string(g.from)) + " and receives in the other"
+	}
+	return nil, &WellFormednessError{Global: g, Role: p.role, Reason: reason}
+}
+
+// orphaned reports whether a ends while b receives.
+func orphaned(a, b *Spec) bool {
+	return a.kind == specEnd && (b.kind == specRecv || b.kind == specOffer)
+}
+
+// specEqual reports whether a and b describe the same behavior.
+func specEqual(a, b *Spec, seen map[[2]*Spec]bool) bool {
+	if a == b {
+		return true
+	}
+	if a == nil || b == nil || a.kind == specHole || b.kind == specHole {
+		return false
+	}
+	key := [2]*Spec{a, b}
+	if seen[key] {
+		return true
+	}
+	seen[key] = true
+	if a.kind != b.kind || a.typ != b.typ || a.peer != b.peer {
+		return false
+	}
+	return specEqual(a.next, b.next, seen) && specEqual(a.alt, b.alt, seen)
+}
+
+// WellFormednessError reports a global protocol that cannot be projected.
+type WellFormednessError struct {
+	Global *Global // offending interaction; nil if the protocol as a whole
+	Role   Role    // role whose projection failed; empty if none
+	Reason string
+}
+
+// Error implements error.
+func (e *WellFormednessError) Error() string {
+	return "sess: ill-formed global protocol: " + e.Reason
+}
+
+// NewMultiMonitored checks g, creates a multiparty session for its roles
+// (in the order of g.Roles()), and attaches to each endpoint a monitor
+// enforcing that role's projection. A mismatch is returned from
+// AdvanceMulti as a *ProtocolViolation, and panics with the same value in
+// ExecMulti.
+func NewMultiMonitored(g *Global) ([]*MultiEndpoint, error) {
+	if err := g.Check(); err != nil {
+		return nil, err
+	}
+	roles := g.Roles()
+	eps := NewMulti(roles...)
+	for i, r := range roles {
+		spec, _ := g.Project(r)
+		eps[i].mon = &monitor{cur: spec}
+	}
+	return eps, nil
+}
@@ -0,0 +1,171 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+// client → gateway: int; gateway → backend: int; backend → gateway: string;
+// gateway → client: string.
+func relayGlobal() *sess.Global {
+	return sess.GlobalMsg[int](client, gateway,
+		sess.GlobalMsg[int](gateway, backend,
+			sess.GlobalMsg[string](backend, gateway,
+				sess.GlobalMsg[string](gateway, client, sess.GlobalEnd()))))
+}
+
+func TestGlobalProjection(t *testing.T) {
+	g := relayGlobal()
+	if got, want := g.String(), "client→gateway:int.gateway→backend:int.backend→gateway:string.gateway→client:string.end"; got != want {
+		t.Fatalf("String got %q, want %q", got, want)
+	}
+	if got, want := g.Roles(), []sess.Role{client, gateway, backend}; !slices.Equal(got, want) {
+		t.Fatalf("Roles got %v, want %v", got, want)
+	}
+	for role, want := range map[sess.Role]string{
+		client:  "gateway!int.gateway?string.end",
+		gateway: "client?int.backend!int.backend?string.client!string.end",
+		backend: "gateway?int.gateway!string.end",
+	} {
+		spec, err := g.Project(role)
+		if err != nil {
+			t.Fatalf("Project(%s): %v", role, err)
+		}
+		if got := spec.String(); got != want {
+			t.Fatalf("Project(%s) got %q, want %q", role, got, want)
+		}
+	}
+}
+
+func TestGlobalForwardedChoice(t *testing.T) {
+	// The client's choice reaches the backend through the gateway: both
+	// branches of the client's choice continue with the same gateway choice,
+	// so the backend's projection is the same in either.
+	forward := sess.GlobalChoice(gateway, backend, sess.GlobalMsg[int](gateway, backend, sess.GlobalEnd()), sess.GlobalEnd())
+	g := sess.GlobalChoice(client, gateway, forward, forward)
+	if err := g.Check(); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	for role, want := range map[sess.Role]string{
+		client:  "gateway⊕{end, end}",
+		gateway: "client&{backend⊕{backend!int.end, end}, μ}",
+		backend: "gateway&{gateway?int.end, end}",
+	} {
+		spec, _ := g.Project(role)
+		if got := spec.String(); got != want {
+			t.Fatalf("Project(%s) got %q, want %q", role, got, want)
+		}
+	}
+}
+
+func TestGlobalLoopProjection(t *testing.T) {
+	g := sess.GlobalMsg[string](backend, client, sess.GlobalLoop(func(loop *sess.Global) *sess.Global {
+		return sess.GlobalChoice(client, gateway, sess.GlobalMsg[int](client, gateway, loop), sess.GlobalEnd())
+	}))
+	if err := g.Check(); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	for role, want := range map[sess.Role]string{
+		client:  "backend?string.gateway⊕{gateway!int.μ, end}",
+		gateway: "client&{client?int.μ, end}",
+		backend: "client!string.end", // takes no part in the loop
+	} {
+		spec, _ := g.Project(role)
+		if got := spec.String(); got != want {
+			t.Fatalf("Project(%s) got %q, want %q", role, got, want)
+		}
+	}
+}
+
+func TestGlobalIllFormed(t *testing.T) {
+	for name, tc := range map[string]struct {
+		g    *sess.Global
+		want string
+	}{
+		"self": {
+			sess.GlobalMsg[int](client, client, sess.GlobalEnd()),
+			`role "client" interacts with itself`,
+		},
+		"knowledge of choice": {
+			sess.GlobalChoice(client, gateway,
+				sess.GlobalMsg[int](backend, gateway, sess.GlobalEnd()),
+				sess.GlobalEnd()),
+			`role "backend" has no knowledge of the choice by "client"`,
+		},
+		"orphan message": {
+			sess.GlobalChoice(client, gateway,
+				sess.GlobalMsg[int](gateway, backend, sess.GlobalEnd()),
+				sess.GlobalEnd()),
+			`orphan message: role "backend" ends in one branch`,
+		},
+		"one role": {
+			sess.GlobalEnd(),
+			"at least two roles",
+		},
+	} {
+		err := tc.g.Check()
+		var wf *sess.WellFormednessError
+		if !errors.As(err, &wf) || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: got %v, want %q", name, err, tc.want)
+		}
+	}
+	if _, err := sess.NewMultiMonitored(sess.GlobalMsg[int](client, client, sess.GlobalEnd())); err == nil {
+		t.Fatal("NewMultiMonitored must reject an ill-formed protocol")
+	}
+}
+
+func TestMultiMonitoredRelay(t *testing.T) {
+	skipRace(t)
+	eps, err := sess.NewMultiMonitored(relayGlobal())
+	if err != nil {
+		t.Fatalf("NewMultiMonitored: %v", err)
+	}
+	ps := relayProtocols()
+
+	done := make(chan string, 2)
+	go func() { done <- sess.ExecMulti(eps[1], ps[gateway]) }()
+	go func() { done <- sess.ExecMulti(eps[2], ps[backend]) }()
+	if got := sess.ExecMulti(eps[0], ps[client]); got != "x" {
+		t.Fatalf("client got %q, want x", got)
+	}
+	<-done
+	<-done
+}
+
+func TestMultiMonitoredWrongPeer(t *testing.T) {
+	eps, err := sess.NewMultiMonitored(relayGlobal())
+	if err != nil {
+		t.Fatalf("NewMultiMonitored: %v", err)
+	}
+
+	// The client must send to the gateway, not to the backend.
+	_, susp := sess.Step[struct{}](sess.ExprSendToThen(backend, 1, sess.ExprCloseDone(struct{}{})))
+	_, _, err = sess.AdvanceMulti(eps[0], susp)
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) {
+		t.Fatalf("expected *ProtocolViolation, got %v", err)
+	}
+	if pv.ExpectedPeer != gateway || pv.ActualPeer != backend || pv.ExpectedType != reflect.TypeFor[int]() {
+		t.Fatalf("unexpected violation: %v", pv)
+	}
+	if !strings.Contains(err.Error(), "expected Send[int] with gateway, got Send[int] with backend") {
+		t.Fatalf("unexpected message: %v", err)
+	}
+	susp.Discard()
+
+	_, recv := sess.Step[int](sess.ExprRecvFromBind(client, func(n int) kont.Expr[int] { return kont.ExprReturn(n) }))
+	if _, _, err := sess.AdvanceMulti(eps[2], recv); !errors.As(err, &pv) {
+		t.Fatalf("backend: expected *ProtocolViolation, got %v", err)
+	}
+	recv.Discard()
+}
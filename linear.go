@@ -0,0 +1,117 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"code.hybscloud.com/kont"
+)
+
+// Errors reported by linearity checks (see SetLinearityChecks).
+var (
+	// ErrEndpointClosed is returned by operations on an endpoint after its
+	// own Close.
+	ErrEndpointClosed = errors.New("sess: endpoint closed")
+
+	// ErrConcurrentUse is returned by an operation started on an endpoint
+	// while another goroutine is performing one. Each endpoint is the
+	// single producer and single consumer of its queues, so at most one
+	// goroutine may drive it at a time.
+	ErrConcurrentUse = errors.New("sess: concurrent use of endpoint")
+)
+
+// linearityChecks holds the mode of endpoints created from now on.
+var linearityChecks atomic.Bool
+
+func init() { linearityChecks.Store(linearityDefault) }
+
+// SetLinearityChecks turns linearity checks on or off for every endpoint
+// created afterwards; WithLinearityChecks overrides it for one session.
+// The checks are off by default, and on by default in builds with the
+// sessdebug tag.
+//
+// A checked endpoint carries an ownership token taken by every operation.
+// An operation then fails with ErrConcurrentUse if another goroutine is
+// operating on the endpoint, with ErrEndpointClosed after the endpoint's
+// own Close, and with ErrEndpointMoved once the endpoint has been sent to
+// a peer as a *Endpoint; the peer receives a new handle for it, as with
+// typed delegation. Typed delegation (DelegateThen) retires the
+// delegator's handle whether checks are on or off.
+func SetLinearityChecks(on bool) {
+	linearityChecks.Store(on)
+}
+
+// WithLinearityChecks turns linearity checks on or off for both endpoints
+// of the session; see SetLinearityChecks.
+func WithLinearityChecks(on bool) Option {
+	return func(o *options) { o.linear, o.hasLinear = on, true }
+}
+
+// Ownership token states.
+const (
+	ownerIdle uint32 = iota
+	ownerBusy
+	ownerMoved
+)
+
+// ownership is the token of a checked endpoint.
+type ownership struct {
+	state atomic.Uint32
+}
+
+// newOwnership returns a token if on, and nil otherwise.
+func newOwnership(on bool) *ownership {
+	if !on {
+		return nil
+	}
+	return &ownership{}
+}
+
+// acquire takes the token for an operation.
+func (o *ownership) acquire() error {
+	if o.state.CompareAndSwap(ownerIdle, ownerBusy) {
+		return nil
+	}
+	return o.err()
+}
+
+// release returns the token after an operation.
+func (o *ownership) release() {
+	o.state.Store(ownerIdle)
+}
+
+// hand marks the endpoint as in transit to a peer.
+func (o *ownership) hand() error {
+	if o.state.CompareAndSwap(ownerIdle, ownerMoved) {
+		return nil
+	}
+	return o.err()
+}
+
+func (o *ownership) err() error {
+	if o.state.Load() == ownerMoved {
+		return ErrEndpointMoved
+	}
+	return ErrConcurrentUse
+}
+
+// sendEndpoint sends ep, checked, to the peer as a fresh handle for its
+// endpoint, as DelegateChan does; ep itself stays moved.
+func sendEndpoint(ctx *sessionContext, ep *Endpoint) (kont.Resumed, error) {
+	o := ep.ctx.ext.owner
+	if err := o.hand(); err != nil {
+		return nil, err
+	}
+	rd, wr := ep.ctx.ready().readable.Load(), ep.ctx.ready().writable.Load()
+	ctx.sendSlot = ep.ctx.handoff()
+	if err := ctx.sendQ.Enqueue(&ctx.sendSlot); err != nil {
+		o.release()
+		return nil, err
+	}
+	ep.ctx.retire(rd, wr)
+	return struct{}{}, nil
+}
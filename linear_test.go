@@ -0,0 +1,123 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"testing"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+func TestLinearityClosed(t *testing.T) {
+	ep, _ := sess.NewWithOptions(sess.WithLinearityChecks(true))
+	if _, _, err := sess.Advance(ep, stepSusp(sess.ExprCloseDone(struct{}{}))); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := sess.Advance(ep, stepSusp(sess.ExprSendThen(1, kont.ExprReturn(struct{}{}))))
+	if err != sess.ErrEndpointClosed {
+		t.Fatalf("send after Close got %v, want ErrEndpointClosed", err)
+	}
+	_, _, err = sess.Advance(ep, stepSusp(sess.ExprCloseDone(struct{}{})))
+	if err != sess.ErrEndpointClosed {
+		t.Fatalf("second Close got %v, want ErrEndpointClosed", err)
+	}
+
+	unchecked, _ := sess.NewWithOptions(sess.WithLinearityChecks(false))
+	sess.Advance(unchecked, stepSusp(sess.ExprCloseDone(struct{}{})))
+	if _, _, err := sess.Advance(unchecked, stepSusp(sess.ExprCloseDone(struct{}{}))); err != nil {
+		t.Fatalf("unchecked second Close got %v", err)
+	}
+}
+
+func TestLinearityMoved(t *testing.T) {
+	sub, peer := sess.NewWithOptions(sess.WithLinearityChecks(true))
+	epA, epB := sess.New()
+	send := func(ep *sess.Endpoint) error {
+		_, _, err := sess.Advance(ep, stepSusp(sess.ExprSendThen(1, kont.ExprReturn(struct{}{}))))
+		return err
+	}
+
+	if _, _, err := sess.Advance(epA, stepSusp(sess.ExprSendThen(sub, kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(sub); err != sess.ErrEndpointMoved {
+		t.Fatalf("send on an endpoint in transit got %v, want ErrEndpointMoved", err)
+	}
+	_, _, err := sess.Advance(epA, stepSusp(sess.ExprSendThen(sub, kont.ExprReturn(struct{}{}))))
+	if err != sess.ErrEndpointMoved {
+		t.Fatalf("sending an endpoint twice got %v, want ErrEndpointMoved", err)
+	}
+
+	got, _, err := sess.Advance(epB, stepSusp(sess.ExprRecvBind(func(ep *sess.Endpoint) kont.Expr[*sess.Endpoint] {
+		return kont.ExprReturn(ep)
+	})))
+	if err != nil || got == nil || got == sub || got.Serial() != sub.Serial() {
+		t.Fatalf("received %p, %v, want a new handle for the sent endpoint", got, err)
+	}
+	if err := send(got); err != nil {
+		t.Fatalf("send on the received endpoint got %v", err)
+	}
+	// The sender's handle stays moved once the peer has received it.
+	if err := send(sub); err != sess.ErrEndpointMoved {
+		t.Fatalf("send on the sent endpoint after receipt got %v, want ErrEndpointMoved", err)
+	}
+	if err := send(peer); err != nil {
+		t.Fatalf("send on the peer got %v", err)
+	}
+}
+
+// gateCodec blocks in Encode until its gate is closed.
+type gateCodec struct {
+	sess.GobCodec
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func (c gateCodec) Encode(v any) ([]byte, error) {
+	c.entered <- struct{}{}
+	<-c.gate
+	return c.GobCodec.Encode(v)
+}
+
+func TestLinearityConcurrentUse(t *testing.T) {
+	c := gateCodec{entered: make(chan struct{}), gate: make(chan struct{})}
+	ep, _ := sess.NewWithOptions(sess.WithCodec(c), sess.WithLinearityChecks(true))
+	done := make(chan error)
+	go func() {
+		_, _, err := sess.Advance(ep, stepSusp(sess.ExprSendThen(1, kont.ExprReturn(struct{}{}))))
+		done <- err
+	}()
+	<-c.entered
+
+	_, _, err := sess.Advance(ep, stepSusp(sess.ExprSendThen(2, kont.ExprReturn(struct{}{}))))
+	if err != sess.ErrConcurrentUse {
+		t.Fatalf("second goroutine got %v, want ErrConcurrentUse", err)
+	}
+	close(c.gate)
+	if err := <-done; err != nil {
+		t.Fatalf("first goroutine got %v", err)
+	}
+	go func() { <-c.entered }()
+	if _, _, err := sess.Advance(ep, stepSusp(sess.ExprSendThen(3, kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatalf("after the first send completed got %v", err)
+	}
+}
+
+func TestSetLinearityChecks(t *testing.T) {
+	sess.SetLinearityChecks(true)
+	checked, _ := sess.New()
+	sess.SetLinearityChecks(false)
+	unchecked, _ := sess.New()
+	for _, ep := range []*sess.Endpoint{checked, unchecked} {
+		sess.Advance(ep, stepSusp(sess.ExprCloseDone(struct{}{})))
+	}
+	if _, _, err := sess.Advance(checked, stepSusp(sess.ExprCloseDone(struct{}{}))); err != sess.ErrEndpointClosed {
+		t.Fatalf("endpoint created with checks got %v", err)
+	}
+	if _, _, err := sess.Advance(unchecked, stepSusp(sess.ExprCloseDone(struct{}{}))); err != nil {
+		t.Fatalf("endpoint created without checks got %v", err)
+	}
+}
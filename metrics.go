@@ -0,0 +1,161 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"code.hybscloud.com/kont"
+)
+
+// blockedBounds are the upper bounds of the blocked-time histogram
+// buckets; a last bucket counts longer waits.
+var blockedBounds = [...]time.Duration{
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// Metrics counts the operations of the endpoints it is attached to with
+// Endpoint.SetMetrics: one Metrics per endpoint gives per-endpoint
+// figures, and a Metrics shared by many endpoints aggregates them.
+// Counters are atomic, so a Metrics is safe for concurrent use, and
+// Snapshot may be taken while sessions run.
+//
+// Metrics implements expvar.Var, so it can be published directly:
+//
+//	expvar.Publish("sess", m)
+type Metrics struct {
+	sent           atomic.Uint64
+	received       atomic.Uint64
+	selected       atomic.Uint64
+	offered        atomic.Uint64
+	closed         atomic.Uint64
+	wouldBlock     atomic.Uint64
+	sendWouldBlock atomic.Uint64
+	backoffWaits   atomic.Uint64
+	blockedCount   atomic.Uint64
+	blockedSum     atomic.Int64
+	blocked        [len(blockedBounds) + 1]atomic.Uint64
+}
+
+// NewMetrics returns a Metrics with every counter at zero.
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// MetricsSnapshot is a point-in-time copy of a Metrics.
+type MetricsSnapshot struct {
+	Sent     uint64 // values sent
+	Received uint64 // values received
+	Selected uint64 // choices made with SelectL, SelectR or Select
+	Offered  uint64 // choices received
+	Closed   uint64 // endpoints closed
+
+	// WouldBlock counts attempts that returned iox.ErrWouldBlock.
+	// SendWouldBlock counts those of sends and selects, which block only
+	// on a full queue: a steadily growing value signals backpressure.
+	WouldBlock     uint64
+	SendWouldBlock uint64
+
+	// BackoffWaits counts the backoff sleeps of Exec and ExecContext, and
+	// Blocked the time their operations spent blocked, from the first
+	// attempt that would block to completion.
+	BackoffWaits uint64
+	Blocked      LatencyHistogram
+}
+
+// LatencyHistogram is a histogram of durations. Counts[i] is the number
+// of durations at most Bounds[i] and above Bounds[i-1]; the last count,
+// Counts[len(Bounds)], is the number above every bound.
+type LatencyHistogram struct {
+	Bounds []time.Duration
+	Counts []uint64
+	Count  uint64
+	Sum    time.Duration
+}
+
+// SetMetrics makes ep count its operations in m; nil disables counting.
+// It must not be called while an operation on ep is pending.
+func (ep *Endpoint) SetMetrics(m *Metrics) {
+	ep.ctx.extend().metrics = m
+}
+
+// Snapshot returns the current values of m's counters. Counters are read
+// one at a time, so a snapshot taken while sessions run may be off by the
+// operations in flight.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	s := MetricsSnapshot{
+		Sent:           m.sent.Load(),
+		Received:       m.received.Load(),
+		Selected:       m.selected.Load(),
+		Offered:        m.offered.Load(),
+		Closed:         m.closed.Load(),
+		WouldBlock:     m.wouldBlock.Load(),
+		SendWouldBlock: m.sendWouldBlock.Load(),
+		BackoffWaits:   m.backoffWaits.Load(),
+		Blocked: LatencyHistogram{
+			Bounds: blockedBounds[:],
+			Counts: make([]uint64, len(m.blocked)),
+			Count:  m.blockedCount.Load(),
+			Sum:    time.Duration(m.blockedSum.Load()),
+		},
+	}
+	for i := range m.blocked {
+		s.Blocked.Counts[i] = m.blocked[i].Load()
+	}
+	return s
+}
+
+// String implements expvar.Var: it renders a Snapshot as JSON.
+func (m *Metrics) String() string {
+	b, _ := json.Marshal(m.Snapshot())
+	return string(b)
+}
+
+// completed counts sop, which completed with result v.
+func (m *Metrics) completed(sop sessionDispatcher, v kont.Resumed) {
+	if t, ok := sop.(timedOp); ok && t.timedOut(v) {
+		return
+	}
+	switch kind, _ := sop.opInfo(); kind {
+	case OpSend:
+		m.sent.Add(1)
+	case OpRecv:
+		m.received.Add(1)
+	case OpSelectL, OpSelectR, OpSelect:
+		m.selected.Add(1)
+	case OpOffer:
+		m.offered.Add(1)
+	case OpClose:
+		m.closed.Add(1)
+	}
+}
+
+// blockedOn counts an attempt of sop that returned iox.ErrWouldBlock.
+func (m *Metrics) blockedOn(sop sessionDispatcher) {
+	m.wouldBlock.Add(1)
+	switch kind, _ := sop.opInfo(); kind {
+	case OpSend, OpSelectL, OpSelectR, OpSelect:
+		m.sendWouldBlock.Add(1)
+	}
+}
+
+// observeBlocked records an operation that was blocked for d.
+func (m *Metrics) observeBlocked(d time.Duration) {
+	i := 0
+	for i < len(blockedBounds) && d > blockedBounds[i] {
+		i++
+	}
+	m.blocked[i].Add(1)
+	m.blockedCount.Add(1)
+	m.blockedSum.Add(int64(d))
+}
@@ -0,0 +1,132 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"reflect"
+	"testing"
+	"time"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+func TestMetricsCounts(t *testing.T) {
+	epA, epB := sess.New()
+	ma, mb := sess.NewMetrics(), sess.NewMetrics()
+	epA.SetMetrics(ma)
+	epB.SetMetrics(mb)
+
+	_, server := sess.Step(sess.ExprOfferBranch(
+		func() kont.Expr[int] {
+			return sess.ExprRecvBind(func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) })
+		},
+		func() kont.Expr[int] { return sess.ExprCloseDone(0) },
+	))
+	if _, _, err := sess.Advance(epB, server); err != iox.ErrWouldBlock {
+		t.Fatalf("got %v, want ErrWouldBlock", err)
+	}
+	_, client := sess.Step(sess.ExprSelectLThen(sess.ExprSendThen(1, sess.ExprCloseDone(struct{}{}))))
+	for client != nil {
+		_, client, _ = sess.Advance(epA, client)
+	}
+	for server != nil {
+		_, server, _ = sess.Advance(epB, server)
+	}
+
+	a, b := ma.Snapshot(), mb.Snapshot()
+	if a.Sent != 1 || a.Selected != 1 || a.Closed != 1 || a.Received != 0 || a.WouldBlock != 0 {
+		t.Fatalf("client metrics %+v", a)
+	}
+	if b.Received != 1 || b.Offered != 1 || b.Closed != 1 || b.WouldBlock != 1 || b.SendWouldBlock != 0 {
+		t.Fatalf("server metrics %+v", b)
+	}
+	if a.BackoffWaits != 0 || a.Blocked.Count != 0 {
+		t.Fatalf("stepping backed off: %+v", a)
+	}
+}
+
+func TestMetricsBackpressure(t *testing.T) {
+	epA, _ := sess.New()
+	m := sess.NewMetrics()
+	epA.SetMetrics(m)
+	var sent int
+	for {
+		_, _, err := sess.Advance(epA, stepSusp(sess.ExprSendThen(sent, kont.ExprReturn(struct{}{}))))
+		if err == iox.ErrWouldBlock {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		sent++
+	}
+	s := m.Snapshot()
+	if s.Sent != uint64(sent) || s.WouldBlock != 1 || s.SendWouldBlock != 1 {
+		t.Fatalf("after %d sends: %+v", sent, s)
+	}
+}
+
+func TestMetricsBlockedTime(t *testing.T) {
+	_, epB := sess.New()
+	m := sess.NewMetrics()
+	epB.SetMetrics(m)
+	epB.SetClock(sess.NewVirtualClock(time.Unix(0, 0)))
+
+	sess.Exec(epB, sess.RecvWithinBind(time.Second, func(int) kont.Eff[struct{}] {
+		return kont.Pure(struct{}{})
+	}, func(sess.Timeout) kont.Eff[struct{}] {
+		return kont.Pure(struct{}{})
+	}))
+
+	s := m.Snapshot()
+	if s.BackoffWaits == 0 || s.WouldBlock == 0 {
+		t.Fatalf("no waits counted: %+v", s)
+	}
+	if s.Received != 0 {
+		t.Fatalf("timed-out receive counted: %+v", s)
+	}
+	h := s.Blocked
+	if h.Count != 1 || h.Sum < time.Second {
+		t.Fatalf("blocked %d times for %v, want once for at least 1s", h.Count, h.Sum)
+	}
+	if len(h.Counts) != len(h.Bounds)+1 {
+		t.Fatalf("%d counts for %d bounds", len(h.Counts), len(h.Bounds))
+	}
+	var total uint64
+	for i, n := range h.Counts {
+		if n != 0 && i < len(h.Bounds) && h.Bounds[i] < time.Second {
+			t.Fatalf("a 1s wait landed in the bucket up to %v", h.Bounds[i])
+		}
+		total += n
+	}
+	if total != 1 {
+		t.Fatalf("histogram holds %d waits, want 1", total)
+	}
+}
+
+func TestMetricsExpvar(t *testing.T) {
+	m := sess.NewMetrics()
+	epA, epB := sess.New()
+	epA.SetMetrics(m)
+	epB.SetMetrics(m)
+	sess.Advance(epA, stepSusp(sess.ExprSendThen(1, kont.ExprReturn(struct{}{}))))
+	sess.Advance(epB, stepSusp(sess.ExprRecvBind(func(n int) kont.Expr[int] { return kont.ExprReturn(n) })))
+
+	expvar.Publish("sess_test_metrics", m)
+	var got sess.MetricsSnapshot
+	if err := json.Unmarshal([]byte(expvar.Get("sess_test_metrics").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := m.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expvar got %+v, want %+v", got, want)
+	}
+	if got.Sent != 1 || got.Received != 1 {
+		t.Fatalf("shared metrics %+v", got)
+	}
+}
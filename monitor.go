@@ -0,0 +1,428 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"code.hybscloud.com/kont"
+)
+
+// specKind is the node kind of a Spec.
+type specKind uint8
+
+const (
+	specEnd specKind = iota
+	specSend
+	specRecv
+	specChoose
+	specOffer
+	specHole
+)
+
+// Spec is a runtime session type: the operations one endpoint is expected
+// to perform, in order. Choices branch into left and right continuations;
+// SpecLoop ties a continuation back to an earlier node for recursive protocols.
+// A Spec is immutable once built and may be shared by any number of endpoints.
+type Spec struct {
+	kind specKind
+	typ  reflect.Type
+	peer Role  // addressed role in a projected multiparty Spec; empty otherwise
+	next *Spec // continuation; left branch for choices
+	alt  *Spec // right branch for choices
+
+	cases map[Label]*Spec // branches of a labeled choice; nil for binary choices
+}
+
+// SpecSend expects a Send[T], then continues with next.
+func SpecSend[T any](next *Spec) *Spec {
+	return &Spec{kind: specSend, typ: reflect.TypeFor[T](), next: next}
+}
+
+// SpecRecv expects a Recv[T], then continues with next.
+func SpecRecv[T any](next *Spec) *Spec {
+	return &Spec{kind: specRecv, typ: reflect.TypeFor[T](), next: next}
+}
+
+// SpecChoose expects SelectL (continuing with left) or SelectR (continuing with right).
+func SpecChoose(left, right *Spec) *Spec {
+	return &Spec{kind: specChoose, next: left, alt: right}
+}
+
+// SpecOffer expects Offer, then continues with left or right
+// according to the peer's choice.
+func SpecOffer(left, right *Spec) *Spec {
+	return &Spec{kind: specOffer, next: left, alt: right}
+}
+
+// SpecChooseCases expects a Select of one of the labels of cases
+// (SelectL and SelectR count as LabelLeft and LabelRight), then continues
+// with that label's Spec.
+func SpecChooseCases(cases map[Label]*Spec) *Spec {
+	return &Spec{kind: specChoose, cases: maps.Clone(cases)}
+}
+
+// SpecOfferCases expects an Offer, then continues with the Spec of the
+// label the peer selected.
+func SpecOfferCases(cases map[Label]*Spec) *Spec {
+	return &Spec{kind: specOffer, cases: maps.Clone(cases)}
+}
+
+// SpecEnd expects Close, after which no further operation is allowed.
+func SpecEnd() *Spec {
+	return &Spec{kind: specEnd}
+}
+
+// SpecLoop builds a recursive Spec. body receives a placeholder standing
+// for the loop head and returns the loop body; every reference to the
+// placeholder continues at the start of the body again.
+func SpecLoop(body func(loop *Spec) *Spec) *Spec {
+	hole := &Spec{kind: specHole}
+	s := body(hole)
+	if s == hole || s.kind == specHole {
+		panic("sess: SpecLoop body must perform an operation")
+	}
+	*hole = *s
+	return hole
+}
+
+// equal reports whether s and t describe the same protocol, comparing
+// their graphs node by node. Pairs already being compared are assumed
+// equal, so loops unrolled differently compare equal.
+func (s *Spec) equal(t *Spec) bool {
+	return s.equiv(t, make(map[[2]*Spec]bool))
+}
+
+func (s *Spec) equiv(t *Spec, seen map[[2]*Spec]bool) bool {
+	if s == t {
+		return true
+	}
+	if s == nil || t == nil {
+		return false
+	}
+	if seen[[2]*Spec{s, t}] {
+		return true
+	}
+	seen[[2]*Spec{s, t}] = true
+	if s.kind != t.kind || s.typ != t.typ || s.peer != t.peer ||
+		(s.cases == nil) != (t.cases == nil) || len(s.cases) != len(t.cases) {
+		return false
+	}
+	for l, c := range s.cases {
+		if d, ok := t.cases[l]; !ok || !c.equiv(d, seen) {
+			return false
+		}
+	}
+	return s.next.equiv(t.next, seen) && s.alt.equiv(t.alt, seen)
+}
+
+// Dual returns the Spec of the peer endpoint: sends become receives,
+// choices become offers, and vice versa.
+func (s *Spec) Dual() *Spec {
+	return s.dual(make(map[*Spec]*Spec))
+}
+
+func (s *Spec) dual(memo map[*Spec]*Spec) *Spec {
+	if s == nil {
+		return nil
+	}
+	if d, ok := memo[s]; ok {
+		return d
+	}
+	d := &Spec{typ: s.typ, peer: s.peer}
+	memo[s] = d
+	switch s.kind {
+	case specSend:
+		d.kind = specRecv
+	case specRecv:
+		d.kind = specSend
+	case specChoose:
+		d.kind = specOffer
+	case specOffer:
+		d.kind = specChoose
+	default:
+		d.kind = s.kind
+	}
+	d.next = s.next.dual(memo)
+	d.alt = s.alt.dual(memo)
+	if s.cases != nil {
+		d.cases = make(map[Label]*Spec, len(s.cases))
+		for l, c := range s.cases {
+			d.cases[l] = c.dual(memo)
+		}
+	}
+	return d
+}
+
+// String renders s in session-type notation, e.g. "!int.?string.end".
+// A revisited loop head is rendered as "μ", and labeled choices list
+// their branches by label, e.g. "&{0: end, 2: !int.end}". In a projected
+// multiparty Spec each operation is prefixed by the addressed role, e.g.
+// "S!int".
+func (s *Spec) String() string {
+	var sb strings.Builder
+	s.format(&sb, make(map[*Spec]bool))
+	return sb.String()
+}
+
+func (s *Spec) format(sb *strings.Builder, seen map[*Spec]bool) {
+	if s == nil || s.kind == specEnd {
+		sb.WriteString("end")
+		return
+	}
+	if seen[s] {
+		sb.WriteString("μ")
+		return
+	}
+	seen[s] = true
+	sb.WriteString(string(s.peer))
+	switch s.kind {
+	case specSend, specRecv:
+		if s.kind == specSend {
+			sb.WriteByte('!')
+		} else {
+			sb.WriteByte('?')
+		}
+		sb.WriteString(s.typ.String())
+		sb.WriteByte('.')
+		s.next.format(sb, seen)
+	case specChoose, specOffer:
+		if s.kind == specChoose {
+			sb.WriteString("⊕{")
+		} else {
+			sb.WriteString("&{")
+		}
+		if s.cases == nil {
+			s.next.format(sb, seen)
+			sb.WriteString(", ")
+			s.alt.format(sb, seen)
+		}
+		for i, l := range slices.Sorted(maps.Keys(s.cases)) {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(strconv.FormatUint(uint64(l), 10))
+			sb.WriteString(": ")
+			s.cases[l].format(sb, seen)
+		}
+		sb.WriteByte('}')
+	}
+}
+
+// branch returns the continuation of a choice node for label l.
+func (s *Spec) branch(l Label) (*Spec, bool) {
+	if s.cases != nil {
+		c, ok := s.cases[l]
+		return c, ok
+	}
+	switch l {
+	case LabelLeft:
+		return s.next, true
+	case LabelRight:
+		return s.alt, true
+	}
+	return nil, false
+}
+
+// expected returns the operation kind and payload type s expects.
+// The addressed role, if any, is s.peer.
+// A binary choice expects OpSelectL or OpSelectR; OpSelectL is reported.
+func (s *Spec) expected() (OpKind, reflect.Type) {
+	if s == nil {
+		return OpNone, nil
+	}
+	switch s.kind {
+	case specSend:
+		return OpSend, s.typ
+	case specRecv:
+		return OpRecv, s.typ
+	case specChoose:
+		if s.cases != nil {
+			return OpSelect, nil
+		}
+		return OpSelectL, nil
+	case specOffer:
+		return OpOffer, nil
+	default:
+		return OpClose, nil
+	}
+}
+
+// monitor tracks an endpoint's position in its Spec.
+// cur is nil once the session has been closed.
+type monitor struct {
+	cur *Spec
+}
+
+// opDescriber reports the kind and payload type of an operation.
+// Role-addressed operations also implement peerRole.
+type opDescriber interface {
+	opInfo() (OpKind, reflect.Type)
+}
+
+// opPeer returns the role op addresses, or "" if op is dyadic.
+func opPeer(op opDescriber) Role {
+	if p, ok := op.(interface{ peerRole() Role }); ok {
+		return p.peerRole()
+	}
+	return ""
+}
+
+// opLabel returns the label a selecting operation chooses.
+func opLabel(op opDescriber) Label {
+	if s, ok := op.(interface{ selectLabel() Label }); ok {
+		return s.selectLabel()
+	}
+	return LabelLeft
+}
+
+// offeredLabel returns the label received by an offer with result v.
+func offeredLabel(v kont.Resumed) Label {
+	switch v := v.(type) {
+	case Label:
+		return v
+	case kont.Either[struct{}, struct{}]:
+		if v.IsRight() {
+			return LabelRight
+		}
+	case kont.Either[Timeout, kont.Either[struct{}, struct{}]]:
+		if c, _ := v.GetRight(); c.IsRight() {
+			return LabelRight
+		}
+	}
+	return LabelLeft
+}
+
+// check validates op, performed as the operation numbered step in
+// session serial, against the current Spec node without advancing.
+func (m *monitor) check(op opDescriber, step int, serial Serial) error {
+	kind, typ := op.opInfo()
+	peer := opPeer(op)
+	var ok bool
+	if cur := m.cur; cur != nil && cur.peer == peer {
+		switch cur.kind {
+		case specSend:
+			ok = kind == OpSend && typ == cur.typ
+		case specRecv:
+			ok = kind == OpRecv && typ == cur.typ
+		case specChoose:
+			if kind == OpSelectL || kind == OpSelectR || kind == OpSelect {
+				_, ok = cur.branch(opLabel(op))
+			}
+		case specOffer:
+			ok = kind == OpOffer
+		case specEnd:
+			ok = kind == OpClose
+		}
+	}
+	if ok {
+		return nil
+	}
+	expected, expectedType := m.cur.expected()
+	pv := &ProtocolViolation{
+		Expected:     expected,
+		Actual:       kind,
+		ExpectedType: expectedType,
+		ActualType:   typ,
+		ActualPeer:   peer,
+		Step:         step,
+		Serial:       serial,
+	}
+	if m.cur != nil {
+		pv.ExpectedPeer = m.cur.peer
+	}
+	return pv
+}
+
+// advance moves past the current node after op completed with result v.
+// An offered label the Spec has no branch for leaves no further operation
+// allowed. A timed-out operation leaves the monitor where it is.
+func (m *monitor) advance(op opDescriber, v kont.Resumed) {
+	if t, ok := op.(timedOp); ok && t.timedOut(v) {
+		return
+	}
+	cur := m.cur
+	switch cur.kind {
+	case specChoose:
+		m.cur, _ = cur.branch(opLabel(op))
+	case specOffer:
+		m.cur, _ = cur.branch(offeredLabel(v))
+	case specEnd:
+		m.cur = nil
+	default:
+		m.cur = cur.next
+	}
+}
+
+// ProtocolViolation reports an operation that does not match the session
+// type: either the endpoint's Spec expected a different operation, or a
+// received value does not have the type the receiver asked for.
+type ProtocolViolation struct {
+	Expected     OpKind
+	Actual       OpKind
+	ExpectedType reflect.Type // payload type expected; nil if none
+	ActualType   reflect.Type // payload type performed or received; nil if none
+	ExpectedPeer Role         // role expected to be addressed; empty for dyadic sessions
+	ActualPeer   Role         // role addressed; empty for dyadic sessions
+	Step         int          // number of operations completed on the endpoint
+	Serial       Serial
+}
+
+// Error implements error.
+func (e *ProtocolViolation) Error() string {
+	return "sess: protocol violation in session " + strconv.FormatUint(uint64(e.Serial), 10) +
+		" at step " + strconv.Itoa(e.Step) +
+		": expected " + peerOpString(e.Expected, e.ExpectedType, e.ExpectedPeer) +
+		", got " + peerOpString(e.Actual, e.ActualType, e.ActualPeer)
+}
+
+func opString(k OpKind, t reflect.Type) string {
+	if t == nil {
+		return k.String()
+	}
+	return k.String() + "[" + t.String() + "]"
+}
+
+func peerOpString(k OpKind, t reflect.Type, peer Role) string {
+	if peer == "" {
+		return opString(k, t)
+	}
+	return opString(k, t) + " with " + string(peer)
+}
+
+// NewMonitored creates a connected pair of session endpoints like New,
+// with every operation validated at dispatch time. The first endpoint
+// follows spec and the second follows spec.Dual(). A mismatch is
+// returned from Advance as a *ProtocolViolation, and panics with the
+// same value in the blocking Exec and Run families.
+func NewMonitored(spec *Spec) (*Endpoint, *Endpoint) {
+	return NewWithOptions(WithMonitor(spec))
+}
+
+// SpecOf returns the Spec of side S of the protocol descriptor P.
+// Panics if P is not built from Msg, Branch and EndP.
+func SpecOf[P any, S Side]() *Spec {
+	var s S
+	_, sideB := any(s).(SideB)
+	return specOf[P](sideB)
+}
+
+// descriptor is implemented by the protocol descriptor types.
+type descriptor interface {
+	spec(sideB bool) *Spec
+}
+
+func specOf[P any](sideB bool) *Spec {
+	var p P
+	d, ok := any(p).(descriptor)
+	if !ok {
+		panic("sess: " + reflect.TypeFor[P]().String() + " is not a protocol descriptor")
+	}
+	return d.spec(sideB)
+}
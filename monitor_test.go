@@ -0,0 +1,249 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+func TestSpecString(t *testing.T) {
+	spec := sess.SpecSend[int](sess.SpecRecv[string](sess.SpecEnd()))
+	if got, want := spec.String(), "!int.?string.end"; got != want {
+		t.Fatalf("String got %q, want %q", got, want)
+	}
+	if got, want := spec.Dual().String(), "?int.!string.end"; got != want {
+		t.Fatalf("Dual got %q, want %q", got, want)
+	}
+
+	choice := sess.SpecChoose(sess.SpecEnd(), sess.SpecSend[bool](sess.SpecEnd()))
+	if got, want := choice.Dual().String(), "&{end, ?bool.end}"; got != want {
+		t.Fatalf("Dual choice got %q, want %q", got, want)
+	}
+}
+
+func TestSpecOfDescriptor(t *testing.T) {
+	a := sess.SpecOf[echoProto, sess.SideA]()
+	b := sess.SpecOf[echoProto, sess.SideB]()
+	if got, want := a.String(), "!int.?string.end"; got != want {
+		t.Fatalf("SideA got %q, want %q", got, want)
+	}
+	if got, want := b.String(), a.Dual().String(); got != want {
+		t.Fatalf("SideB got %q, want %q", got, want)
+	}
+	if got, want := sess.SpecOf[choiceProto, sess.SideB]().String(), "&{?int.end, ?string.end}"; got != want {
+		t.Fatalf("choice SideB got %q, want %q", got, want)
+	}
+}
+
+func TestSpecOfNonDescriptorPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for non-descriptor")
+		}
+	}()
+	sess.SpecOf[sess.SendP[int, string], sess.SideA]()
+}
+
+func TestSpecLoop(t *testing.T) {
+	spec := sess.SpecLoop(func(loop *sess.Spec) *sess.Spec {
+		return sess.SpecChoose(sess.SpecSend[int](loop), sess.SpecEnd())
+	})
+	if got, want := spec.String(), "⊕{!int.μ, end}"; got != want {
+		t.Fatalf("String got %q, want %q", got, want)
+	}
+	if got, want := spec.Dual().String(), "&{?int.μ, end}"; got != want {
+		t.Fatalf("Dual got %q, want %q", got, want)
+	}
+}
+
+func TestMonitoredProtocol(t *testing.T) {
+	skipRace(t)
+	spec := sess.SpecLoop(func(loop *sess.Spec) *sess.Spec {
+		return sess.SpecChoose(sess.SpecSend[int](loop), sess.SpecEnd())
+	})
+	epA, epB := sess.NewMonitored(spec)
+
+	sender := sess.ExprLoop(0, func(i int) kont.Expr[kont.Either[int, struct{}]] {
+		if i == 3 {
+			return sess.ExprSelectRThen(sess.ExprCloseDone(kont.Right[int, struct{}](struct{}{})))
+		}
+		return sess.ExprSelectLThen(sess.ExprSendThen(i, kont.ExprReturn(kont.Left[int, struct{}](i+1))))
+	})
+	receiver := sess.ExprLoop(0, func(sum int) kont.Expr[kont.Either[int, int]] {
+		return sess.ExprOfferBranch(
+			func() kont.Expr[kont.Either[int, int]] {
+				return sess.ExprRecvBind(func(n int) kont.Expr[kont.Either[int, int]] {
+					return kont.ExprReturn(kont.Left[int, int](sum + n))
+				})
+			},
+			func() kont.Expr[kont.Either[int, int]] {
+				return sess.ExprCloseDone(kont.Right[int, int](sum))
+			},
+		)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		sess.ExecExpr(epA, sender)
+		close(done)
+	}()
+	if got := sess.ExecExpr(epB, receiver); got != 3 {
+		t.Fatalf("receiver got %d, want 3", got)
+	}
+	<-done
+}
+
+func TestMonitorWrongOperation(t *testing.T) {
+	epA, _ := sess.NewMonitored(sess.SpecSend[int](sess.SpecEnd()))
+
+	_, susp := sess.Step[int](sess.ExprRecvBind(func(n int) kont.Expr[int] {
+		return sess.ExprCloseDone(n)
+	}))
+	_, same, err := sess.Advance(epA, susp)
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) {
+		t.Fatalf("expected *ProtocolViolation, got %v", err)
+	}
+	if pv.Expected != sess.OpSend || pv.Actual != sess.OpRecv {
+		t.Fatalf("got expected=%v actual=%v, want Send/Recv", pv.Expected, pv.Actual)
+	}
+	if pv.Step != 0 || pv.Serial != epA.Serial() {
+		t.Fatalf("got step=%d serial=%d, want 0/%d", pv.Step, pv.Serial, epA.Serial())
+	}
+	if same != susp {
+		t.Fatal("suspension must be returned unconsumed on violation")
+	}
+	same.Discard()
+	if !strings.Contains(err.Error(), "expected Send[int], got Recv[int]") {
+		t.Fatalf("unexpected message: %v", err)
+	}
+}
+
+func TestMonitorWrongPayloadType(t *testing.T) {
+	epA, _ := sess.NewMonitored(sess.SpecSend[int](sess.SpecEnd()))
+
+	_, susp := sess.Step[struct{}](sess.ExprSendThen("x", sess.ExprCloseDone(struct{}{})))
+	_, _, err := sess.Advance(epA, susp)
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) {
+		t.Fatalf("expected *ProtocolViolation, got %v", err)
+	}
+	if pv.ExpectedType != reflect.TypeFor[int]() || pv.ActualType != reflect.TypeFor[string]() {
+		t.Fatalf("got types %v/%v, want int/string", pv.ExpectedType, pv.ActualType)
+	}
+}
+
+func TestMonitorAfterClose(t *testing.T) {
+	epA, _ := sess.NewMonitored(sess.SpecEnd())
+
+	_, susp := sess.Step[struct{}](sess.ExprCloseDone(struct{}{}))
+	_, susp, err := sess.Advance(epA, susp)
+	if err != nil || susp != nil {
+		t.Fatalf("Close: susp=%v err=%v", susp, err)
+	}
+
+	_, susp = sess.Step[struct{}](sess.ExprSelectLThen(kont.ExprReturn(struct{}{})))
+	_, _, err = sess.Advance(epA, susp)
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) {
+		t.Fatalf("expected *ProtocolViolation, got %v", err)
+	}
+	if pv.Expected != sess.OpNone || pv.Actual != sess.OpSelectL || pv.Step != 1 {
+		t.Fatalf("got expected=%v actual=%v step=%d, want None/SelectL/1", pv.Expected, pv.Actual, pv.Step)
+	}
+}
+
+func TestRecvTypeMismatch(t *testing.T) {
+	skipRace(t)
+	// Unmonitored: the receiver still reports a violation instead of panicking
+	// on the type assertion.
+	epA, epB := sess.New()
+
+	_, susp := sess.Step[struct{}](sess.ExprSendThen("x", kont.ExprReturn(struct{}{})))
+	if _, _, err := sess.Advance(epA, susp); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	_, recv := sess.Step[int](sess.ExprRecvBind(func(n int) kont.Expr[int] { return kont.ExprReturn(n) }))
+	_, _, err := sess.Advance(epB, recv)
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) {
+		t.Fatalf("expected *ProtocolViolation, got %v", err)
+	}
+	if pv.Expected != sess.OpRecv || pv.ExpectedType != reflect.TypeFor[int]() || pv.ActualType != reflect.TypeFor[string]() {
+		t.Fatalf("unexpected violation: %v", pv)
+	}
+}
+
+func TestExecMonitorViolationPanics(t *testing.T) {
+	epA, _ := sess.NewMonitored(sess.SpecEnd())
+
+	defer func() {
+		err, _ := recover().(error)
+		var pv *sess.ProtocolViolation
+		if !errors.As(err, &pv) {
+			t.Fatalf("expected *ProtocolViolation panic, got %v", err)
+		}
+	}()
+	sess.Exec(epA, sess.SendThen(1, sess.CloseDone(struct{}{})))
+}
+
+func TestSpecCases(t *testing.T) {
+	spec := sess.SpecChooseCases(map[sess.Label]*sess.Spec{
+		0: sess.SpecEnd(),
+		2: sess.SpecSend[int](sess.SpecEnd()),
+	})
+	if got, want := spec.String(), "⊕{0: end, 2: !int.end}"; got != want {
+		t.Fatalf("String got %q, want %q", got, want)
+	}
+	if got, want := spec.Dual().String(), "&{0: end, 2: ?int.end}"; got != want {
+		t.Fatalf("Dual got %q, want %q", got, want)
+	}
+}
+
+func TestMonitoredLabeledChoice(t *testing.T) {
+	skipRace(t)
+	spec := sess.SpecChooseCases(map[sess.Label]*sess.Spec{
+		sess.LabelLeft: sess.SpecEnd(),
+		5:              sess.SpecSend[int](sess.SpecEnd()),
+	})
+	epA, epB := sess.NewMonitored(spec)
+
+	done := make(chan int)
+	go func() {
+		done <- sess.Exec(epB, sess.OfferCases(map[sess.Label]func() kont.Eff[int]{
+			sess.LabelLeft: func() kont.Eff[int] { return sess.CloseDone(0) },
+			5:              func() kont.Eff[int] { return sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) }) },
+		}))
+	}()
+	sess.Exec(epA, sess.SelectThen(5, sess.SendThen(9, sess.CloseDone(struct{}{}))))
+	if got := <-done; got != 9 {
+		t.Fatalf("got %d, want 9", got)
+	}
+}
+
+func TestMonitorUnknownSelectLabel(t *testing.T) {
+	epA, _ := sess.NewMonitored(sess.SpecChooseCases(map[sess.Label]*sess.Spec{
+		sess.LabelLeft: sess.SpecEnd(),
+		5:              sess.SpecEnd(),
+	}))
+
+	_, susp := sess.Step(sess.ExprSelectRThen(sess.ExprCloseDone(0)))
+	_, _, err := sess.Advance(epA, susp)
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) {
+		t.Fatalf("expected *ProtocolViolation, got %v", err)
+	}
+	if pv.Expected != sess.OpSelect || pv.Actual != sess.OpSelectR {
+		t.Fatalf("got expected=%v actual=%v, want Select/SelectR", pv.Expected, pv.Actual)
+	}
+	susp.Discard()
+}
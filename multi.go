@@ -0,0 +1,425 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+)
+
+// Role names a participant of a multiparty session.
+type Role string
+
+// MultiEndpoint is one role's endpoint in a multiparty session created by
+// NewMulti. It holds a dyadic channel to every other role; role-addressed
+// operations are dispatched on the channel to the addressed role, so each
+// pair of roles keeps the ordering and backpressure of an Endpoint pair.
+type MultiEndpoint struct {
+	role   Role
+	serial Serial
+	index  map[Role]int // shared by all endpoints of the session
+	peers  []*Endpoint  // channel to each role by index; nil for own role
+	step   int
+	mon    *monitor
+	clock  Clock // nil for the system clock
+}
+
+// NewMulti creates a multiparty session with one endpoint per role,
+// returned in the order of roles. Every pair of roles is connected by
+// bounded lock-free SPSC queues as in New. Panics if fewer than two roles
+// are given or a role is repeated.
+func NewMulti(roles ...Role) []*MultiEndpoint {
+	if len(roles) < 2 {
+		panic("sess: NewMulti needs at least two roles")
+	}
+	s := nextSerial()
+	index := make(map[Role]int, len(roles))
+	eps := make([]*MultiEndpoint, len(roles))
+	for i, r := range roles {
+		if _, dup := index[r]; dup {
+			panic("sess: duplicate role " + strconv.Quote(string(r)))
+		}
+		index[r] = i
+		eps[i] = &MultiEndpoint{role: r, serial: s, index: index, peers: make([]*Endpoint, len(roles))}
+	}
+	for i := range eps {
+		for j := i + 1; j < len(eps); j++ {
+			a, b := newPair(s, channelCapacity, channelCapacity)
+			a.ctx.extend().role, a.ctx.ext.peer = roles[i], roles[j]
+			b.ctx.extend().role, b.ctx.ext.peer = roles[j], roles[i]
+			eps[i].peers[j], eps[j].peers[i] = a, b
+		}
+	}
+	return eps
+}
+
+// Role returns the role this endpoint plays.
+func (m *MultiEndpoint) Role() Role {
+	return m.role
+}
+
+// Serial returns the serial number assigned to the multiparty session.
+func (m *MultiEndpoint) Serial() Serial {
+	return m.serial
+}
+
+// SetClock sets the clock of the channel to every other role, and the
+// clock ExecMulti waits on; see Endpoint.SetClock.
+func (m *MultiEndpoint) SetClock(c Clock) {
+	m.clock = c
+	for _, ep := range m.peers {
+		if ep != nil {
+			ep.SetClock(c)
+		}
+	}
+}
+
+// Abort aborts the channel to every other role; see Endpoint.Abort.
+func (m *MultiEndpoint) Abort(reason error) {
+	for _, ep := range m.peers {
+		if ep != nil {
+			ep.ctx.abort(reason)
+		}
+	}
+}
+
+// peer returns the channel to role.
+func (m *MultiEndpoint) peer(role Role) (*Endpoint, error) {
+	i, ok := m.index[role]
+	if !ok {
+		return nil, errors.New("sess: unknown role " + strconv.Quote(string(role)))
+	}
+	if m.peers[i] == nil {
+		return nil, errors.New("sess: role " + strconv.Quote(string(role)) + " addressed itself")
+	}
+	return m.peers[i], nil
+}
+
+// multiDispatcher is the structural interface for role-addressed operations.
+// Like DispatchSession, DispatchMulti is non-blocking.
+type multiDispatcher interface {
+	DispatchMulti(m *MultiEndpoint) (kont.Resumed, error)
+	opInfo() (OpKind, reflect.Type)
+	peerRole() Role
+}
+
+// dispatchTo performs sop on the channel to role.
+func (m *MultiEndpoint) dispatchTo(role Role, sop sessionDispatcher) (kont.Resumed, error) {
+	ep, err := m.peer(role)
+	if err != nil {
+		return nil, err
+	}
+	return ep.ctx.dispatch(sop)
+}
+
+// dispatch performs op on m: a role-addressed operation on its channel,
+// or Close on every channel. Errors other than iox.ErrWouldBlock are terminal.
+func (m *MultiEndpoint) dispatch(op kont.Operation) (kont.Resumed, error) {
+	if m.mon != nil {
+		if d, ok := op.(opDescriber); ok {
+			if err := m.mon.check(d, m.step, m.serial); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var v kont.Resumed
+	var err error
+	switch op := op.(type) {
+	case multiDispatcher:
+		v, err = op.DispatchMulti(m)
+	case Close:
+		v, err = m.closeAll()
+	default:
+		panic("sess: unhandled effect in multiparty session")
+	}
+	if err == nil {
+		if m.mon != nil {
+			m.mon.advance(op.(opDescriber), v)
+		}
+		m.step++
+	}
+	return v, err
+}
+
+// closeAll closes the channel to every other role. Close never blocks.
+func (m *MultiEndpoint) closeAll() (kont.Resumed, error) {
+	for _, ep := range m.peers {
+		if ep == nil {
+			continue
+		}
+		if _, err := ep.ctx.dispatch(Close{}); err != nil {
+			return nil, err
+		}
+	}
+	return struct{}{}, nil
+}
+
+// SendTo is the effect operation for sending a value of type T to Role.
+type SendTo[T any] struct {
+	kont.Phantom[struct{}]
+	Role  Role
+	Value T
+}
+
+// DispatchMulti handles SendTo on the channel to Role.
+func (s SendTo[T]) DispatchMulti(m *MultiEndpoint) (kont.Resumed, error) {
+	return m.dispatchTo(s.Role, Send[T]{Value: s.Value})
+}
+
+func (SendTo[T]) opInfo() (OpKind, reflect.Type) { return OpSend, reflect.TypeFor[T]() }
+func (s SendTo[T]) peerRole() Role               { return s.Role }
+
+// RecvFrom is the effect operation for receiving a value of type T from Role.
+type RecvFrom[T any] struct {
+	kont.Phantom[T]
+	Role Role
+}
+
+// DispatchMulti handles RecvFrom on the channel to Role.
+func (r RecvFrom[T]) DispatchMulti(m *MultiEndpoint) (kont.Resumed, error) {
+	return m.dispatchTo(r.Role, Recv[T]{})
+}
+
+func (RecvFrom[T]) opInfo() (OpKind, reflect.Type) { return OpRecv, reflect.TypeFor[T]() }
+func (r RecvFrom[T]) peerRole() Role               { return r.Role }
+
+// SelectTo is the effect operation for sending a branch choice to Role:
+// the left branch, or the right branch if Right is set.
+type SelectTo struct {
+	kont.Phantom[struct{}]
+	Role  Role
+	Right bool
+}
+
+// DispatchMulti handles SelectTo on the channel to Role.
+func (s SelectTo) DispatchMulti(m *MultiEndpoint) (kont.Resumed, error) {
+	if s.Right {
+		return m.dispatchTo(s.Role, SelectR{})
+	}
+	return m.dispatchTo(s.Role, SelectL{})
+}
+
+func (s SelectTo) opInfo() (OpKind, reflect.Type) {
+	if s.Right {
+		return OpSelectR, nil
+	}
+	return OpSelectL, nil
+}
+func (s SelectTo) peerRole() Role { return s.Role }
+
+// OfferFrom is the effect operation for receiving a branch choice from Role.
+type OfferFrom struct {
+	kont.Phantom[kont.Either[struct{}, struct{}]]
+	Role Role
+}
+
+// DispatchMulti handles OfferFrom on the channel to Role.
+func (o OfferFrom) DispatchMulti(m *MultiEndpoint) (kont.Resumed, error) {
+	return m.dispatchTo(o.Role, Offer{})
+}
+
+func (OfferFrom) opInfo() (OpKind, reflect.Type) { return OpOffer, nil }
+func (o OfferFrom) peerRole() Role               { return o.Role }
+
+// multiHandler implements kont.Handler for multiparty session effects,
+// waiting on iox.ErrWouldBlock like sessionHandler.
+type multiHandler struct {
+	m *MultiEndpoint
+}
+
+// Dispatch implements kont.Handler.
+func (h multiHandler) Dispatch(op kont.Operation) (kont.Resumed, bool) {
+	bo := backoff{clock: h.m.clock}
+	for {
+		v, err := h.m.dispatch(op)
+		if err == nil {
+			return v, true
+		}
+		if err != iox.ErrWouldBlock {
+			panic(err)
+		}
+		bo.Wait()
+	}
+}
+
+// ExecMulti runs a Cont-world multiparty protocol on m, blocking on
+// iox.ErrWouldBlock via adaptive backoff. Close closes the channel to
+// every other role. Panics with the error value if an operation fails
+// terminally; if the protocol panics, every channel of m is aborted.
+func ExecMulti[R any](m *MultiEndpoint, protocol kont.Eff[R]) R {
+	defer m.abortOnPanic()
+	return kont.Handle(protocol, multiHandler{m: m})
+}
+
+// ExecMultiExpr runs an Expr-world multiparty protocol on m like ExecMulti.
+func ExecMultiExpr[R any](m *MultiEndpoint, protocol kont.Expr[R]) R {
+	defer m.abortOnPanic()
+	return kont.HandleExpr(protocol, multiHandler{m: m})
+}
+
+func (m *MultiEndpoint) abortOnPanic() {
+	if r := recover(); r != nil {
+		m.Abort(panicReason(r))
+		panic(r)
+	}
+}
+
+// AdvanceMulti dispatches the suspended multiparty operation on m.
+// It follows the contract of Advance: iox.ErrWouldBlock leaves the
+// suspension unconsumed for retry; any other error is terminal.
+func AdvanceMulti[R any](m *MultiEndpoint, susp *kont.Suspension[R]) (R, *kont.Suspension[R], error) {
+	v, err := m.dispatch(susp.Op())
+	if err != nil {
+		var zero R
+		return zero, susp, err
+	}
+	result, next := susp.Resume(v)
+	return result, next, nil
+}
+
+// RunMulti creates a multiparty session for the roles of protocols, runs
+// every role's Cont-world protocol interleaved on the calling goroutine,
+// and returns each role's result. Panics with a *MultiDeadlockError if no
+// role can make progress, or with the error value if an operation fails
+// terminally.
+func RunMulti[R any](protocols map[Role]kont.Eff[R]) map[Role]R {
+	results, err := TryRunMulti(protocols)
+	if err != nil {
+		panic(err)
+	}
+	return results
+}
+
+// RunMultiExpr is RunMulti for Expr-world protocols.
+func RunMultiExpr[R any](protocols map[Role]kont.Expr[R]) map[Role]R {
+	results, err := TryRunMultiExpr(protocols)
+	if err != nil {
+		panic(err)
+	}
+	return results
+}
+
+// TryRunMulti is like RunMulti but returns a deadlock or terminal
+// operation failure as an error instead of panicking.
+func TryRunMulti[R any](protocols map[Role]kont.Eff[R]) (map[Role]R, error) {
+	exprs := make(map[Role]kont.Expr[R], len(protocols))
+	for r, p := range protocols {
+		exprs[r] = Reify(p)
+	}
+	return TryRunMultiExpr(exprs)
+}
+
+// TryRunMultiExpr is like RunMultiExpr but returns a deadlock or terminal
+// operation failure as an error instead of panicking.
+// Roles are stepped in sorted order, so runs are deterministic.
+func TryRunMultiExpr[R any](protocols map[Role]kont.Expr[R]) (map[Role]R, error) {
+	roles := make([]Role, 0, len(protocols))
+	for r := range protocols {
+		roles = append(roles, r)
+	}
+	slices.Sort(roles)
+	eps := NewMulti(roles...)
+
+	results := make([]R, len(roles))
+	susps := make([]*kont.Suspension[R], len(roles))
+	pending := 0
+	for i, r := range roles {
+		results[i], susps[i] = Step[R](protocols[r])
+		if susps[i] != nil {
+			pending++
+		}
+	}
+
+	var err error
+	for pending > 0 && err == nil {
+		progress := false
+		for i, susp := range susps {
+			if susp == nil {
+				continue
+			}
+			var stepErr error
+			results[i], susps[i], stepErr = AdvanceMulti(eps[i], susp)
+			if stepErr == nil {
+				progress = true
+				if susps[i] == nil {
+					pending--
+				}
+			} else if stepErr != iox.ErrWouldBlock {
+				err = stepErr
+				break
+			}
+		}
+		if err == nil && !progress {
+			de := &MultiDeadlockError{Serial: eps[0].serial, Pending: make(map[Role]PendingOp, len(roles))}
+			for i, r := range roles {
+				de.Pending[r] = multiPendingOf(eps[i], susps[i])
+			}
+			err = de
+		}
+	}
+	if err != nil {
+		for _, susp := range susps {
+			discard(susp)
+		}
+		return nil, err
+	}
+	out := make(map[Role]R, len(roles))
+	for i, r := range roles {
+		out[r] = results[i]
+	}
+	return out, nil
+}
+
+// multiPendingOf describes the operation susp is suspended on, if any.
+func multiPendingOf[R any](m *MultiEndpoint, susp *kont.Suspension[R]) PendingOp {
+	p := PendingOp{Step: m.step}
+	if susp == nil {
+		return p
+	}
+	switch op := susp.Op().(type) {
+	case multiDispatcher:
+		p.Kind, p.Type = op.opInfo()
+		p.Peer = op.peerRole()
+	case Close:
+		p.Kind = OpClose
+	}
+	return p
+}
+
+// MultiDeadlockError reports that no role of a multiparty session run by
+// the RunMulti family can ever make progress.
+type MultiDeadlockError struct {
+	Serial  Serial
+	Pending map[Role]PendingOp
+}
+
+// Error implements error. Roles are listed in sorted order.
+func (e *MultiDeadlockError) Error() string {
+	roles := make([]Role, 0, len(e.Pending))
+	for r := range e.Pending {
+		roles = append(roles, r)
+	}
+	slices.Sort(roles)
+	var sb strings.Builder
+	sb.WriteString("sess: deadlock in multiparty session ")
+	sb.WriteString(strconv.FormatUint(uint64(e.Serial), 10))
+	for i, r := range roles {
+		if i == 0 {
+			sb.WriteString(": ")
+		} else {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(string(r))
+		sb.WriteByte(' ')
+		sb.WriteString(e.Pending[r].String())
+	}
+	return sb.String()
+}
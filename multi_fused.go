@@ -0,0 +1,101 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"code.hybscloud.com/kont"
+)
+
+// SendToThen sends a value to role and then continues with next.
+// Fuses Perform(SendTo[T]{Role: role, Value: v}) + Then.
+func SendToThen[T, B any](role Role, v T, next kont.Eff[B]) kont.Eff[B] {
+	return kont.Then(kont.Perform(SendTo[T]{Role: role, Value: v}), next)
+}
+
+// RecvFromBind receives a value from role and passes it to f.
+// Fuses Perform(RecvFrom[T]{Role: role}) + Bind.
+func RecvFromBind[T, B any](role Role, f func(T) kont.Eff[B]) kont.Eff[B] {
+	return kont.Bind(kont.Perform(RecvFrom[T]{Role: role}), f)
+}
+
+// SelectLToThen selects the left branch towards role and continues with next.
+// Fuses Perform(SelectTo{Role: role}) + Then.
+func SelectLToThen[B any](role Role, next kont.Eff[B]) kont.Eff[B] {
+	return kont.Then(kont.Perform(SelectTo{Role: role}), next)
+}
+
+// SelectRToThen selects the right branch towards role and continues with next.
+// Fuses Perform(SelectTo{Role: role, Right: true}) + Then.
+func SelectRToThen[B any](role Role, next kont.Eff[B]) kont.Eff[B] {
+	return kont.Then(kont.Perform(SelectTo{Role: role, Right: true}), next)
+}
+
+// OfferFromBranch waits for role's choice and calls onLeft or onRight.
+// Fuses Perform(OfferFrom{Role: role}) + Bind + Either branch.
+func OfferFromBranch[A any](role Role, onLeft func() kont.Eff[A], onRight func() kont.Eff[A]) kont.Eff[A] {
+	return kont.Bind(kont.Perform(OfferFrom{Role: role}), func(e kont.Either[struct{}, struct{}]) kont.Eff[A] {
+		if e.IsLeft() {
+			return onLeft()
+		}
+		return onRight()
+	})
+}
+
+// ExprSendToThen sends a value to role and then continues with next.
+// Fuses ExprPerform(SendTo[T]{Role: role, Value: v}) + ExprThen.
+func ExprSendToThen[T, B any](role Role, v T, next kont.Expr[B]) kont.Expr[B] {
+	return exprThen(SendTo[T]{Role: role, Value: v}, next)
+}
+
+// ExprRecvFromBind receives a value from role and passes it to f.
+// Fuses ExprPerform(RecvFrom[T]{Role: role}) + ExprBind.
+func ExprRecvFromBind[T, B any](role Role, f func(T) kont.Expr[B]) kont.Expr[B] {
+	bf := kont.AcquireUnwindFrame()
+	bf.Data1 = f
+	bf.Unwind = recvBindUnwind[T, B]
+	ef := kont.AcquireEffectFrame()
+	ef.Operation = RecvFrom[T]{Role: role}
+	ef.Resume = identityResume
+	ef.Next = bf
+	return kont.ExprSuspend[B](ef)
+}
+
+// ExprSelectLToThen selects the left branch towards role and continues with next.
+// Fuses ExprPerform(SelectTo{Role: role}) + ExprThen.
+func ExprSelectLToThen[B any](role Role, next kont.Expr[B]) kont.Expr[B] {
+	return exprThen(SelectTo{Role: role}, next)
+}
+
+// ExprSelectRToThen selects the right branch towards role and continues with next.
+// Fuses ExprPerform(SelectTo{Role: role, Right: true}) + ExprThen.
+func ExprSelectRToThen[B any](role Role, next kont.Expr[B]) kont.Expr[B] {
+	return exprThen(SelectTo{Role: role, Right: true}, next)
+}
+
+// ExprOfferFromBranch waits for role's choice and calls onLeft or onRight.
+// Fuses ExprPerform(OfferFrom{Role: role}) + ExprBind + Either branch.
+func ExprOfferFromBranch[A any](role Role, onLeft func() kont.Expr[A], onRight func() kont.Expr[A]) kont.Expr[A] {
+	bf := kont.AcquireUnwindFrame()
+	bf.Data1 = onLeft
+	bf.Data2 = onRight
+	bf.Unwind = offerBranchUnwind[A]
+	ef := kont.AcquireEffectFrame()
+	ef.Operation = OfferFrom{Role: role}
+	ef.Resume = identityResume
+	ef.Next = bf
+	return kont.ExprSuspend[A](ef)
+}
+
+// exprThen performs op and then continues with next.
+func exprThen[B any](op kont.Erased, next kont.Expr[B]) kont.Expr[B] {
+	tf := kont.AcquireThenFrame()
+	tf.Second = kont.Expr[kont.Erased]{Value: kont.Erased(next.Value), Frame: next.Frame}
+	tf.Next = exprReturnFrame
+	ef := kont.AcquireEffectFrame()
+	ef.Operation = op
+	ef.Resume = identityResume
+	ef.Next = tf
+	return kont.ExprSuspend[B](ef)
+}
@@ -0,0 +1,140 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+const (
+	client  sess.Role = "client"
+	gateway sess.Role = "gateway"
+	backend sess.Role = "backend"
+)
+
+// client → gateway: request; gateway → backend: forwarded request;
+// backend → gateway: reply; gateway → client: reply.
+func relayProtocols() map[sess.Role]kont.Eff[string] {
+	return map[sess.Role]kont.Eff[string]{
+		client: sess.SendToThen(gateway, 20, sess.RecvFromBind(gateway, func(s string) kont.Eff[string] {
+			return sess.CloseDone(s)
+		})),
+		gateway: sess.RecvFromBind(client, func(n int) kont.Eff[string] {
+			return sess.SendToThen(backend, n+1, sess.RecvFromBind(backend, func(s string) kont.Eff[string] {
+				return sess.SendToThen(client, s, sess.CloseDone("relayed"))
+			}))
+		}),
+		backend: sess.RecvFromBind(gateway, func(n int) kont.Eff[string] {
+			return sess.SendToThen(gateway, strings.Repeat("x", n%5), sess.CloseDone("served"))
+		}),
+	}
+}
+
+func TestRunMultiRelay(t *testing.T) {
+	skipRace(t)
+	got := sess.RunMulti(relayProtocols())
+	if got[client] != "x" || got[gateway] != "relayed" || got[backend] != "served" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestRunMultiExprChoice(t *testing.T) {
+	skipRace(t)
+	// The client picks a branch at the gateway, which forwards it to the backend.
+	forward := func(pick func(sess.Role, kont.Expr[int]) kont.Expr[int]) map[sess.Role]kont.Expr[int] {
+		return map[sess.Role]kont.Expr[int]{
+			client: pick(gateway, sess.ExprRecvFromBind(backend, func(n int) kont.Expr[int] {
+				return sess.ExprCloseDone(n)
+			})),
+			gateway: sess.ExprOfferFromBranch(client,
+				func() kont.Expr[int] { return sess.ExprSelectLToThen(backend, sess.ExprCloseDone(1)) },
+				func() kont.Expr[int] { return sess.ExprSelectRToThen(backend, sess.ExprCloseDone(2)) },
+			),
+			backend: sess.ExprOfferFromBranch(gateway,
+				func() kont.Expr[int] { return sess.ExprSendToThen(client, 10, sess.ExprCloseDone(0)) },
+				func() kont.Expr[int] { return sess.ExprSendToThen(client, 20, sess.ExprCloseDone(0)) },
+			),
+		}
+	}
+
+	got := sess.RunMultiExpr(forward(sess.ExprSelectLToThen[int]))
+	if got[client] != 10 || got[gateway] != 1 {
+		t.Fatalf("left got %v", got)
+	}
+	got = sess.RunMultiExpr(forward(sess.ExprSelectRToThen[int]))
+	if got[client] != 20 || got[gateway] != 2 {
+		t.Fatalf("right got %v", got)
+	}
+}
+
+func TestExecMultiGoroutines(t *testing.T) {
+	skipRace(t)
+	eps := sess.NewMulti(client, gateway, backend)
+	if eps[1].Role() != gateway || eps[0].Serial() != eps[2].Serial() {
+		t.Fatalf("unexpected endpoints: %v %v", eps[1].Role(), eps[0].Serial())
+	}
+	ps := relayProtocols()
+
+	done := make(chan string, 2)
+	go func() { done <- sess.ExecMulti(eps[1], ps[gateway]) }()
+	go func() { done <- sess.ExecMulti(eps[2], ps[backend]) }()
+	if got := sess.ExecMulti(eps[0], ps[client]); got != "x" {
+		t.Fatalf("client got %q, want x", got)
+	}
+	<-done
+	<-done
+}
+
+func TestTryRunMultiDeadlock(t *testing.T) {
+	skipRace(t)
+	// A three-way cycle of receives.
+	_, err := sess.TryRunMultiExpr(map[sess.Role]kont.Expr[int]{
+		client:  sess.ExprRecvFromBind(backend, func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) }),
+		gateway: sess.ExprRecvFromBind(client, func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) }),
+		backend: sess.ExprRecvFromBind(gateway, func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) }),
+	})
+	var de *sess.MultiDeadlockError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected *MultiDeadlockError, got %v", err)
+	}
+	if p := de.Pending[gateway]; p.Kind != sess.OpRecv || p.Peer != client {
+		t.Fatalf("gateway pending got %v", p)
+	}
+	if !strings.Contains(err.Error(), "backend Recv[int] with gateway at step 0, client Recv[int] with backend") {
+		t.Fatalf("unexpected message: %v", err)
+	}
+}
+
+func TestTryRunMultiUnknownRole(t *testing.T) {
+	skipRace(t)
+	_, err := sess.TryRunMulti(map[sess.Role]kont.Eff[int]{
+		client:  sess.SendToThen("nobody", 1, sess.CloseDone(0)),
+		gateway: sess.CloseDone(0),
+	})
+	if err == nil || !strings.Contains(err.Error(), `unknown role "nobody"`) {
+		t.Fatalf("got %v, want unknown role error", err)
+	}
+}
+
+func TestNewMultiPanics(t *testing.T) {
+	for name, roles := range map[string][]sess.Role{
+		"one role":  {client},
+		"duplicate": {client, gateway, client},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected panic", name)
+				}
+			}()
+			sess.NewMulti(roles...)
+		}()
+	}
+}
@@ -5,9 +5,46 @@
 package sess
 
 import (
+	"reflect"
+	"strconv"
+
 	"code.hybscloud.com/kont"
 )
 
+// OpKind identifies the kind of a session operation.
+type OpKind uint8
+
+const (
+	// OpNone is the kind expected once a session has been closed.
+	OpNone OpKind = iota
+	OpSend
+	OpRecv
+	OpSelectL
+	OpSelectR
+	OpOffer
+	OpClose
+	OpSelect
+)
+
+var opKindNames = [...]string{
+	OpNone:    "None",
+	OpSend:    "Send",
+	OpRecv:    "Recv",
+	OpSelectL: "SelectL",
+	OpSelectR: "SelectR",
+	OpOffer:   "Offer",
+	OpClose:   "Close",
+	OpSelect:  "Select",
+}
+
+// String returns the name of the operation kind.
+func (k OpKind) String() string {
+	if int(k) < len(opKindNames) {
+		return opKindNames[k]
+	}
+	return "OpKind(" + strconv.Itoa(int(k)) + ")"
+}
+
 // Send is the effect operation for sending a value of type T.
 // Perform(Send[T]{Value: v}) sends v to the peer endpoint.
 type Send[T any] struct {
@@ -18,13 +55,30 @@ type Send[T any] struct {
 // DispatchSession handles Send on the session transport.
 // Non-blocking: returns iox.ErrWouldBlock if the bounded SPSC queue is full.
 func (s Send[T]) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
-	ctx.sendSlot = s.Value
+	if ctx.ext.wire != nil {
+		return wireSend(ctx, reflect.TypeFor[T](), s.Value)
+	}
+	if ctx.ext.codec != nil {
+		data, err := encodePayload(ctx, reflect.TypeFor[T](), s.Value)
+		if err != nil {
+			return nil, err
+		}
+		ctx.sendSlot = data
+	} else {
+		ctx.sendSlot = s.Value
+		if ep, ok := ctx.sendSlot.(*Endpoint); ok && ep.ctx.ext.owner != nil {
+			return sendEndpoint(ctx, ep)
+		}
+	}
 	if err := ctx.sendQ.Enqueue(&ctx.sendSlot); err != nil {
 		return nil, err
 	}
 	return struct{}{}, nil
 }
 
+func (Send[T]) opInfo() (OpKind, reflect.Type) { return OpSend, reflect.TypeFor[T]() }
+func (s Send[T]) payload(kont.Resumed) any     { return s.Value }
+
 // Recv is the effect operation for receiving a value of type T.
 // Perform(Recv[T]{}) receives a typed value from the peer.
 type Recv[T any] struct {
@@ -33,14 +87,35 @@ type Recv[T any] struct {
 
 // DispatchSession handles Recv on the session transport.
 // Non-blocking: returns iox.ErrWouldBlock if the bounded SPSC queue is empty.
+// A value of another type than T is reported as a *ProtocolViolation.
 func (Recv[T]) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
 	v, err := ctx.recvQ.Dequeue()
 	if err != nil {
 		return nil, err
 	}
-	return v.(T), nil
+	if ctx.ext.codec != nil {
+		if d, ok := v.(delegation); ok {
+			return acceptEndpoint[T](ctx, d)
+		}
+		return decodePayload[T](ctx, v)
+	}
+	t, ok := v.(T)
+	if !ok {
+		return nil, &ProtocolViolation{
+			Expected:     OpRecv,
+			Actual:       OpRecv,
+			ExpectedType: reflect.TypeFor[T](),
+			ActualType:   reflect.TypeOf(v),
+			Step:         ctx.step,
+			Serial:       ctx.serial,
+		}
+	}
+	return t, nil
 }
 
+func (Recv[T]) opInfo() (OpKind, reflect.Type) { return OpRecv, reflect.TypeFor[T]() }
+func (Recv[T]) payload(v kont.Resumed) any     { return v }
+
 // Close is the effect operation for closing the session.
 // Perform(Close{}) signals session termination.
 type Close struct {
@@ -48,17 +123,38 @@ type Close struct {
 }
 
 // DispatchSession handles Close on the session transport.
-// Atomically increments the shared close counter. Never blocks.
+// Atomically sets this endpoint's close bit in the shared state. Never blocks,
+// except on a network endpoint, where the close frame is queued behind
+// pending sends and iox.ErrWouldBlock is returned while the queue is full.
 func (Close) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
-	ctx.closed.Add(1)
+	if ctx.ext.wire != nil {
+		if _, err := wireQueue(ctx, wireClose); err != nil {
+			return nil, err
+		}
+	}
+	ctx.state.bits.Or(ctx.closeBit)
 	return struct{}{}, nil
 }
 
+func (Close) opInfo() (OpKind, reflect.Type) { return OpClose, nil }
+
+// Label names a branch of a labeled choice. SelectL and SelectR choose
+// LabelLeft and LabelRight, so binary and labeled choices share one
+// choice queue and interoperate: OfferCases can serve a peer using
+// SelectL, and Offer a peer selecting one of the first two labels.
+type Label uint32
+
+// Labels of the binary choice.
+const (
+	LabelLeft Label = iota
+	LabelRight
+)
+
 // signalLeft and signalRight are pre-allocated choice values
 // for SelectL/SelectR, avoiding per-dispatch heap escape.
 var (
-	signalLeft  = true
-	signalRight = false
+	signalLeft  = LabelLeft
+	signalRight = LabelRight
 )
 
 // offerLeft and offerRight are pre-boxed Resumed values for Offer dispatch.
@@ -78,12 +174,18 @@ type SelectL struct {
 // DispatchSession handles SelectL on the session transport.
 // Non-blocking: returns iox.ErrWouldBlock if the choice queue is full.
 func (SelectL) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
+	if ctx.ext.wire != nil {
+		return wireQueue(ctx, wireSelectL)
+	}
 	if err := ctx.signalQ.Enqueue(&signalLeft); err != nil {
 		return nil, err
 	}
 	return struct{}{}, nil
 }
 
+func (SelectL) opInfo() (OpKind, reflect.Type) { return OpSelectL, nil }
+func (SelectL) selectLabel() Label             { return LabelLeft }
+
 // SelectR is the effect operation for choosing the right branch.
 // Perform(SelectR{}) signals the right choice to the peer.
 type SelectR struct {
@@ -93,12 +195,40 @@ type SelectR struct {
 // DispatchSession handles SelectR on the session transport.
 // Non-blocking: returns iox.ErrWouldBlock if the choice queue is full.
 func (SelectR) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
+	if ctx.ext.wire != nil {
+		return wireQueue(ctx, wireSelectR)
+	}
 	if err := ctx.signalQ.Enqueue(&signalRight); err != nil {
 		return nil, err
 	}
 	return struct{}{}, nil
 }
 
+func (SelectR) opInfo() (OpKind, reflect.Type) { return OpSelectR, nil }
+func (SelectR) selectLabel() Label             { return LabelRight }
+
+// Select is the effect operation for choosing the branch named Label.
+// Perform(Select{Label: l}) signals l to the peer.
+type Select struct {
+	kont.Phantom[struct{}]
+	Label Label
+}
+
+// DispatchSession handles Select on the session transport.
+// Non-blocking: returns iox.ErrWouldBlock if the choice queue is full.
+func (s Select) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
+	if ctx.ext.wire != nil {
+		return wireQueue(ctx, wireLabel(s.Label))
+	}
+	if err := ctx.signalQ.Enqueue(&s.Label); err != nil {
+		return nil, err
+	}
+	return struct{}{}, nil
+}
+
+func (Select) opInfo() (OpKind, reflect.Type) { return OpSelect, nil }
+func (s Select) selectLabel() Label           { return s.Label }
+
 // Offer is the effect operation for receiving a branch choice from the peer.
 // Perform(Offer{}) receives the peer's Left or Right selection.
 type Offer struct {
@@ -107,14 +237,78 @@ type Offer struct {
 
 // DispatchSession handles Offer on the session transport.
 // Non-blocking: returns iox.ErrWouldBlock if the choice queue is empty.
-// true → Left (peer selected left), false → Right (peer selected right).
+// A label other than LabelLeft and LabelRight is reported as a *LabelError.
 func (Offer) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
-	v, err := ctx.awaitQ.Dequeue()
+	l, err := ctx.awaitQ.Dequeue()
 	if err != nil {
 		return nil, err
 	}
-	if v {
+	switch l {
+	case LabelLeft:
 		return offerLeft, nil
+	case LabelRight:
+		return offerRight, nil
 	}
-	return offerRight, nil
+	return nil, &LabelError{Label: l, Step: ctx.step, Serial: ctx.serial}
+}
+
+func (Offer) opInfo() (OpKind, reflect.Type) { return OpOffer, nil }
+
+// OfferLabel is the effect operation for receiving a labeled choice.
+// Perform(OfferLabel{}) receives the Label the peer selected; any label
+// is accepted. OfferCases also rejects labels it has no case for.
+type OfferLabel struct {
+	kont.Phantom[Label]
+}
+
+// DispatchSession handles OfferLabel on the session transport.
+// Non-blocking: returns iox.ErrWouldBlock if the choice queue is empty.
+func (OfferLabel) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
+	l, err := ctx.awaitQ.Dequeue()
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (OfferLabel) opInfo() (OpKind, reflect.Type) { return OpOffer, nil }
+
+// offerCases is the operation behind OfferCases and ExprOfferCases.
+// Its single map field keeps it pointer-shaped, so boxing it into an
+// operation does not allocate.
+type offerCases[F any] struct {
+	cases map[Label]F
+}
+
+// OpResult implements kont.Op.
+func (offerCases[F]) OpResult() Label { panic("phantom") }
+
+// DispatchSession receives a label like OfferLabel, reporting a label
+// without a case as a *LabelError.
+func (o offerCases[F]) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
+	l, err := ctx.awaitQ.Dequeue()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := o.cases[l]; !ok {
+		return nil, &LabelError{Label: l, Step: ctx.step, Serial: ctx.serial}
+	}
+	return l, nil
+}
+
+func (offerCases[F]) opInfo() (OpKind, reflect.Type) { return OpOffer, nil }
+
+// LabelError reports a choice label that the offering endpoint has no
+// branch for.
+type LabelError struct {
+	Label  Label
+	Step   int // number of operations completed on the endpoint
+	Serial Serial
+}
+
+// Error implements error.
+func (e *LabelError) Error() string {
+	return "sess: unknown label " + strconv.FormatUint(uint64(e.Label), 10) +
+		" offered in session " + strconv.FormatUint(uint64(e.Serial), 10) +
+		" at step " + strconv.Itoa(e.Step)
 }
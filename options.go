@@ -0,0 +1,103 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+// Option configures a session created by NewWithOptions.
+type Option func(*options)
+
+// options holds the configuration of NewWithOptions.
+type options struct {
+	dataCap   int
+	choiceCap int
+	serial    Serial
+	hasSerial bool
+	tracer    Tracer
+	codec     Codec
+	spec      *Spec
+	recorder  *Recorder
+	clock     Clock
+	linear    bool
+	hasLinear bool
+}
+
+// WithDataCapacity sets the capacity of the two data queues, which carry
+// Send payloads. A deep queue lets a streaming sender run ahead of its
+// receiver; a capacity of 1 keeps the two in lockstep. Capacities above 1
+// are rounded up to a power of two. WithDataCapacity panics if n is not in
+// [1, 1<<20].
+func WithDataCapacity(n int) Option {
+	checkCapacity(n)
+	return func(o *options) { o.dataCap = n }
+}
+
+// WithChoiceCapacity sets the capacity of the two choice queues, which
+// carry SelectL, SelectR and Select labels, as WithDataCapacity does for
+// data.
+func WithChoiceCapacity(n int) Option {
+	checkCapacity(n)
+	return func(o *options) { o.choiceCap = n }
+}
+
+// WithSerial assigns s to the session instead of the next serial. Serials
+// set this way are not checked for uniqueness.
+func WithSerial(s Serial) Option {
+	return func(o *options) { o.serial, o.hasSerial = s, true }
+}
+
+// WithTracer sets the tracer of both endpoints; see Endpoint.SetTracer.
+func WithTracer(t Tracer) Option {
+	return func(o *options) { o.tracer = t }
+}
+
+// WithCodec makes the session serialize every Send payload with c, as a
+// network endpoint does, and decode it into the type the receiver asks
+// for. Receivers get copies rather than shared values, and payloads that
+// c cannot encode fail the same way they would over a connection; this
+// makes the session a stand-in for a network session in tests. Endpoints
+// cannot be delegated over such a session.
+func WithCodec(c Codec) Option {
+	return func(o *options) { o.codec = c }
+}
+
+// WithMonitor validates every operation as NewMonitored does: the first
+// endpoint follows spec and the second follows spec.Dual().
+func WithMonitor(spec *Spec) Option {
+	return func(o *options) { o.spec = spec }
+}
+
+// NewWithOptions creates a connected pair of session endpoints like New,
+// configured by opts. New remains the cheapest way to create a session.
+func NewWithOptions(opts ...Option) (*Endpoint, *Endpoint) {
+	o := options{dataCap: channelCapacity, choiceCap: channelCapacity}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !o.hasSerial {
+		o.serial = nextSerial()
+	}
+	a, b := newPair(o.serial, o.dataCap, o.choiceCap)
+	for _, ctx := range []*sessionContext{&a.ctx, &b.ctx} {
+		if o.tracer != nil {
+			ctx.extend().tracer = o.tracer
+		}
+		if o.codec != nil {
+			ctx.extend().codec = o.codec
+		}
+		if o.recorder != nil {
+			ctx.extend().recorder = o.recorder
+		}
+		if o.clock != nil {
+			ctx.extend().clock = o.clock
+		}
+		if o.hasLinear && (o.linear || ctx.ext.owner != nil) {
+			ctx.extend().owner = newOwnership(o.linear)
+		}
+	}
+	if o.spec != nil {
+		a.ctx.extend().mon = &monitor{cur: o.spec}
+		b.ctx.extend().mon = &monitor{cur: o.spec.Dual()}
+	}
+	return a, b
+}
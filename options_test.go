@@ -0,0 +1,165 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"errors"
+	"testing"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+// fill sends on ep until the data queue is full and returns the number of
+// values sent.
+func fill(t *testing.T, ep *sess.Endpoint) int {
+	t.Helper()
+	for n := 0; ; n++ {
+		_, _, err := sess.Advance(ep, stepSusp(sess.ExprSendThen(n, kont.ExprReturn(struct{}{}))))
+		if err == iox.ErrWouldBlock {
+			return n
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDataCapacity(t *testing.T) {
+	for _, tc := range []struct {
+		opts []sess.Option
+		want int
+	}{
+		{nil, 4},
+		{[]sess.Option{sess.WithDataCapacity(64)}, 64},
+		{[]sess.Option{sess.WithDataCapacity(5)}, 8},
+		{[]sess.Option{sess.WithDataCapacity(1)}, 1},
+		{[]sess.Option{sess.WithChoiceCapacity(64)}, 4},
+	} {
+		epA, epB := sess.NewWithOptions(tc.opts...)
+		if got := fill(t, epA); got != tc.want {
+			t.Errorf("%d options: queued %d values, want %d", len(tc.opts), got, tc.want)
+		}
+		// Values are delivered in order.
+		for i := range tc.want {
+			v, _, err := sess.Advance(epB, stepSusp(sess.ExprRecvBind(func(n int) kont.Expr[int] { return kont.ExprReturn(n) })))
+			if err != nil || v != i {
+				t.Fatalf("received %d, %v, want %d", v, err, i)
+			}
+		}
+	}
+}
+
+func TestDataCapacityOne(t *testing.T) {
+	epA, epB := sess.NewWithOptions(sess.WithDataCapacity(1))
+	recv := func() int {
+		v, _, err := sess.Advance(epB, stepSusp(sess.ExprRecvBind(func(n int) kont.Expr[int] { return kont.ExprReturn(n) })))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	// Each value must be taken before the next one is queued.
+	for i := range 3 {
+		if got := fill(t, epA); got != 1 {
+			t.Fatalf("round %d: queued %d values, want 1", i, got)
+		}
+		if v := recv(); v != 0 {
+			t.Fatalf("round %d: received %d, want 0", i, v)
+		}
+	}
+}
+
+func TestCapacityOutOfRange(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opt  func(int) sess.Option
+		n    int
+	}{
+		{"data 0", sess.WithDataCapacity, 0},
+		{"data -1", sess.WithDataCapacity, -1},
+		{"data 1<<20+1", sess.WithDataCapacity, 1<<20 + 1},
+		{"choice 0", sess.WithChoiceCapacity, 0},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: no panic", tc.name)
+				}
+			}()
+			tc.opt(tc.n)
+		}()
+	}
+}
+
+func TestChoiceCapacity(t *testing.T) {
+	epA, _ := sess.NewWithOptions(sess.WithChoiceCapacity(16))
+	n := 0
+	for {
+		_, _, err := sess.Advance(epA, stepSusp(sess.ExprSelectLThen(kont.ExprReturn(struct{}{}))))
+		if err == iox.ErrWouldBlock {
+			break
+		}
+		n++
+	}
+	if n != 16 {
+		t.Fatalf("queued %d choices, want 16", n)
+	}
+}
+
+func TestWithSerialAndTracer(t *testing.T) {
+	var tr recordingTracer
+	epA, epB := sess.NewWithOptions(sess.WithSerial(90000), sess.WithTracer(&tr))
+	if epA.Serial() != 90000 || epB.Serial() != 90000 {
+		t.Fatalf("serials %d/%d, want 90000", epA.Serial(), epB.Serial())
+	}
+	sess.Advance(epA, stepSusp(sess.ExprSendThen(1, kont.ExprReturn(struct{}{}))))
+	sess.Advance(epB, stepSusp(sess.ExprRecvBind(func(n int) kont.Expr[int] { return kont.ExprReturn(n) })))
+	if len(tr.records) != 2 || tr.records[0].ev.Serial != 90000 || tr.records[1].ev.Side != 1 {
+		t.Fatalf("traced %+v", tr.records)
+	}
+}
+
+func TestWithCodec(t *testing.T) {
+	epA, epB := sess.NewWithOptions(sess.WithCodec(sess.GobCodec{}))
+	sent := []int{1, 2, 3}
+	if _, _, err := sess.Advance(epA, stepSusp(sess.ExprSendThen(sent, kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatal(err)
+	}
+	sent[0] = 100
+	got, _, err := sess.Advance(epB, stepSusp(sess.ExprRecvBind(func(s []int) kont.Expr[[]int] { return kont.ExprReturn(s) })))
+	if err != nil || len(got) != 3 || got[0] != 1 {
+		t.Fatalf("received %v, %v, want a copy of [1 2 3]", got, err)
+	}
+
+	var ce *sess.CodecError
+	_, _, err = sess.Advance(epA, stepSusp(sess.ExprSendThen(func() {}, kont.ExprReturn(struct{}{}))))
+	if !errors.As(err, &ce) {
+		t.Fatalf("sending a func got %v, want *CodecError", err)
+	}
+}
+
+func TestWithCodecTypeCheck(t *testing.T) {
+	reg := sess.NewRegistry()
+	sess.Register[int](reg, 1)
+	sess.Register[string](reg, 2)
+	epA, epB := sess.NewWithOptions(sess.WithCodec(reg.Codec(sess.GobCodec{})))
+	sess.Advance(epA, stepSusp(sess.ExprSendThen(7, kont.ExprReturn(struct{}{}))))
+	_, _, err := sess.Advance(epB, stepSusp(sess.ExprRecvBind(func(s string) kont.Expr[string] { return kont.ExprReturn(s) })))
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) {
+		t.Fatalf("got %v, want *ProtocolViolation", err)
+	}
+}
+
+func TestWithMonitor(t *testing.T) {
+	epA, _ := sess.NewWithOptions(sess.WithMonitor(sess.SpecRecv[int](sess.SpecEnd())), sess.WithDataCapacity(32))
+	_, _, err := sess.Advance(epA, stepSusp(sess.ExprSendThen(1, kont.ExprReturn(struct{}{}))))
+	var pv *sess.ProtocolViolation
+	if !errors.As(err, &pv) || pv.Expected != sess.OpRecv || pv.Actual != sess.OpSend {
+		t.Fatalf("got %v, want a violation expecting Recv", err)
+	}
+}
@@ -0,0 +1,168 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"code.hybscloud.com/kont"
+)
+
+// SideA and SideB name the two ends of a protocol descriptor.
+// A descriptor is written once, from SideA's point of view;
+// SideB follows the same descriptor with every direction reversed.
+type (
+	SideA struct{}
+	SideB struct{}
+)
+
+func (SideA) dual() SideB { return SideB{} }
+func (SideB) dual() SideA { return SideA{} }
+
+// Side constrains a type parameter to one of the two protocol ends.
+type Side interface {
+	SideA | SideB
+}
+
+// Dual is satisfied only by the side opposite to S:
+// SideB satisfies Dual[SideA] and SideA satisfies Dual[SideB].
+// Typed receive and offer operations use it to require that the
+// peer, not the holder, is the active party of the current step.
+type Dual[S any] interface {
+	Side
+	dual() S
+}
+
+// Msg is the descriptor for a step where From sends a T to the other side,
+// after which both sides continue with Next.
+type Msg[From Side, T, Next any] struct{}
+
+// Branch is the descriptor for a step where By chooses between
+// the continuations L and R, and the other side follows the choice.
+type Branch[By Side, L, R any] struct{}
+
+// EndP is the descriptor for a closed session.
+type EndP struct{}
+
+func (Msg[From, T, Next]) spec(sideB bool) *Spec {
+	var from From
+	_, fromB := any(from).(SideB)
+	next := specOf[Next](sideB)
+	if fromB == sideB {
+		return SpecSend[T](next)
+	}
+	return SpecRecv[T](next)
+}
+
+func (Branch[By, L, R]) spec(sideB bool) *Spec {
+	var by By
+	_, byB := any(by).(SideB)
+	left, right := specOf[L](sideB), specOf[R](sideB)
+	if byB == sideB {
+		return SpecChoose(left, right)
+	}
+	return SpecOffer(left, right)
+}
+
+func (EndP) spec(bool) *Spec { return SpecEnd() }
+
+// SendP is !T.Next from SideA's point of view (?T.Next for SideB).
+type SendP[T, Next any] = Msg[SideA, T, Next]
+
+// RecvP is ?T.Next from SideA's point of view (!T.Next for SideB).
+type RecvP[T, Next any] = Msg[SideB, T, Next]
+
+// ChooseP is L ⊕ R from SideA's point of view (L & R for SideB).
+type ChooseP[L, R any] = Branch[SideA, L, R]
+
+// OfferP is L & R from SideA's point of view (L ⊕ R for SideB).
+type OfferP[L, R any] = Branch[SideB, L, R]
+
+// Chan is an endpoint typed by the residual protocol P seen from side S.
+// Each typed operation consumes a Chan and passes the continuation's Chan
+// to the next step, so only the next legal operation type-checks.
+// Both ends of a pair share the same P, which makes them dual by construction.
+type Chan[P any, S Side] struct {
+	ep *Endpoint
+}
+
+// Endpoint returns the untyped endpoint underlying c.
+func (c Chan[P, S]) Endpoint() *Endpoint {
+	return c.ep
+}
+
+// ChanOf types ep as side S of the protocol P, for example to delegate an
+// endpoint created by NewMonitored. Nothing is checked at this point; a
+// monitored ep is checked against P when it is delegated.
+func ChanOf[P any, S Side](ep *Endpoint) Chan[P, S] {
+	return Chan[P, S]{ep: ep}
+}
+
+// NewChan creates a connected pair of endpoints typed by the protocol P.
+// The first follows P as written; the second follows its dual.
+func NewChan[P any]() (Chan[P, SideA], Chan[P, SideB]) {
+	a, b := New()
+	return Chan[P, SideA]{ep: a}, Chan[P, SideB]{ep: b}
+}
+
+// ChanSendThen sends v and continues with the Chan of the next step.
+// Type-checks only when the holder of c is the sender of the current step.
+func ChanSendThen[S Side, T, N, B any](c Chan[Msg[S, T, N], S], v T, next func(Chan[N, S]) kont.Eff[B]) kont.Eff[B] {
+	return kont.Bind(kont.Perform(Send[T]{Value: v}), func(struct{}) kont.Eff[B] {
+		return next(Chan[N, S]{ep: c.ep})
+	})
+}
+
+// ChanRecvBind receives a value and passes it to f with the Chan of the next step.
+// Type-checks only when the peer of c is the sender of the current step.
+func ChanRecvBind[S Dual[O], O Side, T, N, B any](c Chan[Msg[O, T, N], S], f func(T, Chan[N, S]) kont.Eff[B]) kont.Eff[B] {
+	return kont.Bind(kont.Perform(Recv[T]{}), func(v T) kont.Eff[B] {
+		return f(v, Chan[N, S]{ep: c.ep})
+	})
+}
+
+// ChanSelectLThen selects the left branch and continues with its Chan.
+// Type-checks only when the holder of c is the chooser of the current step.
+func ChanSelectLThen[S Side, L, R, B any](c Chan[Branch[S, L, R], S], next func(Chan[L, S]) kont.Eff[B]) kont.Eff[B] {
+	return kont.Bind(kont.Perform(SelectL{}), func(struct{}) kont.Eff[B] {
+		return next(Chan[L, S]{ep: c.ep})
+	})
+}
+
+// ChanSelectRThen selects the right branch and continues with its Chan.
+// Type-checks only when the holder of c is the chooser of the current step.
+func ChanSelectRThen[S Side, L, R, B any](c Chan[Branch[S, L, R], S], next func(Chan[R, S]) kont.Eff[B]) kont.Eff[B] {
+	return kont.Bind(kont.Perform(SelectR{}), func(struct{}) kont.Eff[B] {
+		return next(Chan[R, S]{ep: c.ep})
+	})
+}
+
+// ChanOfferBranch waits for the peer's choice and calls onLeft or onRight
+// with the Chan of the chosen branch.
+// Type-checks only when the peer of c is the chooser of the current step.
+func ChanOfferBranch[S Dual[O], O Side, L, R, B any](c Chan[Branch[O, L, R], S], onLeft func(Chan[L, S]) kont.Eff[B], onRight func(Chan[R, S]) kont.Eff[B]) kont.Eff[B] {
+	return OfferBranch(
+		func() kont.Eff[B] { return onLeft(Chan[L, S]{ep: c.ep}) },
+		func() kont.Eff[B] { return onRight(Chan[R, S]{ep: c.ep}) },
+	)
+}
+
+// ChanCloseDone closes a session whose residual protocol is EndP and returns a.
+func ChanCloseDone[S Side, A any](_ Chan[EndP, S], a A) kont.Eff[A] {
+	return CloseDone(a)
+}
+
+// ExecChan runs a typed Cont-world protocol on c's endpoint.
+// Blocks on iox.ErrWouldBlock via adaptive backoff, like Exec.
+func ExecChan[P any, S Side, R any](c Chan[P, S], protocol func(Chan[P, S]) kont.Eff[R]) R {
+	return Exec(c.ep, protocol(c))
+}
+
+// RunChan creates a typed session pair for P, runs both sides on the
+// calling goroutine, and returns both results. The compiler accepts a
+// and b only if each follows P from its own side, so a non-dual pair
+// is rejected before any endpoint is created.
+func RunChan[P, A, B any](a func(Chan[P, SideA]) kont.Eff[A], b func(Chan[P, SideB]) kont.Eff[B]) (A, B) {
+	ca, cb := NewChan[P]()
+	return runExpr(ca.ep, cb.ep, Reify(a(ca)), Reify(b(cb)))
+}
@@ -0,0 +1,124 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"fmt"
+	"testing"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+// !int.?string.end from the client's (SideA) point of view.
+type echoProto = sess.SendP[int, sess.RecvP[string, sess.EndP]]
+
+// (!int.end) ⊕ (!string.end) from the client's (SideA) point of view.
+type choiceProto = sess.ChooseP[
+	sess.SendP[int, sess.EndP],
+	sess.SendP[string, sess.EndP],
+]
+
+func TestRunChanSendRecv(t *testing.T) {
+	skipRace(t)
+	client := func(c sess.Chan[echoProto, sess.SideA]) kont.Eff[string] {
+		return sess.ChanSendThen(c, 42, func(c sess.Chan[sess.RecvP[string, sess.EndP], sess.SideA]) kont.Eff[string] {
+			return sess.ChanRecvBind(c, func(s string, c sess.Chan[sess.EndP, sess.SideA]) kont.Eff[string] {
+				return sess.ChanCloseDone(c, s)
+			})
+		})
+	}
+	server := func(c sess.Chan[echoProto, sess.SideB]) kont.Eff[int] {
+		return sess.ChanRecvBind(c, func(n int, c sess.Chan[sess.RecvP[string, sess.EndP], sess.SideB]) kont.Eff[int] {
+			return sess.ChanSendThen(c, fmt.Sprintf("got %d", n), func(c sess.Chan[sess.EndP, sess.SideB]) kont.Eff[int] {
+				return sess.ChanCloseDone(c, n)
+			})
+		})
+	}
+
+	clientResult, serverResult := sess.RunChan(client, server)
+	if clientResult != "got 42" {
+		t.Fatalf("client got %q, want %q", clientResult, "got 42")
+	}
+	if serverResult != 42 {
+		t.Fatalf("server got %d, want 42", serverResult)
+	}
+}
+
+func TestRunChanChoice(t *testing.T) {
+	skipRace(t)
+	type left = sess.SendP[int, sess.EndP]
+	type right = sess.SendP[string, sess.EndP]
+
+	server := func(c sess.Chan[choiceProto, sess.SideB]) kont.Eff[string] {
+		return sess.ChanOfferBranch(c,
+			func(c sess.Chan[left, sess.SideB]) kont.Eff[string] {
+				return sess.ChanRecvBind(c, func(n int, c sess.Chan[sess.EndP, sess.SideB]) kont.Eff[string] {
+					return sess.ChanCloseDone(c, fmt.Sprintf("left:%d", n))
+				})
+			},
+			func(c sess.Chan[right, sess.SideB]) kont.Eff[string] {
+				return sess.ChanRecvBind(c, func(s string, c sess.Chan[sess.EndP, sess.SideB]) kont.Eff[string] {
+					return sess.ChanCloseDone(c, "right:"+s)
+				})
+			},
+		)
+	}
+
+	selectLeft := func(c sess.Chan[choiceProto, sess.SideA]) kont.Eff[struct{}] {
+		return sess.ChanSelectLThen(c, func(c sess.Chan[left, sess.SideA]) kont.Eff[struct{}] {
+			return sess.ChanSendThen(c, 7, func(c sess.Chan[sess.EndP, sess.SideA]) kont.Eff[struct{}] {
+				return sess.ChanCloseDone(c, struct{}{})
+			})
+		})
+	}
+	if _, got := sess.RunChan(selectLeft, server); got != "left:7" {
+		t.Fatalf("server got %q, want %q", got, "left:7")
+	}
+
+	selectRight := func(c sess.Chan[choiceProto, sess.SideA]) kont.Eff[struct{}] {
+		return sess.ChanSelectRThen(c, func(c sess.Chan[right, sess.SideA]) kont.Eff[struct{}] {
+			return sess.ChanSendThen(c, "hi", func(c sess.Chan[sess.EndP, sess.SideA]) kont.Eff[struct{}] {
+				return sess.ChanCloseDone(c, struct{}{})
+			})
+		})
+	}
+	if _, got := sess.RunChan(selectRight, server); got != "right:hi" {
+		t.Fatalf("server got %q, want %q", got, "right:hi")
+	}
+}
+
+func TestExecChanPair(t *testing.T) {
+	skipRace(t)
+	ca, cb := sess.NewChan[echoProto]()
+	if ca.Endpoint().Serial() != cb.Endpoint().Serial() {
+		t.Fatalf("pair serials differ: %d != %d", ca.Endpoint().Serial(), cb.Endpoint().Serial())
+	}
+
+	done := make(chan int)
+	go func() {
+		done <- sess.ExecChan(cb, func(c sess.Chan[echoProto, sess.SideB]) kont.Eff[int] {
+			return sess.ChanRecvBind(c, func(n int, c sess.Chan[sess.RecvP[string, sess.EndP], sess.SideB]) kont.Eff[int] {
+				return sess.ChanSendThen(c, "ok", func(c sess.Chan[sess.EndP, sess.SideB]) kont.Eff[int] {
+					return sess.ChanCloseDone(c, n)
+				})
+			})
+		})
+	}()
+
+	got := sess.ExecChan(ca, func(c sess.Chan[echoProto, sess.SideA]) kont.Eff[string] {
+		return sess.ChanSendThen(c, 5, func(c sess.Chan[sess.RecvP[string, sess.EndP], sess.SideA]) kont.Eff[string] {
+			return sess.ChanRecvBind(c, func(s string, c sess.Chan[sess.EndP, sess.SideA]) kont.Eff[string] {
+				return sess.ChanCloseDone(c, s)
+			})
+		})
+	})
+	if got != "ok" {
+		t.Fatalf("client got %q, want %q", got, "ok")
+	}
+	if n := <-done; n != 5 {
+		t.Fatalf("server got %d, want 5", n)
+	}
+}
@@ -0,0 +1,76 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"strconv"
+
+	"code.hybscloud.com/atomix"
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/lfq"
+)
+
+// maxQueueCapacity bounds the capacity of a session queue.
+const maxQueueCapacity = 1 << 20
+
+// queue is a bounded SPSC queue of an endpoint. The lfq ring holds at
+// least two elements; a queue of capacity 1 is a ring of two whose
+// producer waits for the consumer to take each element first.
+type queue[T any] struct {
+	lfq.SPSC[T]
+	single bool
+	full   atomix.Bool // single only: an element is in flight
+}
+
+// Init initializes q with the given capacity, which must be in
+// [1, maxQueueCapacity]. Capacities above 1 round up to a power of two.
+func (q *queue[T]) Init(capacity int) {
+	checkCapacity(capacity)
+	q.single = capacity == 1
+	q.SPSC.Init(max(capacity, 2))
+}
+
+// Enqueue adds an element (producer only), returning iox.ErrWouldBlock
+// if the queue is full.
+func (q *queue[T]) Enqueue(elem *T) error {
+	if !q.single {
+		return q.SPSC.Enqueue(elem)
+	}
+	if q.full.LoadAcquire() {
+		return iox.ErrWouldBlock
+	}
+	// Marked before publishing, so that the consumer's clear follows it.
+	q.full.StoreRelease(true)
+	if err := q.SPSC.Enqueue(elem); err != nil {
+		q.full.StoreRelease(false)
+		return err
+	}
+	return nil
+}
+
+// Dequeue removes and returns an element (consumer only), returning
+// iox.ErrWouldBlock if the queue is empty.
+func (q *queue[T]) Dequeue() (T, error) {
+	v, err := q.SPSC.Dequeue()
+	if err == nil && q.single {
+		q.full.StoreRelease(false)
+	}
+	return v, err
+}
+
+// Cap returns the capacity of q.
+func (q *queue[T]) Cap() int {
+	if q.single {
+		return 1
+	}
+	return q.SPSC.Cap()
+}
+
+// checkCapacity panics if n is not a valid queue capacity.
+func checkCapacity(n int) {
+	if n < 1 || n > maxQueueCapacity {
+		panic("sess: queue capacity " + strconv.Itoa(n) + " out of range [1, " + strconv.Itoa(maxQueueCapacity) + "]")
+	}
+}
@@ -0,0 +1,102 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"sync/atomic"
+)
+
+// readiness holds an endpoint's readiness callbacks. They are fired by
+// whoever changes the endpoint's queues or state: the peer endpoint's
+// operations, or a network endpoint's reader and writer goroutines.
+type readiness struct {
+	readable atomic.Pointer[func()]
+	writable atomic.Pointer[func()]
+}
+
+// notify fires the callbacks made due by sop, which the peer has just
+// performed: a send or choice can be received, a receive or offer has
+// freed queue space, and a close lets every operation return.
+func (r *readiness) notify(sop sessionDispatcher) {
+	rd, wr := r.readable.Load(), r.writable.Load()
+	if rd == nil && wr == nil {
+		return
+	}
+	switch kind, _ := sop.opInfo(); kind {
+	case OpSend, OpSelectL, OpSelectR, OpSelect:
+		wr = nil
+	case OpRecv, OpOffer:
+		rd = nil
+	}
+	fire(rd)
+	fire(wr)
+}
+
+// notifyAll fires both callbacks.
+func (r *readiness) notifyAll() {
+	fire(r.readable.Load())
+	fire(r.writable.Load())
+}
+
+// notifyReadable fires the readable callback.
+func (r *readiness) notifyReadable() {
+	fire(r.readable.Load())
+}
+
+// notifyWritable fires the writable callback.
+func (r *readiness) notifyWritable() {
+	fire(r.writable.Load())
+}
+
+func fire(fn *func()) {
+	if fn != nil {
+		(*fn)()
+	}
+}
+
+// OnReadable registers fn to be called whenever Recv or Offer on ep may
+// have become able to progress: the peer has sent a value or a choice,
+// closed, or the session was aborted. fn replaces any earlier callback;
+// nil removes it.
+//
+// fn runs on the goroutine that caused the change — the peer's, or a
+// network endpoint's reader — and must not block. It may be called
+// spuriously, so a woken event loop simply retries Advance. Registering
+// before the first Advance that returns iox.ErrWouldBlock ensures that no
+// notification is missed. It has no effect on a handle whose endpoint has
+// been delegated away.
+func (ep *Endpoint) OnReadable(fn func()) {
+	if !ep.ctx.ext.moved {
+		ep.ctx.ready().readable.Store(callback(fn))
+	}
+}
+
+// OnWritable registers fn to be called whenever Send or a choice on ep
+// may have become able to progress: the peer has drained the send queue,
+// closed, or the session was aborted. On a network endpoint, the send
+// queue is drained by the writer goroutine. See OnReadable.
+func (ep *Endpoint) OnWritable(fn func()) {
+	if !ep.ctx.ext.moved {
+		ep.ctx.ready().writable.Store(callback(fn))
+	}
+}
+
+// ready returns the readiness of ctx's side of the session.
+func (ctx *sessionContext) ready() *readiness {
+	return &ctx.state.ready[ctx.side()]
+}
+
+// peerReady returns the readiness of the peer's side of the session; on a
+// network endpoint, it has no callbacks.
+func (ctx *sessionContext) peerReady() *readiness {
+	return &ctx.state.ready[ctx.side()^1]
+}
+
+func callback(fn func()) *func() {
+	if fn == nil {
+		return nil
+	}
+	return &fn
+}
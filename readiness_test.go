@@ -0,0 +1,118 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"errors"
+	"testing"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+func TestOnReadable(t *testing.T) {
+	epA, epB := sess.New()
+	readable, writable := 0, 0
+	epB.OnReadable(func() { readable++ })
+	epB.OnWritable(func() { writable++ })
+
+	_, susp := sess.Step(sess.ExprRecvBind(func(n int) kont.Expr[int] { return kont.ExprReturn(n) }))
+	if _, _, err := sess.Advance(epB, susp); err != iox.ErrWouldBlock {
+		t.Fatalf("got %v, want ErrWouldBlock", err)
+	}
+	if _, _, err := sess.Advance(epA, stepSusp(sess.ExprSendThen(5, kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatal(err)
+	}
+	if readable != 1 || writable != 0 {
+		t.Fatalf("got readable=%d writable=%d after peer Send, want 1/0", readable, writable)
+	}
+	n, _, err := sess.Advance(epB, susp)
+	if err != nil || n != 5 {
+		t.Fatalf("got %d, %v, want 5", n, err)
+	}
+}
+
+func TestOnWritable(t *testing.T) {
+	epA, epB := sess.New()
+	writable := 0
+	epA.OnWritable(func() { writable++ })
+
+	// Fill A's send queue until it would block.
+	sends := 0
+	for {
+		_, susp := sess.Step(sess.ExprSendThen(sends, kont.ExprReturn(struct{}{})))
+		if _, _, err := sess.Advance(epA, susp); err != nil {
+			susp.Discard()
+			break
+		}
+		sends++
+	}
+	if writable != 0 {
+		t.Fatalf("writable fired %d times while filling", writable)
+	}
+	if _, _, err := sess.Advance(epB, stepSusp(sess.ExprRecvBind(func(n int) kont.Expr[int] { return kont.ExprReturn(n) }))); err != nil {
+		t.Fatal(err)
+	}
+	if writable != 1 {
+		t.Fatalf("got writable=%d after peer Recv, want 1", writable)
+	}
+}
+
+func TestReadinessOnCloseAndAbort(t *testing.T) {
+	epA, epB := sess.New()
+	readable, writable := 0, 0
+	epB.OnReadable(func() { readable++ })
+	epB.OnWritable(func() { writable++ })
+
+	if _, _, err := sess.Advance(epA, stepSusp(sess.ExprCloseDone(struct{}{}))); err != nil {
+		t.Fatal(err)
+	}
+	if readable != 1 || writable != 1 {
+		t.Fatalf("got readable=%d writable=%d after peer Close, want 1/1", readable, writable)
+	}
+	epB.Abort(errors.New("stop"))
+	if readable != 2 || writable != 2 {
+		t.Fatalf("got readable=%d writable=%d after Abort, want 2/2", readable, writable)
+	}
+
+	epB.OnReadable(nil)
+	epB.Abort(nil)
+	if readable != 2 {
+		t.Fatal("removed callback fired")
+	}
+}
+
+func TestConnOnReadable(t *testing.T) {
+	skipRace(t)
+	epA, epB := pipeEndpoints()
+	ready := make(chan struct{}, 1)
+	epB.OnReadable(func() {
+		select {
+		case ready <- struct{}{}:
+		default:
+		}
+	})
+
+	_, susp := sess.Step(sess.ExprRecvBind(func(s string) kont.Expr[string] { return sess.ExprCloseDone(s) }))
+	go execExpr(epA, sess.ExprSendThen("hello", sess.ExprCloseDone(struct{}{})))
+	for {
+		v, next, err := sess.Advance(epB, susp)
+		if err == iox.ErrWouldBlock {
+			<-ready
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next == nil {
+			if v != "hello" {
+				t.Fatalf("got %q, want hello", v)
+			}
+			return
+		}
+		susp = next
+	}
+}
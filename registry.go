@@ -0,0 +1,138 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// TypeID is a stable identifier for a payload type, agreed on by both
+// ends of a connection independently of Go type names.
+type TypeID uint32
+
+// Registry maps payload types to stable TypeIDs. Its Codec tags each
+// payload with the ID of its type, so that a receiver detects a payload
+// of another type than the one it asked for before decoding it.
+// A Registry is safe for concurrent use.
+type Registry struct {
+	mu     sync.RWMutex
+	byType map[reflect.Type]TypeID
+	byID   map[TypeID]reflect.Type
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		byType: make(map[reflect.Type]TypeID),
+		byID:   make(map[TypeID]reflect.Type),
+	}
+}
+
+// Register assigns id to T in r.
+// Panics if T or id is already registered.
+func Register[T any](r *Registry, id TypeID) {
+	t := reflect.TypeFor[T]()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.byType[t]; ok {
+		panic("sess: " + t.String() + " already registered as type ID " + strconv.FormatUint(uint64(old), 10))
+	}
+	if old, ok := r.byID[id]; ok {
+		panic("sess: type ID " + strconv.FormatUint(uint64(id), 10) + " already registered for " + old.String())
+	}
+	r.byType[t] = id
+	r.byID[id] = t
+}
+
+// ID returns the TypeID registered for t.
+func (r *Registry) ID(t reflect.Type) (TypeID, bool) {
+	r.mu.RLock()
+	id, ok := r.byType[t]
+	r.mu.RUnlock()
+	return id, ok
+}
+
+// Type returns the type registered under id.
+func (r *Registry) Type(id TypeID) (reflect.Type, bool) {
+	r.mu.RLock()
+	t, ok := r.byID[id]
+	r.mu.RUnlock()
+	return t, ok
+}
+
+// Codec returns a Codec that prefixes each payload encoded by inner with
+// the 4-byte big-endian TypeID of its type. Encoding an unregistered type
+// fails; decoding a payload whose ID is not the receiver's type fails
+// with a *TypeIDError.
+//
+// On a network endpoint both sides key the ID on the declared type: the T
+// of Send[T] and Recv[T]. A payload sent as an interface type is passed to
+// inner as a pointer to the interface, so that inner can encode its
+// dynamic type, as encoding/gob does for registered types.
+func (r *Registry) Codec(inner Codec) Codec {
+	return registryCodec{reg: r, inner: inner}
+}
+
+type registryCodec struct {
+	reg   *Registry
+	inner Codec
+}
+
+// Encode implements Codec, keying the ID on the dynamic type of v.
+func (c registryCodec) Encode(v any) ([]byte, error) {
+	return c.encodeAs(reflect.TypeOf(v), v)
+}
+
+// encodeAs implements typedEncoder, keying the ID on t.
+func (c registryCodec) encodeAs(t reflect.Type, v any) ([]byte, error) {
+	id, ok := c.reg.ID(t)
+	if !ok {
+		return nil, errUnregistered(t)
+	}
+	if t != nil && t.Kind() == reflect.Interface {
+		p := reflect.New(t)
+		if v != nil {
+			p.Elem().Set(reflect.ValueOf(v))
+		}
+		v = p.Interface()
+	}
+	data, err := c.inner.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(out, uint32(id))
+	return append(out, data...), nil
+}
+
+// Decode implements Codec.
+func (c registryCodec) Decode(data []byte, ptr any) error {
+	if len(data) < 4 {
+		return errors.New("sess: payload too short for type ID")
+	}
+	expected := reflect.TypeOf(ptr).Elem()
+	want, ok := c.reg.ID(expected)
+	if !ok {
+		return errUnregistered(expected)
+	}
+	id := TypeID(binary.BigEndian.Uint32(data))
+	if id != want {
+		actual, _ := c.reg.Type(id)
+		return &TypeIDError{ID: id, Expected: expected, Actual: actual}
+	}
+	return c.inner.Decode(data[4:], ptr)
+}
+
+func errUnregistered(t reflect.Type) error {
+	name := "<nil>"
+	if t != nil {
+		name = t.String()
+	}
+	return errors.New("sess: type " + name + " is not registered")
+}
@@ -0,0 +1,281 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+
+	"code.hybscloud.com/kont"
+)
+
+// TranscriptEntry is an operation completed on one endpoint of a
+// recorded session.
+type TranscriptEntry struct {
+	Side    uint8  // 0 for the first endpoint of a pair, 1 for its peer; see TraceEvent
+	Step    int    // number of operations completed on the endpoint before this one
+	Op      OpKind // kind of the operation
+	Type    string // payload type, e.g. "int"; empty for choices and Close
+	Label   Label  // label chosen or offered; zero for other operations
+	Payload []byte // value sent or received, encoded by the Recorder's codec
+}
+
+// Transcript is the ordered record of the operations of one session.
+// Entries of both endpoints are interleaved in the order they completed.
+type Transcript struct {
+	Serial  Serial
+	Entries []TranscriptEntry
+}
+
+// WriteTo writes t to w as JSON, for ReadTranscript.
+func (t *Transcript) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// ReadTranscript reads a transcript written by Transcript.WriteTo.
+func ReadTranscript(r io.Reader) (*Transcript, error) {
+	t := &Transcript{}
+	if err := json.NewDecoder(r).Decode(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// side returns the entries of one endpoint, in step order.
+func (t *Transcript) side(side uint8) []TranscriptEntry {
+	var entries []TranscriptEntry
+	for _, e := range t.Entries {
+		if e.Side == side {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Recorder records a transcript of every session whose endpoints it is
+// attached to, with Endpoint.SetRecorder or WithRecorder. Payloads are
+// encoded with its codec. Timed operations that time out are not
+// recorded. A Recorder is safe for concurrent use.
+type Recorder struct {
+	codec    Codec
+	mu       sync.Mutex
+	sessions map[Serial]*Transcript
+	order    []Serial
+}
+
+// NewRecorder returns a Recorder encoding payloads with codec, or with
+// GobCodec if codec is nil.
+func NewRecorder(codec Codec) *Recorder {
+	if codec == nil {
+		codec = GobCodec{}
+	}
+	return &Recorder{codec: codec, sessions: make(map[Serial]*Transcript)}
+}
+
+// SetRecorder makes r record the operations of ep; nil stops recording.
+// It must not be called while an operation on ep is pending.
+func (ep *Endpoint) SetRecorder(r *Recorder) {
+	ep.ctx.extend().recorder = r
+}
+
+// WithRecorder records both endpoints of the session with r.
+func WithRecorder(r *Recorder) Option {
+	return func(o *options) { o.recorder = r }
+}
+
+// Transcript returns a copy of the transcript of session serial.
+func (r *Recorder) Transcript(serial Serial) (*Transcript, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.sessions[serial]
+	if !ok {
+		return nil, false
+	}
+	return &Transcript{Serial: t.Serial, Entries: slices.Clone(t.Entries)}, true
+}
+
+// Transcripts returns copies of every transcript, in the order the
+// sessions were first recorded.
+func (r *Recorder) Transcripts() []*Transcript {
+	r.mu.Lock()
+	serials := slices.Clone(r.order)
+	r.mu.Unlock()
+	ts := make([]*Transcript, 0, len(serials))
+	for _, s := range serials {
+		t, _ := r.Transcript(s)
+		ts = append(ts, t)
+	}
+	return ts
+}
+
+// payloadOp is implemented by operations carrying a payload; payload
+// returns it given the operation's result v.
+type payloadOp interface {
+	payload(v kont.Resumed) any
+}
+
+// record appends sop, completed on ctx with result v, to its transcript.
+func (r *Recorder) record(ctx *sessionContext, sop sessionDispatcher, v kont.Resumed) {
+	if t, ok := sop.(timedOp); ok && t.timedOut(v) {
+		return
+	}
+	e := entryOf(ctx, sop)
+	switch e.Op {
+	case OpSend, OpRecv:
+		if p, ok := sop.(payloadOp); ok {
+			// A payload the codec cannot encode is recorded as empty and
+			// shows up as a divergence on replay.
+			e.Payload, _ = r.codec.Encode(p.payload(v))
+		}
+	case OpOffer:
+		e.Label = offeredLabel(v)
+	}
+	r.mu.Lock()
+	t, ok := r.sessions[ctx.serial]
+	if !ok {
+		t = &Transcript{Serial: ctx.serial}
+		r.sessions[ctx.serial] = t
+		r.order = append(r.order, ctx.serial)
+	}
+	t.Entries = append(t.Entries, e)
+	r.mu.Unlock()
+}
+
+// entryOf describes sop, about to be performed on ctx, without payload.
+func entryOf(ctx *sessionContext, sop sessionDispatcher) TranscriptEntry {
+	kind, typ := sop.opInfo()
+	e := TranscriptEntry{Side: ctx.side(), Step: ctx.step, Op: kind}
+	if typ != nil {
+		e.Type = typ.String()
+	}
+	if isSelect(kind) {
+		e.Label = opLabel(sop)
+	}
+	return e
+}
+
+func isSelect(kind OpKind) bool {
+	return kind == OpSelectL || kind == OpSelectR || kind == OpSelect
+}
+
+// NewReplayEndpoint returns an endpoint that replays side of the session
+// recorded in t against the recorded peer, decoding payloads with codec
+// (GobCodec if nil), which must be the codec t was recorded with.
+//
+// A protocol run on the endpoint, with Exec, Advance or any other runner,
+// receives the values and choices the peer sent in the recording, and
+// never blocks. Each operation is checked against the transcript: an
+// operation of another kind, payload type or label, a sent value whose
+// encoding differs, or an operation past the end of the transcript fails
+// with a *DivergenceError at that step. Codecs that encode equal values
+// differently, such as GobCodec with maps, report false divergences.
+func NewReplayEndpoint(t *Transcript, side uint8, codec Codec) *Endpoint {
+	if codec == nil {
+		codec = GobCodec{}
+	}
+	a, b := newPair(t.Serial, channelCapacity, channelCapacity)
+	ep := a
+	if side == 1 {
+		ep = b
+	}
+	ep.ctx.extend().codec = codec
+	ep.ctx.extend().replay = &replayer{entries: t.side(side)}
+	return ep
+}
+
+// replayer plays the recorded peer of a replay endpoint: before each
+// operation it supplies what the peer sent, and after it consumes what
+// the endpoint sent, checking both against the transcript.
+type replayer struct {
+	entries []TranscriptEntry
+	pos     int
+}
+
+// before checks sop against the next entry and queues the payload or
+// label it receives.
+func (r *replayer) before(ctx *sessionContext, sop sessionDispatcher) error {
+	actual := entryOf(ctx, sop)
+	if r.pos >= len(r.entries) {
+		return r.diverged(ctx, TranscriptEntry{Side: actual.Side, Step: ctx.step}, actual)
+	}
+	e := r.entries[r.pos]
+	if e.Op != actual.Op || e.Type != actual.Type || (isSelect(e.Op) && e.Label != actual.Label) {
+		return r.diverged(ctx, e, actual)
+	}
+	switch e.Op {
+	case OpRecv:
+		var p any = e.Payload
+		ctx.recvQ.Enqueue(&p)
+	case OpOffer:
+		l := e.Label
+		ctx.awaitQ.Enqueue(&l)
+	}
+	return nil
+}
+
+// after consumes what sop sent and checks it against the entry.
+func (r *replayer) after(ctx *sessionContext, sop sessionDispatcher) error {
+	e := r.entries[r.pos]
+	r.pos++
+	switch e.Op {
+	case OpSend:
+		v, _ := ctx.sendQ.Dequeue()
+		if data := v.([]byte); !bytes.Equal(data, e.Payload) {
+			actual := entryOf(ctx, sop)
+			actual.Payload = data
+			return r.diverged(ctx, e, actual)
+		}
+	case OpSelectL, OpSelectR, OpSelect:
+		ctx.signalQ.Dequeue()
+	}
+	return nil
+}
+
+func (r *replayer) diverged(ctx *sessionContext, expected, actual TranscriptEntry) error {
+	return &DivergenceError{Serial: ctx.serial, Step: ctx.step, Expected: expected, Actual: actual}
+}
+
+// DivergenceError reports a replayed operation that does not match the
+// transcript. Expected is the recorded entry, with Op OpNone if the
+// transcript has ended; Actual describes the operation performed.
+type DivergenceError struct {
+	Serial   Serial
+	Step     int
+	Expected TranscriptEntry
+	Actual   TranscriptEntry
+}
+
+// Error implements error.
+func (e *DivergenceError) Error() string {
+	msg := "sess: replay of session " + strconv.FormatUint(uint64(e.Serial), 10) +
+		" diverged at step " + strconv.Itoa(e.Step) + ": "
+	switch {
+	case e.Expected.Op == OpNone:
+		return msg + "transcript ended, protocol performed " + describeEntry(e.Actual)
+	case e.Expected.Op == OpSend && e.Actual.Op == OpSend && e.Expected.Type == e.Actual.Type:
+		return msg + "protocol sent a different " + e.Actual.Type + " than recorded"
+	}
+	return msg + "transcript has " + describeEntry(e.Expected) + ", protocol performed " + describeEntry(e.Actual)
+}
+
+func describeEntry(e TranscriptEntry) string {
+	s := e.Op.String()
+	switch {
+	case e.Type != "":
+		s += " " + e.Type
+	case e.Op == OpSelect:
+		s += " " + strconv.FormatUint(uint64(e.Label), 10)
+	}
+	return s
+}
@@ -0,0 +1,199 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+// advancePair runs two protocols on epA and epB on the calling goroutine,
+// alternating between them while either can progress.
+func advancePair[A, B any](t *testing.T, epA, epB *sess.Endpoint, a kont.Expr[A], b kont.Expr[B]) (A, B) {
+	t.Helper()
+	ra, sa := sess.Step(a)
+	rb, sb := sess.Step(b)
+	for sa != nil || sb != nil {
+		progress := false
+		if sa != nil {
+			r, next, err := sess.Advance(epA, sa)
+			if err != nil && err != iox.ErrWouldBlock {
+				t.Fatal(err)
+			}
+			if err == nil {
+				ra, sa, progress = r, next, true
+			}
+		}
+		if sb != nil {
+			r, next, err := sess.Advance(epB, sb)
+			if err != nil && err != iox.ErrWouldBlock {
+				t.Fatal(err)
+			}
+			if err == nil {
+				rb, sb, progress = r, next, true
+			}
+		}
+		if !progress {
+			t.Fatal("deadlock")
+		}
+	}
+	return ra, rb
+}
+
+// lookupClient asks for key and receives its length times ten, or -1.
+func lookupClient(key string) kont.Expr[int] {
+	return sess.ExprSendThen(key, sess.ExprOfferBranch(
+		func() kont.Expr[int] {
+			return sess.ExprRecvBind(func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) })
+		},
+		func() kont.Expr[int] { return sess.ExprCloseDone(-1) },
+	))
+}
+
+func lookupServer() kont.Expr[string] {
+	return sess.ExprRecvBind(func(key string) kont.Expr[string] {
+		return sess.ExprSelectLThen(sess.ExprSendThen(len(key)*10, sess.ExprCloseDone(key)))
+	})
+}
+
+func recordLookup(t *testing.T) *sess.Transcript {
+	t.Helper()
+	r := sess.NewRecorder(nil)
+	epA, epB := sess.NewWithOptions(sess.WithRecorder(r))
+	if got, _ := advancePair(t, epA, epB, lookupClient("abc"), lookupServer()); got != 30 {
+		t.Fatalf("client got %d, want 30", got)
+	}
+	tr, ok := r.Transcript(epA.Serial())
+	if !ok {
+		t.Fatal("session not recorded")
+	}
+	return tr
+}
+
+func TestRecordTranscript(t *testing.T) {
+	tr := recordLookup(t)
+	var ops [2][]sess.OpKind
+	for _, e := range tr.Entries {
+		if e.Step != len(ops[e.Side]) {
+			t.Fatalf("entry %+v out of step order", e)
+		}
+		ops[e.Side] = append(ops[e.Side], e.Op)
+	}
+	wantA := []sess.OpKind{sess.OpSend, sess.OpOffer, sess.OpRecv, sess.OpClose}
+	wantB := []sess.OpKind{sess.OpRecv, sess.OpSelectL, sess.OpSend, sess.OpClose}
+	if !reflect.DeepEqual(ops[0], wantA) || !reflect.DeepEqual(ops[1], wantB) {
+		t.Fatalf("recorded %v and %v", ops[0], ops[1])
+	}
+	if tr.Entries[0].Type != "string" || len(tr.Entries[0].Payload) == 0 {
+		t.Fatalf("first entry %+v", tr.Entries[0])
+	}
+
+	var buf bytes.Buffer
+	if _, err := tr.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := sess.ReadTranscript(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, tr) {
+		t.Fatalf("read back %+v, want %+v", read, tr)
+	}
+}
+
+func TestReplayBothSides(t *testing.T) {
+	tr := recordLookup(t)
+	client := sess.NewReplayEndpoint(tr, 0, nil)
+	if got := execExpr(client, lookupClient("abc")); got != 30 {
+		t.Fatalf("replayed client got %d, want 30", got)
+	}
+	server := sess.NewReplayEndpoint(tr, 1, nil)
+	if got := execExpr(server, lookupServer()); got != "abc" {
+		t.Fatalf("replayed server got %q, want abc", got)
+	}
+	if client.Serial() != tr.Serial {
+		t.Fatalf("replay serial %d, want %d", client.Serial(), tr.Serial)
+	}
+}
+
+// replayErr replays protocol as side of tr and returns the first error.
+func replayErr[R any](tr *sess.Transcript, side uint8, protocol kont.Expr[R]) error {
+	ep := sess.NewReplayEndpoint(tr, side, nil)
+	_, susp := sess.Step(protocol)
+	for susp != nil {
+		var err error
+		if _, susp, err = sess.Advance(ep, susp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestReplayDivergence(t *testing.T) {
+	tr := recordLookup(t)
+	var de *sess.DivergenceError
+
+	err := replayErr(tr, 0, lookupClient("abcd"))
+	if !errors.As(err, &de) || de.Step != 0 || de.Expected.Op != sess.OpSend {
+		t.Fatalf("different payload got %v", err)
+	}
+	if want := "diverged at step 0: protocol sent a different string than recorded"; !bytes.Contains([]byte(err.Error()), []byte(want)) {
+		t.Fatalf("message %q", err)
+	}
+
+	err = replayErr(tr, 1, sess.ExprRecvBind(func(string) kont.Expr[struct{}] {
+		return sess.ExprSelectRThen(sess.ExprCloseDone(struct{}{}))
+	}))
+	if !errors.As(err, &de) || de.Step != 1 || de.Expected.Op != sess.OpSelectL || de.Actual.Op != sess.OpSelectR {
+		t.Fatalf("different choice got %v", err)
+	}
+
+	err = replayErr(tr, 1, sess.ExprRecvBind(func(int) kont.Expr[int] { return sess.ExprCloseDone(0) }))
+	if !errors.As(err, &de) || de.Expected.Type != "string" || de.Actual.Type != "int" {
+		t.Fatalf("different payload type got %v", err)
+	}
+
+	short := &sess.Transcript{Serial: tr.Serial, Entries: tr.Entries[:1]}
+	err = replayErr(short, 0, lookupClient("abc"))
+	if !errors.As(err, &de) || de.Step != 1 || de.Expected.Op != sess.OpNone || de.Actual.Op != sess.OpOffer {
+		t.Fatalf("past the end got %v", err)
+	}
+	if want := "transcript ended, protocol performed Offer"; !bytes.Contains([]byte(err.Error()), []byte(want)) {
+		t.Fatalf("message %q", err)
+	}
+}
+
+func TestRecorderTranscripts(t *testing.T) {
+	r := sess.NewRecorder(sess.JSONCodec{})
+	var serials []sess.Serial
+	for range 3 {
+		epA, epB := sess.New()
+		epA.SetRecorder(r)
+		epB.SetRecorder(r)
+		advancePair(t, epA, epB, lookupClient("k"), lookupServer())
+		serials = append(serials, epA.Serial())
+	}
+	ts := r.Transcripts()
+	if len(ts) != 3 {
+		t.Fatalf("%d transcripts, want 3", len(ts))
+	}
+	for i, tr := range ts {
+		if tr.Serial != serials[i] || len(tr.Entries) != 8 {
+			t.Fatalf("transcript %d: serial %d with %d entries", i, tr.Serial, len(tr.Entries))
+		}
+	}
+	if string(ts[0].Entries[0].Payload) != `"k"` {
+		t.Fatalf("JSON payload %q", ts[0].Entries[0].Payload)
+	}
+	if got := execExpr(sess.NewReplayEndpoint(ts[1], 0, sess.JSONCodec{}), lookupClient("k")); got != 10 {
+		t.Fatalf("replayed with JSONCodec got %d", got)
+	}
+}
@@ -5,27 +5,75 @@
 package sess
 
 import (
+	"context"
+
 	"code.hybscloud.com/iox"
 	"code.hybscloud.com/kont"
 )
 
 // Run creates a session pair, runs both Cont-world protocols, and returns
 // both results. Interleaves execution of both sides on the calling
-// goroutine using adaptive backoff (iox.Backoff) when neither side
-// can make progress. Does not spawn goroutines or create channels.
+// goroutine. Does not spawn goroutines or create channels.
+// Panics with a *DeadlockError if neither side can make progress, or with
+// the error value if an operation fails terminally.
 func Run[A, B any](a kont.Eff[A], b kont.Eff[B]) (A, B) {
 	return RunExpr(Reify(a), Reify(b))
 }
 
 // RunExpr creates a session pair, runs both Expr-world protocols, and
 // returns both results. Interleaves execution of both sides on the
-// calling goroutine using adaptive backoff (iox.Backoff) when neither
-// side can make progress. Does not spawn goroutines or create channels.
+// calling goroutine. Does not spawn goroutines or create channels.
+// Panics with a *DeadlockError if neither side can make progress, or with
+// the error value if an operation fails terminally.
 func RunExpr[A, B any](a kont.Expr[A], b kont.Expr[B]) (A, B) {
 	epA, epB := New()
+	return runExpr(epA, epB, a, b)
+}
+
+// TryRun is like Run but returns a deadlock or terminal operation
+// failure as an error instead of panicking.
+func TryRun[A, B any](a kont.Eff[A], b kont.Eff[B]) (A, B, error) {
+	return TryRunExpr(Reify(a), Reify(b))
+}
+
+// TryRunExpr is like RunExpr but returns a deadlock or terminal operation
+// failure as an error instead of panicking.
+func TryRunExpr[A, B any](a kont.Expr[A], b kont.Expr[B]) (A, B, error) {
+	epA, epB := New()
+	return tryRunExpr(context.Background(), epA, epB, a, b)
+}
+
+// RunWithOptions is Run on a pair created by NewWithOptions(opts...).
+// With WithClock, both sides wait on that clock.
+func RunWithOptions[A, B any](a kont.Eff[A], b kont.Eff[B], opts ...Option) (A, B) {
+	epA, epB := NewWithOptions(opts...)
+	return runExpr(epA, epB, Reify(a), Reify(b))
+}
+
+// TryRunWithOptions is TryRun on a pair created by NewWithOptions(opts...).
+func TryRunWithOptions[A, B any](a kont.Eff[A], b kont.Eff[B], opts ...Option) (A, B, error) {
+	epA, epB := NewWithOptions(opts...)
+	return tryRunExpr(context.Background(), epA, epB, Reify(a), Reify(b))
+}
+
+// runExpr interleaves a on epA and b on epB until both complete,
+// panicking on failure.
+func runExpr[A, B any](epA, epB *Endpoint, a kont.Expr[A], b kont.Expr[B]) (A, B) {
+	resultA, resultB, err := tryRunExpr(context.Background(), epA, epB, a, b)
+	if err != nil {
+		panic(err)
+	}
+	return resultA, resultB
+}
+
+// tryRunExpr interleaves a on epA and b on epB until both complete,
+// observing ctx between rounds. The pair is private to the run, so a
+// round in which neither side makes progress leaves both queues unchanged
+// and repeats forever: it is reported as a *DeadlockError.
+func tryRunExpr[A, B any](ctx context.Context, epA, epB *Endpoint, a kont.Expr[A], b kont.Expr[B]) (A, B, error) {
+	done := ctx.Done()
 	resultA, suspA := Step[A](a)
 	resultB, suspB := Step[B](b)
-	var bo iox.Backoff
 
 	var sopA sessionDispatcher
 	if suspA != nil {
@@ -36,33 +84,69 @@ func RunExpr[A, B any](a kont.Expr[A], b kont.Expr[B]) (A, B) {
 		sopB = suspB.Op().(sessionDispatcher)
 	}
 
+	var err error
+	bo := backoff{clock: epA.ctx.ext.clock}
 	for suspA != nil || suspB != nil {
+		if isDone(done) {
+			err = ctx.Err()
+			epA.ctx.abort(err)
+			break
+		}
 		progress := false
 		if suspA != nil {
-			v, err := sopA.DispatchSession(&epA.ctx)
+			var v kont.Resumed
+			v, err = epA.ctx.dispatch(sopA)
 			if err == nil {
 				resultA, suspA = suspA.Resume(v)
 				if suspA != nil {
 					sopA = suspA.Op().(sessionDispatcher)
 				}
 				progress = true
+			} else if err != iox.ErrWouldBlock {
+				break
 			}
 		}
 		if suspB != nil {
-			v, err := sopB.DispatchSession(&epB.ctx)
+			var v kont.Resumed
+			v, err = epB.ctx.dispatch(sopB)
 			if err == nil {
 				resultB, suspB = suspB.Resume(v)
 				if suspB != nil {
 					sopB = suspB.Op().(sessionDispatcher)
 				}
 				progress = true
+			} else if err != iox.ErrWouldBlock {
+				break
 			}
 		}
-		if !progress {
+		if !progress && (isTimed(sopA) || isTimed(sopB)) {
+			// A timed operation completes once its deadline passes.
 			bo.Wait()
-		} else {
-			bo.Reset()
+			continue
 		}
+		if !progress {
+			err = &DeadlockError{
+				Serial: epA.Serial(),
+				A:      pendingOf(&epA.ctx, suspA),
+				B:      pendingOf(&epB.ctx, suspB),
+			}
+			break
+		}
+		err = nil
+	}
+	if err != nil {
+		discard(suspA)
+		discard(suspB)
+		var zeroA A
+		var zeroB B
+		return zeroA, zeroB, err
+	}
+	return resultA, resultB, nil
+}
+
+// discard abandons susp if it is still pending.
+func discard[R any](susp *kont.Suspension[R]) {
+	if susp != nil {
+		susp.Discard()
 	}
-	return resultA, resultB
 }
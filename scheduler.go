@@ -0,0 +1,314 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+)
+
+// Scheduler multiplexes many sessions on the calling goroutine in the
+// style of a proactor: each spawned protocol is advanced one operation at
+// a time, sessions that would block are parked, and parked sessions are
+// woken when their peer makes progress.
+//
+// Poll runs one round over the ready sessions in round-robin order.
+// A session that gets iox.ErrWouldBlock is parked until a session sharing
+// its endpoint pair performs an operation, completes, or fails. A session
+// whose peer is not scheduled on the same Scheduler — a network endpoint,
+// or a peer running on another goroutine — is retried once its endpoint's
+// readiness callbacks (see Endpoint.OnReadable) have fired, and the
+// channel returned by Wake is signaled so that an idle event loop can
+// sleep until then. A session blocked in a timed operation (see
+// RecvWithin) is retried in every round until its deadline passes. The
+// Scheduler owns the readiness callbacks of spawned endpoints.
+//
+// NewSimScheduler creates a deterministic Scheduler running in simulated
+// time, for tests.
+//
+// A Scheduler is not safe for concurrent use; completion callbacks run on
+// the goroutine calling Poll and may Spawn further sessions.
+type Scheduler struct {
+	ready  []schedTask // advanced in the next round, in order
+	spare  []schedTask // recycled round buffer
+	polled []schedTask // parked sessions waiting on a peer scheduled elsewhere
+	parked map[*sessionState][]schedTask
+	owners map[*sessionState]int // scheduled sessions per endpoint pair
+	live   int
+	timed  int // sessions blocked in a timed operation after the last round
+	wakeC  chan struct{}
+	clock  *VirtualClock // nil outside simulation
+	rng    *rand.Rand    // shuffles each round; nil for round-robin
+}
+
+// schedTask is a spawned session of any result type.
+type schedTask interface {
+	// advance performs at most one operation. It reports whether the
+	// protocol has completed; iox.ErrWouldBlock means no progress.
+	advance() (bool, error)
+	// finish invokes the completion callback; err is nil on success.
+	finish(err error)
+	context() *sessionContext
+	// notified reports and clears whether a readiness callback fired.
+	notified() bool
+	// timed reports whether the pending operation has a deadline.
+	timed() bool
+}
+
+// NewScheduler returns an empty Scheduler.
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		parked: make(map[*sessionState][]schedTask),
+		owners: make(map[*sessionState]int),
+		wakeC:  make(chan struct{}, 1),
+	}
+}
+
+// NewSimScheduler returns an empty Scheduler that runs its sessions in
+// simulated time. Spawned endpoints read and wait on clock, and the order
+// in which each round advances the ready sessions is a permutation drawn
+// from seed. When every session is blocked and some wait in a timed
+// operation, RunUntilIdle advances clock straight to the earliest
+// deadline instead of sleeping. Sessions whose peers run on the same
+// Scheduler therefore interleave and time out identically on every run
+// with the same seed, which makes timeout-sensitive protocols testable
+// reproducibly; varying the seed explores other interleavings.
+func NewSimScheduler(clock *VirtualClock, seed uint64) *Scheduler {
+	s := NewScheduler()
+	s.clock = clock
+	s.rng = rand.New(rand.NewPCG(seed, seed))
+	return s
+}
+
+// Spawn schedules a Cont-world protocol on ep. onDone, if non-nil, is
+// called from Poll with the protocol's result, or with the zero value and
+// the error that failed the session. See SpawnExpr.
+func Spawn[R any](s *Scheduler, ep *Endpoint, protocol kont.Eff[R], onDone func(R, error)) {
+	SpawnExpr(s, ep, Reify(protocol), onDone)
+}
+
+// SpawnExpr schedules an Expr-world protocol on ep. The protocol runs to
+// its first effect immediately; its operations are performed by Poll.
+// An operation that fails with an error other than iox.ErrWouldBlock ends
+// the session: unless the peer has closed, the session is aborted so that
+// the peer observes ErrSessionAborted, and onDone receives the error.
+func SpawnExpr[R any](s *Scheduler, ep *Endpoint, protocol kont.Expr[R], onDone func(R, error)) {
+	t := &task[R]{ep: ep, onDone: onDone}
+	notify := func() {
+		t.signal.Store(true)
+		select {
+		case s.wakeC <- struct{}{}:
+		default:
+		}
+	}
+	ep.OnReadable(notify)
+	ep.OnWritable(notify)
+	if s.clock != nil {
+		ep.SetClock(s.clock)
+	}
+	t.result, t.susp = Step(protocol)
+	st := ep.ctx.state
+	s.owners[st]++
+	s.live++
+	s.ready = append(s.ready, t)
+	s.wake(st)
+}
+
+// Len returns the number of sessions that have not completed.
+func (s *Scheduler) Len() int {
+	return s.live
+}
+
+// Poll runs one round: every ready session, and every session notified by
+// a peer scheduled elsewhere, performs at most one operation: in spawn
+// order, or in a seeded random order on a simulation Scheduler. Completed
+// and failed sessions report to their callbacks. Poll returns the number of
+// sessions that performed an operation, completed or failed; zero means
+// that every session is blocked.
+func (s *Scheduler) Poll() int {
+	round := s.ready
+	waiting := s.polled[:0]
+	for _, t := range s.polled {
+		if t.notified() || t.timed() {
+			round = append(round, t)
+		} else {
+			waiting = append(waiting, t)
+		}
+	}
+	clear(s.polled[len(waiting):])
+	s.polled = waiting
+	s.ready = s.spare[:0]
+	s.timed = 0
+	if s.rng != nil {
+		s.rng.Shuffle(len(round), func(i, j int) { round[i], round[j] = round[j], round[i] })
+	}
+	progress := 0
+	for _, t := range round {
+		st := t.context().state
+		t.notified()
+		done, err := t.advance()
+		if err == iox.ErrWouldBlock {
+			s.park(t)
+			continue
+		}
+		progress++
+		switch {
+		case err != nil:
+			if err != ErrPeerClosed {
+				t.context().abort(err)
+			}
+			s.exit(t)
+			t.finish(err)
+		case done:
+			s.exit(t)
+			t.finish(nil)
+		default:
+			s.ready = append(s.ready, t)
+		}
+		s.wake(st)
+	}
+	clear(round)
+	s.spare = round[:0]
+	return progress
+}
+
+// RunUntilIdle polls until no session can make progress and returns the
+// number of sessions still pending. Sessions left pending wait on peers
+// scheduled elsewhere, or are deadlocked with each other. While a session
+// is blocked in a timed operation, RunUntilIdle backs off and keeps
+// polling until the operation completes or times out; a simulation
+// Scheduler advances its clock to the next deadline instead.
+func (s *Scheduler) RunUntilIdle() int {
+	var bo iox.Backoff
+	for {
+		if s.Poll() > 0 {
+			bo.Reset()
+			continue
+		}
+		if s.timed == 0 {
+			return s.live
+		}
+		if at, ok := s.nextDeadline(); ok && s.clock != nil {
+			s.clock.AdvanceTo(at)
+			continue
+		}
+		bo.Wait()
+	}
+}
+
+// Wake returns a channel that receives a value after a session waiting on
+// a peer scheduled elsewhere has been notified. An event loop can block
+// on it between calls to RunUntilIdle:
+//
+//	for s.RunUntilIdle() > 0 {
+//		<-s.Wake()
+//	}
+//
+// Sessions deadlocked with each other never signal Wake.
+func (s *Scheduler) Wake() <-chan struct{} {
+	return s.wakeC
+}
+
+// nextDeadline returns the earliest deadline of the sessions blocked in a
+// timed operation.
+func (s *Scheduler) nextDeadline() (time.Time, bool) {
+	var at time.Time
+	ok := false
+	for _, t := range s.polled {
+		ctx := t.context()
+		if !t.timed() || !ctx.ext.timer.armed || ctx.ext.timer.step != ctx.step {
+			continue
+		}
+		if !ok || ctx.ext.timer.at.Before(at) {
+			at, ok = ctx.ext.timer.at, true
+		}
+	}
+	return at, ok
+}
+
+// park sets aside a session that would block.
+func (s *Scheduler) park(t schedTask) {
+	ctx := t.context()
+	if t.timed() {
+		s.timed++
+		s.polled = append(s.polled, t)
+		return
+	}
+	if ctx.ext.wire != nil || s.owners[ctx.state] < 2 {
+		s.polled = append(s.polled, t)
+		return
+	}
+	s.parked[ctx.state] = append(s.parked[ctx.state], t)
+}
+
+// wake makes the sessions parked on st ready again.
+func (s *Scheduler) wake(st *sessionState) {
+	if ts, ok := s.parked[st]; ok {
+		s.ready = append(s.ready, ts...)
+		delete(s.parked, st)
+	}
+}
+
+// exit forgets a session that has completed or failed.
+func (s *Scheduler) exit(t schedTask) {
+	st := t.context().state
+	if s.owners[st]--; s.owners[st] == 0 {
+		delete(s.owners, st)
+	}
+	s.live--
+}
+
+// task is a spawned protocol with result type R.
+type task[R any] struct {
+	ep     *Endpoint
+	susp   *kont.Suspension[R]
+	result R
+	onDone func(R, error)
+	signal atomic.Bool
+}
+
+func (t *task[R]) advance() (bool, error) {
+	if t.susp == nil {
+		return true, nil
+	}
+	result, next, err := Advance(t.ep, t.susp)
+	if err != nil {
+		return false, err
+	}
+	t.result, t.susp = result, next
+	return next == nil, nil
+}
+
+func (t *task[R]) finish(err error) {
+	if err != nil {
+		discard(t.susp)
+		t.susp = nil
+		var zero R
+		t.result = zero
+	}
+	if t.onDone != nil {
+		t.onDone(t.result, err)
+	}
+}
+
+func (t *task[R]) context() *sessionContext {
+	return &t.ep.ctx
+}
+
+func (t *task[R]) notified() bool {
+	return t.signal.Swap(false)
+}
+
+func (t *task[R]) timed() bool {
+	if t.susp == nil {
+		return false
+	}
+	_, ok := t.susp.Op().(timedOp)
+	return ok
+}
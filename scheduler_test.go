@@ -0,0 +1,163 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"errors"
+	"testing"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+func TestSchedulerManySessions(t *testing.T) {
+	const sessions = 2000
+	const rounds = 5
+	s := sess.NewScheduler()
+	sums := make([]int, sessions)
+	completed := 0
+	for i := range sessions {
+		epA, epB := sess.New()
+		client := sess.ExprLoop(0, func(j int) kont.Expr[kont.Either[int, struct{}]] {
+			if j == rounds {
+				return sess.ExprSelectRThen(sess.ExprCloseDone(kont.Right[int, struct{}](struct{}{})))
+			}
+			return sess.ExprSelectLThen(sess.ExprSendThen(i, kont.ExprReturn(kont.Left[int, struct{}](j+1))))
+		})
+		server := sess.ExprLoop(0, func(sum int) kont.Expr[kont.Either[int, int]] {
+			return sess.ExprOfferBranch(
+				func() kont.Expr[kont.Either[int, int]] {
+					return sess.ExprRecvBind(func(n int) kont.Expr[kont.Either[int, int]] {
+						return kont.ExprReturn(kont.Left[int, int](sum + n))
+					})
+				},
+				func() kont.Expr[kont.Either[int, int]] {
+					return sess.ExprCloseDone(kont.Right[int](sum))
+				},
+			)
+		})
+		sess.SpawnExpr(s, epA, client, func(struct{}, error) { completed++ })
+		sess.SpawnExpr(s, epB, server, func(sum int, err error) {
+			if err != nil {
+				t.Errorf("session %d: %v", i, err)
+			}
+			sums[i] = sum
+			completed++
+		})
+	}
+	if got := s.Len(); got != 2*sessions {
+		t.Fatalf("Len got %d, want %d", got, 2*sessions)
+	}
+	if pending := s.RunUntilIdle(); pending != 0 {
+		t.Fatalf("RunUntilIdle left %d sessions pending", pending)
+	}
+	if completed != 2*sessions {
+		t.Fatalf("completed %d sessions, want %d", completed, 2*sessions)
+	}
+	for i, sum := range sums {
+		if sum != i*rounds {
+			t.Fatalf("session %d got %d, want %d", i, sum, i*rounds)
+		}
+	}
+}
+
+func TestSchedulerRoundRobin(t *testing.T) {
+	s := sess.NewScheduler()
+	var order []string
+	for _, name := range []string{"a", "b", "c"} {
+		epA, epB := sess.New()
+		sess.Spawn(s, epA, sess.SendThen(1, sess.SendThen(2, sess.CloseDone(name))), func(r string, err error) {
+			order = append(order, r)
+		})
+		sess.Spawn(s, epB, sess.RecvBind(func(int) kont.Eff[struct{}] {
+			return sess.RecvBind(func(int) kont.Eff[struct{}] { return sess.CloseDone(struct{}{}) })
+		}), nil)
+	}
+	// One operation per session per round: the senders finish in spawn order.
+	for range 2 {
+		if n := s.Poll(); n != 6 {
+			t.Fatalf("Poll performed %d operations, want 6", n)
+		}
+	}
+	if len(order) != 0 {
+		t.Fatalf("completed %v before their third operation", order)
+	}
+	s.RunUntilIdle()
+	if got := len(order); got != 3 || order[0] != "a" || order[1] != "b" || order[2] != "c" {
+		t.Fatalf("completion order %v, want [a b c]", order)
+	}
+}
+
+func TestSchedulerParkedUntilPeerSpawned(t *testing.T) {
+	s := sess.NewScheduler()
+	epA, epB := sess.New()
+	var got int
+	sess.Spawn(s, epB, sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) }), func(n int, err error) {
+		got = n
+	})
+	if pending := s.RunUntilIdle(); pending != 1 {
+		t.Fatalf("RunUntilIdle left %d pending, want 1", pending)
+	}
+	sess.Spawn(s, epA, sess.SendThen(7, sess.CloseDone(struct{}{})), nil)
+	if pending := s.RunUntilIdle(); pending != 0 || got != 7 {
+		t.Fatalf("got pending=%d result=%d, want 0/7", pending, got)
+	}
+}
+
+func TestSchedulerDeadlock(t *testing.T) {
+	s := sess.NewScheduler()
+	epA, epB := sess.New()
+	recv := sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })
+	sess.Spawn(s, epA, recv, nil)
+	sess.Spawn(s, epB, recv, nil)
+	if pending := s.RunUntilIdle(); pending != 2 {
+		t.Fatalf("RunUntilIdle left %d pending, want 2", pending)
+	}
+	if n := s.Poll(); n != 0 {
+		t.Fatalf("Poll performed %d operations on deadlocked sessions", n)
+	}
+}
+
+func TestSchedulerFailureAborts(t *testing.T) {
+	s := sess.NewScheduler()
+	epA, epB := sess.New()
+	var errA, errB error
+	sess.Spawn(s, epA, sess.SendThen("wrong type", sess.CloseDone(struct{}{})), func(_ struct{}, err error) {
+		errA = err
+	})
+	sess.Spawn(s, epB, sess.RecvBind(func(n int) kont.Eff[int] {
+		return sess.RecvBind(func(m int) kont.Eff[int] { return sess.CloseDone(n + m) })
+	}), func(_ int, err error) {
+		errB = err
+	})
+	if pending := s.RunUntilIdle(); pending != 0 {
+		t.Fatalf("RunUntilIdle left %d pending", pending)
+	}
+	var pv *sess.ProtocolViolation
+	if !errors.As(errB, &pv) {
+		t.Fatalf("receiver got %v, want *ProtocolViolation", errB)
+	}
+	if !errors.Is(errA, sess.ErrSessionAborted) {
+		t.Fatalf("sender got %v, want ErrSessionAborted", errA)
+	}
+}
+
+func TestSchedulerExternalPeer(t *testing.T) {
+	skipRace(t)
+	s := sess.NewScheduler()
+	epA, epB := sess.New()
+	go sess.Exec(epA, sess.SendThen(3, sess.SendThen(4, sess.CloseDone(struct{}{}))))
+
+	var got int
+	sess.Spawn(s, epB, sess.RecvBind(func(n int) kont.Eff[int] {
+		return sess.RecvBind(func(m int) kont.Eff[int] { return sess.CloseDone(n * m) })
+	}), func(n int, err error) { got = n })
+	for s.RunUntilIdle() > 0 {
+		<-s.Wake()
+	}
+	if got != 12 {
+		t.Fatalf("got %d, want 12", got)
+	}
+}
@@ -5,10 +5,10 @@
 package sess
 
 import (
-	"code.hybscloud.com/atomix"
+	"reflect"
+
 	"code.hybscloud.com/iox"
 	"code.hybscloud.com/kont"
-	"code.hybscloud.com/lfq"
 )
 
 // channelCapacity is the bounded capacity for session transport queues.
@@ -19,27 +19,155 @@ const channelCapacity = 4
 // sessionContext holds the lock-free transport for a single endpoint.
 // Each direction is a single-producer single-consumer bounded queue.
 type sessionContext struct {
-	sendQ    *lfq.SPSC[any]
-	recvQ    *lfq.SPSC[any]
-	signalQ  *lfq.SPSC[bool]
-	awaitQ   *lfq.SPSC[bool]
-	closed   *atomix.Uint32
+	sendQ    *queue[any]
+	recvQ    *queue[any]
+	signalQ  *queue[Label]
+	awaitQ   *queue[Label]
+	state    *sessionState
 	sendSlot any
+	serial   Serial
+	closeBit uint32 // this endpoint's close bit in state
+	step     int
+	ext      *sessionExt // never nil; &noExt unless an option is set
+}
+
+// sessionExt holds the optional state of an endpoint, kept out of line so
+// that a plain pair stays small (see TestNewAllocation). Endpoints without any share the
+// read-only noExt; extend gives an endpoint its own before a write.
+type sessionExt struct {
+	wire     *wireLink    // non-nil for network endpoints (see DialConn)
+	mon      *monitor     // nil unless monitored
+	moved    bool         // set once this handle is delegated away
+	clock    Clock        // nil for the system clock
+	timer    deadline     // of the pending timed operation
+	tracer   Tracer       // nil for the global tracer, if any
+	metrics  *Metrics     // nil unless counting
+	codec    Codec        // non-nil for network endpoints and sessions created WithCodec
+	recorder *Recorder    // nil unless recording
+	replay   *replayer    // non-nil for replay endpoints
+	owner    *ownership   // nil unless linearity checks are on
+	catches  []catchFrame // Catch operations in progress under AdvanceError
+	role     Role         // own role on a multiparty channel
+	peer     Role         // role at the other end of a multiparty channel
+}
+
+// noExt is the shared optional state of endpoints that set none.
+var noExt sessionExt
+
+// extend returns ctx's own optional state, allocating it on first use.
+func (ctx *sessionContext) extend() *sessionExt {
+	if ctx.ext == &noExt {
+		ctx.ext = &sessionExt{}
+	}
+	return ctx.ext
 }
 
 // sessionDispatcher is the structural interface for session operations.
 // DispatchSession is non-blocking: it returns iox.ErrWouldBlock at
 // the I/O boundary when the bounded queue cannot make progress.
+// opInfo reports the operation kind and payload type (nil if none).
 type sessionDispatcher interface {
 	DispatchSession(ctx *sessionContext) (kont.Resumed, error)
+	opInfo() (OpKind, reflect.Type)
+}
+
+// dispatch is the single entry point for performing sop on ctx.
+// It validates sop against the attached monitor, if any, checks the
+// shared session state, reports to the metrics, recorder and tracer, if
+// any, and counts completed steps. A handle delegated away fails with
+// ErrEndpointMoved. With linearity checks on, the operation holds the
+// endpoint's ownership token (see SetLinearityChecks). On a replay
+// endpoint, it plays the recorded peer around the operation. Errors other
+// than iox.ErrWouldBlock are terminal.
+//
+// The state is loaded before the transport is touched: a receive that
+// would block after the peer's close bit was observed can never succeed,
+// because everything the peer sent before closing is already visible.
+func (ctx *sessionContext) dispatch(sop sessionDispatcher) (kont.Resumed, error) {
+	if o := ctx.ext.owner; o != nil {
+		return ctx.dispatchOwned(o, sop)
+	}
+	return ctx.perform(sop)
+}
+
+// dispatchOwned performs sop holding the ownership token o.
+func (ctx *sessionContext) dispatchOwned(o *ownership, sop sessionDispatcher) (kont.Resumed, error) {
+	if err := o.acquire(); err != nil {
+		return nil, err
+	}
+	defer o.release()
+	return ctx.perform(sop)
+}
+
+// perform is dispatch without the linearity checks.
+func (ctx *sessionContext) perform(sop sessionDispatcher) (kont.Resumed, error) {
+	if ctx.ext.moved {
+		return nil, ErrEndpointMoved
+	}
+	if ctx.ext.mon != nil {
+		if err := ctx.ext.mon.check(sop, ctx.step, ctx.serial); err != nil {
+			return nil, err
+		}
+	}
+	st := ctx.state.bits.LoadAcquire()
+	if st != 0 {
+		if err := ctx.checkState(st, sop); err != nil {
+			return nil, err
+		}
+	}
+	if ctx.ext.wire != nil && ctx.ext.wire.session.Load() == 0 {
+		return nil, iox.ErrWouldBlock // waiting for the dialing side's hello
+	}
+	if ctx.ext.replay != nil {
+		if err := ctx.ext.replay.before(ctx, sop); err != nil {
+			return nil, err
+		}
+	}
+	v, err := sop.DispatchSession(ctx)
+	if err != nil {
+		if err == iox.ErrWouldBlock {
+			if st&ctx.peerCloseBit() != 0 {
+				return nil, ErrPeerClosed
+			}
+			if m := ctx.ext.metrics; m != nil {
+				m.blockedOn(sop)
+			}
+			if tr := ctx.tracing(); tr != nil {
+				ctx.trace(tr, sop, nil, true)
+			}
+		}
+		return nil, err
+	}
+	if ctx.ext.replay != nil {
+		if err := ctx.ext.replay.after(ctx, sop); err != nil {
+			return nil, err
+		}
+	}
+	if ctx.ext.mon != nil {
+		ctx.ext.mon.advance(sop, v)
+	}
+	if m := ctx.ext.metrics; m != nil {
+		m.completed(sop, v)
+	}
+	if r := ctx.ext.recorder; r != nil {
+		r.record(ctx, sop, v)
+	}
+	if tr := ctx.tracing(); tr != nil {
+		ctx.trace(tr, sop, v, false)
+	}
+	ctx.step++
+	ctx.peerReady().notify(sop)
+	return v, nil
 }
 
 // sessionHandler implements kont.Handler for session effects.
 // Waits on iox.ErrWouldBlock, converting non-blocking dispatch
 // into blocking evaluation for Exec/ExecExpr.
 // Value type: passed to evalFrames on the stack, avoiding heap allocation.
+// wait is non-nil for context-aware execution (ExecContext).
 type sessionHandler[R any] struct {
-	ctx *sessionContext
+	ctx  *sessionContext
+	wait *waitContext
 }
 
 // Dispatch implements kont.Handler via structural interface assertion.
@@ -49,17 +177,39 @@ func (h sessionHandler[R]) Dispatch(op kont.Operation) (kont.Resumed, bool) {
 	if !ok {
 		panic("sess: unhandled effect in sessionHandler")
 	}
+	if h.wait != nil {
+		v, ok := h.wait.dispatch(h.ctx, sop)
+		if !ok {
+			var zero R
+			return zero, false
+		}
+		return v, true
+	}
 	return dispatchWait(h.ctx, sop), true
 }
 
 // dispatchWait blocks until DispatchSession succeeds, backing off on
-// iox.ErrWouldBlock with iox.Backoff (I/O readiness waiting).
+// iox.ErrWouldBlock with iox.Backoff (I/O readiness waiting) on ctx's clock.
+// Any other error is terminal and panics with the error value.
 func dispatchWait(ctx *sessionContext, sop sessionDispatcher) kont.Resumed {
-	var bo iox.Backoff
+	v, err := waitDispatch(ctx, sop)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// waitDispatch is dispatchWait returning the terminal error.
+func waitDispatch(ctx *sessionContext, sop sessionDispatcher) (kont.Resumed, error) {
+	bo := backoff{clock: ctx.ext.clock, metrics: ctx.ext.metrics}
 	for {
-		v, err := sop.DispatchSession(ctx)
+		v, err := ctx.dispatch(sop)
 		if err == nil {
-			return v
+			bo.done()
+			return v, nil
+		}
+		if err != iox.ErrWouldBlock {
+			return nil, err
 		}
 		bo.Wait()
 	}
@@ -68,13 +218,12 @@ func dispatchWait(ctx *sessionContext, sop sessionDispatcher) kont.Resumed {
 // Endpoint represents one side of a session-typed channel pair.
 // Transport is backed by bounded lock-free SPSC queues from lfq.
 type Endpoint struct {
-	ctx    sessionContext
-	serial Serial
+	ctx sessionContext
 }
 
 // Serial returns the serial number assigned to this endpoint's session.
 func (ep *Endpoint) Serial() Serial {
-	return ep.serial
+	return ep.ctx.serial
 }
 
 // endpointPair holds both endpoints, queues, and shared state
@@ -83,48 +232,63 @@ func (ep *Endpoint) Serial() Serial {
 type endpointPair struct {
 	a        Endpoint
 	b        Endpoint
-	closed   atomix.Uint32
-	dataAB   lfq.SPSC[any]
-	dataBA   lfq.SPSC[any]
-	choiceAB lfq.SPSC[bool]
-	choiceBA lfq.SPSC[bool]
+	state    sessionState
+	dataAB   queue[any]
+	dataBA   queue[any]
+	choiceAB queue[Label]
+	choiceBA queue[Label]
 }
 
 // New creates a connected pair of session endpoints.
 // Internal transport uses bounded lock-free SPSC queues: two for data
 // (A→B, B→A), two for branch choice (A→B, B→A), and a shared atomic
-// counter for close signaling.
+// state word for close and abort signaling (see Endpoint.State).
 //
 // Session operations are non-blocking: DispatchSession returns
 // iox.ErrWouldBlock when the peer has not yet produced or consumed.
+//
+// Every queue holds channelCapacity (4) elements; see NewWithOptions to
+// configure a session.
 func New() (*Endpoint, *Endpoint) {
-	s := nextSerial()
+	return newPair(nextSerial(), channelCapacity, channelCapacity)
+}
 
+// newPair creates a connected pair of endpoints with serial s, whose data
+// and choice queues hold dataCap and choiceCap elements.
+func newPair(s Serial, dataCap, choiceCap int) (*Endpoint, *Endpoint) {
 	pair := &endpointPair{}
-	pair.dataAB.Init(channelCapacity)
-	pair.dataBA.Init(channelCapacity)
-	pair.choiceAB.Init(channelCapacity)
-	pair.choiceBA.Init(channelCapacity)
+	pair.dataAB.Init(dataCap)
+	pair.dataBA.Init(dataCap)
+	pair.choiceAB.Init(choiceCap)
+	pair.choiceBA.Init(choiceCap)
 
 	pair.a = Endpoint{
 		ctx: sessionContext{
-			sendQ:   &pair.dataAB,
-			recvQ:   &pair.dataBA,
-			signalQ: &pair.choiceAB,
-			awaitQ:  &pair.choiceBA,
-			closed:  &pair.closed,
+			sendQ:    &pair.dataAB,
+			recvQ:    &pair.dataBA,
+			signalQ:  &pair.choiceAB,
+			awaitQ:   &pair.choiceBA,
+			state:    &pair.state,
+			serial:   s,
+			ext:      &noExt,
+			closeBit: stateClosedA,
 		},
-		serial: s,
 	}
 	pair.b = Endpoint{
 		ctx: sessionContext{
-			sendQ:   &pair.dataBA,
-			recvQ:   &pair.dataAB,
-			signalQ: &pair.choiceBA,
-			awaitQ:  &pair.choiceAB,
-			closed:  &pair.closed,
+			sendQ:    &pair.dataBA,
+			recvQ:    &pair.dataAB,
+			signalQ:  &pair.choiceBA,
+			awaitQ:   &pair.choiceAB,
+			state:    &pair.state,
+			serial:   s,
+			ext:      &noExt,
+			closeBit: stateClosedB,
 		},
-		serial: s,
+	}
+	if linearityChecks.Load() {
+		pair.a.ctx.extend().owner = newOwnership(true)
+		pair.b.ctx.extend().owner = newOwnership(true)
 	}
 	return &pair.a, &pair.b
 }
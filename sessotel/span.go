@@ -0,0 +1,108 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sessotel
+
+import (
+	"context"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace, as in the OpenTelemetry data model.
+type TraceID [16]byte
+
+// String renders id as 32 lowercase hex digits.
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// String renders id as 16 lowercase hex digits.
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// Attribute is a key-value pair. Values are strings, int64s or bools, the
+// scalar attribute types of OpenTelemetry.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Event is a timestamped annotation of a span.
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes []Attribute
+}
+
+// Link relates a span to a span of another or the same trace.
+type Link struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Attributes []Attribute
+}
+
+// Span is a finished span. Its fields map one-to-one onto an OpenTelemetry
+// span, so an Exporter can forward it to any OpenTelemetry pipeline.
+type Span struct {
+	Name       string
+	TraceID    TraceID
+	SpanID     SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	Events     []Event
+	Links      []Link
+}
+
+// Attr returns the value of the attribute key, or nil if s has none.
+func (s *Span) Attr(key string) any {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return nil
+}
+
+// Exporter receives finished spans, like the OpenTelemetry SpanExporter.
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []Span) error
+}
+
+// InMemoryExporter keeps exported spans in memory, for tests. It is safe
+// for concurrent use.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+// NewInMemoryExporter returns an empty InMemoryExporter.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpans implements Exporter.
+func (e *InMemoryExporter) ExportSpans(_ context.Context, spans []Span) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, spans...)
+	e.mu.Unlock()
+	return nil
+}
+
+// Spans returns a copy of the spans exported so far, in export order.
+func (e *InMemoryExporter) Spans() []Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.spans)
+}
+
+// Reset forgets the spans exported so far.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
@@ -0,0 +1,275 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package sessotel exports session traces as OpenTelemetry-shaped spans.
+//
+// A [Tracer] implements [sess.Tracer]. Each endpoint of a session becomes
+// one span named "sess.session", and every Send, Recv, Select, Offer and
+// Close on it becomes a span event. The two endpoints of a session share a
+// trace, and each endpoint's span links to its peer's. The trace of an
+// in-process session is derived from its [sess.Serial]; that of a network
+// session from the session ID its two sides exchange when connecting, so
+// Tracers in different processes put both sides in the same trace. A span
+// ends and is exported when its endpoint closes; [Tracer.Flush] exports
+// the spans of sessions still open.
+//
+// The package has no dependency on the OpenTelemetry SDK: [Span] mirrors
+// the OpenTelemetry span data model, and an [Exporter] forwards spans
+// wherever they should go. [InMemoryExporter] keeps them for tests, so
+// traces can be asserted on without a live collector.
+//
+// The channels of a multiparty session share its trace, each role's end
+// of each channel having a span of its own.
+package sessotel
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"hash/fnv"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+
+	"code.hybscloud.com/sess"
+)
+
+// Attribute keys set by Tracer.
+const (
+	AttrSerial     = "sess.serial"      // span: the session's Serial
+	AttrSession    = "sess.session"     // span: the ID shared by both sides of a connection, in hex, if any
+	AttrRole       = "sess.role"        // span: own role on a multiparty channel, if any
+	AttrPeer       = "sess.peer"        // span: role at the other end of a multiparty channel
+	AttrSide       = "sess.side"        // span: 0 or 1, see sess.TraceEvent
+	AttrWouldBlock = "sess.would_block" // span: attempts that would have blocked
+	AttrStep       = "sess.step"        // event: operations completed before this one
+	AttrOp         = "sess.op"          // event: the sess.OpKind
+	AttrType       = "sess.type"        // event: the payload type, if any
+)
+
+// SpanName is the name of session spans.
+const SpanName = "sess.session"
+
+// Tracer turns session trace events into spans. Install it with
+// sess.SetTracer or Endpoint.SetTracer. It is safe for concurrent use.
+type Tracer struct {
+	exp    Exporter
+	prefix [8]byte // high half of every TraceID, unique to this Tracer
+
+	mu   sync.Mutex
+	open map[endpointKey]*openSpan
+	err  error
+}
+
+// endpointKey identifies one endpoint of a session: by its Serial in
+// process, by the session ID over a connection, and on a multiparty
+// channel by the roles at its ends.
+type endpointKey struct {
+	serial     sess.Serial
+	session    uint64
+	role, peer sess.Role
+	side       uint8
+}
+
+// keyOf returns the key of the endpoint that reported ev.
+func keyOf(ev sess.TraceEvent) endpointKey {
+	k := endpointKey{serial: ev.Serial, session: ev.Session, role: ev.Role, peer: ev.Peer, side: ev.Side}
+	if k.session != 0 {
+		k.serial = 0 // the sides of a connection differ in serial
+	}
+	return k
+}
+
+func compareKeys(a, b endpointKey) int {
+	return cmp.Or(
+		cmp.Compare(a.serial, b.serial),
+		cmp.Compare(a.session, b.session),
+		cmp.Compare(a.role, b.role),
+		cmp.Compare(a.peer, b.peer),
+		cmp.Compare(a.side, b.side),
+	)
+}
+
+// peerOf returns the key of the endpoint at the other end of k's channel.
+func peerOf(k endpointKey) endpointKey {
+	k.role, k.peer = k.peer, k.role
+	k.side ^= 1
+	return k
+}
+
+// openSpan is the span of an endpoint that has not closed.
+type openSpan struct {
+	span    Span
+	blocked int64
+}
+
+// NewTracer returns a Tracer exporting to exp.
+func NewTracer(exp Exporter) *Tracer {
+	t := &Tracer{exp: exp, open: make(map[endpointKey]*openSpan)}
+	rand.Read(t.prefix[:])
+	return t
+}
+
+// TraceID returns the ID of the trace of the session ev was reported on.
+// For a network session it depends only on the session ID, so it is the
+// same in every process.
+func (t *Tracer) TraceID(ev sess.TraceEvent) TraceID {
+	return t.traceID(keyOf(ev))
+}
+
+// SpanID returns the ID of the span of the endpoint that reported ev.
+// It is never zero.
+func (t *Tracer) SpanID(ev sess.TraceEvent) SpanID {
+	k := keyOf(ev)
+	return spanID(t.traceID(k), k)
+}
+
+func (t *Tracer) traceID(k endpointKey) TraceID {
+	var id TraceID
+	if k.session != 0 {
+		h := fnv.New64a()
+		h.Write([]byte("sess.session"))
+		binary.Write(h, binary.BigEndian, k.session)
+		binary.BigEndian.PutUint64(id[:8], h.Sum64())
+		binary.BigEndian.PutUint64(id[8:], k.session)
+		return id
+	}
+	copy(id[:8], t.prefix[:])
+	binary.BigEndian.PutUint64(id[8:], uint64(k.serial))
+	return id
+}
+
+// spanID hashes the endpoint k within trace, avoiding the invalid zero ID.
+func spanID(trace TraceID, k endpointKey) SpanID {
+	h := fnv.New64a()
+	h.Write(trace[:])
+	h.Write([]byte{k.side})
+	h.Write([]byte(k.role))
+	h.Write([]byte{0})
+	h.Write([]byte(k.peer))
+	var id SpanID
+	binary.BigEndian.PutUint64(id[:], max(h.Sum64(), 1))
+	return id
+}
+
+// OnSend implements sess.Tracer.
+func (t *Tracer) OnSend(ev sess.TraceEvent) { t.event("sess.send", ev) }
+
+// OnRecv implements sess.Tracer.
+func (t *Tracer) OnRecv(ev sess.TraceEvent) { t.event("sess.recv", ev) }
+
+// OnSelect implements sess.Tracer.
+func (t *Tracer) OnSelect(ev sess.TraceEvent) { t.event("sess.select", ev) }
+
+// OnOffer implements sess.Tracer.
+func (t *Tracer) OnOffer(ev sess.TraceEvent) { t.event("sess.offer", ev) }
+
+// OnClose implements sess.Tracer: the endpoint's span ends and is exported.
+func (t *Tracer) OnClose(ev sess.TraceEvent) {
+	t.mu.Lock()
+	s := t.record("sess.close", ev)
+	delete(t.open, keyOf(ev))
+	t.mu.Unlock()
+	t.export(context.Background(), []Span{s.finish()})
+}
+
+// OnWouldBlock implements sess.Tracer: blocked attempts are counted in
+// the span's AttrWouldBlock attribute rather than recorded as events.
+func (t *Tracer) OnWouldBlock(ev sess.TraceEvent) {
+	t.mu.Lock()
+	t.span(ev).blocked++
+	t.mu.Unlock()
+}
+
+// Flush ends the spans of endpoints that have not closed at their last
+// event and exports them, ordered by session, channel and side. It
+// returns the first export error since the previous Flush.
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	keys := slices.SortedFunc(maps.Keys(t.open), compareKeys)
+	spans := make([]Span, 0, len(keys))
+	for _, k := range keys {
+		spans = append(spans, t.open[k].finish())
+		delete(t.open, k)
+	}
+	t.mu.Unlock()
+	if len(spans) > 0 {
+		t.export(ctx, spans)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	err := t.err
+	t.err = nil
+	return err
+}
+
+func (t *Tracer) event(name string, ev sess.TraceEvent) {
+	t.mu.Lock()
+	t.record(name, ev)
+	t.mu.Unlock()
+}
+
+// record appends ev to its endpoint's span. t.mu must be held.
+func (t *Tracer) record(name string, ev sess.TraceEvent) *openSpan {
+	s := t.span(ev)
+	attrs := []Attribute{
+		{Key: AttrStep, Value: int64(ev.Step)},
+		{Key: AttrOp, Value: ev.Op.String()},
+	}
+	if ev.Type != nil {
+		attrs = append(attrs, Attribute{Key: AttrType, Value: ev.Type.String()})
+	}
+	s.span.Events = append(s.span.Events, Event{Name: name, Time: ev.Time, Attributes: attrs})
+	s.span.End = ev.Time
+	return s
+}
+
+// span returns the open span of ev's endpoint, starting it at ev.
+// t.mu must be held.
+func (t *Tracer) span(ev sess.TraceEvent) *openSpan {
+	k := keyOf(ev)
+	if s, ok := t.open[k]; ok {
+		return s
+	}
+	trace := t.traceID(k)
+	attrs := []Attribute{{Key: AttrSerial, Value: int64(ev.Serial)}}
+	if ev.Session != 0 {
+		attrs = append(attrs, Attribute{Key: AttrSession, Value: strconv.FormatUint(ev.Session, 16)})
+	}
+	if ev.Role != "" {
+		attrs = append(attrs, Attribute{Key: AttrRole, Value: string(ev.Role)}, Attribute{Key: AttrPeer, Value: string(ev.Peer)})
+	}
+	attrs = append(attrs, Attribute{Key: AttrSide, Value: int64(ev.Side)})
+	s := &openSpan{span: Span{
+		Name:       SpanName,
+		TraceID:    trace,
+		SpanID:     spanID(trace, k),
+		Start:      ev.Time,
+		End:        ev.Time,
+		Attributes: attrs,
+		Links:      []Link{{TraceID: trace, SpanID: spanID(trace, peerOf(k))}},
+	}}
+	t.open[k] = s
+	return s
+}
+
+// finish returns the finished span.
+func (s *openSpan) finish() Span {
+	span := s.span
+	span.Attributes = append(span.Attributes, Attribute{Key: AttrWouldBlock, Value: s.blocked})
+	return span
+}
+
+// export passes spans to the exporter, keeping the first error for Flush.
+func (t *Tracer) export(ctx context.Context, spans []Span) {
+	if err := t.exp.ExportSpans(ctx, spans); err != nil {
+		t.mu.Lock()
+		if t.err == nil {
+			t.err = err
+		}
+		t.mu.Unlock()
+	}
+}
@@ -0,0 +1,285 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sessotel_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+	"code.hybscloud.com/sess/sessotel"
+)
+
+// traced returns an endpoint pair traced by tr on a virtual clock.
+func traced(tr *sessotel.Tracer, clock sess.Clock) (*sess.Endpoint, *sess.Endpoint) {
+	epA, epB := sess.New()
+	for _, ep := range []*sess.Endpoint{epA, epB} {
+		ep.SetTracer(tr)
+		ep.SetClock(clock)
+	}
+	return epA, epB
+}
+
+func eventNames(s sessotel.Span) []string {
+	var names []string
+	for _, e := range s.Events {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func TestTracerSpans(t *testing.T) {
+	exp := sessotel.NewInMemoryExporter()
+	tr := sessotel.NewTracer(exp)
+	clock := sess.NewVirtualClock(time.Unix(10, 0))
+	epA, epB := traced(tr, clock)
+
+	_, server := sess.Step(sess.ExprOfferBranch(
+		func() kont.Expr[int] {
+			return sess.ExprRecvBind(func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) })
+		},
+		func() kont.Expr[int] { return sess.ExprCloseDone(0) },
+	))
+	sess.Advance(epB, server) // would block: counted, not recorded
+	_, client := sess.Step(sess.ExprSelectLThen(sess.ExprSendThen(5, sess.ExprCloseDone(struct{}{}))))
+	for client != nil {
+		clock.Advance(time.Millisecond)
+		if _, next, err := sess.Advance(epA, client); err != nil {
+			t.Fatal(err)
+		} else {
+			client = next
+		}
+	}
+	for server != nil {
+		clock.Advance(time.Millisecond)
+		if _, next, err := sess.Advance(epB, server); err != nil {
+			t.Fatal(err)
+		} else {
+			server = next
+		}
+	}
+
+	spans := exp.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(spans))
+	}
+	a, b := spans[0], spans[1]
+	if got, want := eventNames(a), []string{"sess.select", "sess.send", "sess.close"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("client events %v, want %v", got, want)
+	}
+	if got, want := eventNames(b), []string{"sess.offer", "sess.recv", "sess.close"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("server events %v, want %v", got, want)
+	}
+	if a.Name != sessotel.SpanName || a.TraceID != b.TraceID || a.TraceID != tr.TraceID(sess.TraceEvent{Serial: epA.Serial()}) {
+		t.Fatalf("spans %q/%q in traces %v and %v", a.Name, b.Name, a.TraceID, b.TraceID)
+	}
+	if a.SpanID == b.SpanID {
+		t.Fatal("both endpoints share a span ID")
+	}
+	if len(a.Links) != 1 || a.Links[0].SpanID != b.SpanID || len(b.Links) != 1 || b.Links[0].SpanID != a.SpanID {
+		t.Fatalf("links %v and %v do not relate the spans", a.Links, b.Links)
+	}
+	if got := a.Attr(sessotel.AttrSerial); got != int64(epA.Serial()) {
+		t.Fatalf("serial attribute %v", got)
+	}
+	if got := a.Attr(sessotel.AttrSide); got != int64(0) {
+		t.Fatalf("client side %v", got)
+	}
+	if got := b.Attr(sessotel.AttrWouldBlock); got != int64(1) {
+		t.Fatalf("server would-block count %v, want 1", got)
+	}
+	if !a.Start.Equal(time.Unix(10, int64(time.Millisecond))) || !a.End.Equal(time.Unix(10, int64(3*time.Millisecond))) {
+		t.Fatalf("client span from %v to %v", a.Start, a.End)
+	}
+	send := a.Events[1]
+	if send.Attributes[0] != (sessotel.Attribute{Key: sessotel.AttrStep, Value: int64(1)}) ||
+		send.Attributes[2] != (sessotel.Attribute{Key: sessotel.AttrType, Value: "int"}) {
+		t.Fatalf("send attributes %v", send.Attributes)
+	}
+}
+
+func TestTracerGlobal(t *testing.T) {
+	exp := sessotel.NewInMemoryExporter()
+	sess.SetTracer(sessotel.NewTracer(exp))
+	defer sess.SetTracer(nil)
+
+	sess.Run(
+		sess.SendThen("hello", sess.CloseDone(struct{}{})),
+		sess.RecvBind(func(s string) kont.Eff[string] { return sess.CloseDone(s) }),
+	)
+	if n := len(exp.Spans()); n != 2 {
+		t.Fatalf("exported %d spans, want 2", n)
+	}
+	exp.Reset()
+	if n := len(exp.Spans()); n != 0 {
+		t.Fatalf("%d spans after Reset", n)
+	}
+}
+
+type failingExporter struct{}
+
+func (failingExporter) ExportSpans(context.Context, []sessotel.Span) error {
+	return errors.New("collector unavailable")
+}
+
+func TestTracerFlush(t *testing.T) {
+	exp := sessotel.NewInMemoryExporter()
+	tr := sessotel.NewTracer(exp)
+	epA, epB := traced(tr, sess.NewVirtualClock(time.Unix(0, 0)))
+	sess.Advance(epA, stepSusp(sess.ExprSendThen(1, sess.ExprCloseDone(struct{}{}))))
+	sess.Advance(epB, stepSusp(sess.ExprRecvBind(func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) })))
+	if n := len(exp.Spans()); n != 0 {
+		t.Fatalf("exported %d spans before close", n)
+	}
+	if err := tr.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	spans := exp.Spans()
+	if len(spans) != 2 || spans[0].Attr(sessotel.AttrSide) != int64(0) || spans[1].Attr(sessotel.AttrSide) != int64(1) {
+		t.Fatalf("flushed %v", spans)
+	}
+
+	tr = sessotel.NewTracer(failingExporter{})
+	epA, _ = traced(tr, sess.NewVirtualClock(time.Unix(0, 0)))
+	sess.Advance(epA, stepSusp(sess.ExprCloseDone(struct{}{})))
+	if err := tr.Flush(t.Context()); err == nil {
+		t.Fatal("Flush did not report the export error")
+	}
+	if err := tr.Flush(t.Context()); err != nil {
+		t.Fatalf("second Flush got %v", err)
+	}
+}
+
+// linked reports whether every span links to another of spans that links
+// back to it, within the same trace.
+func linked(spans []sessotel.Span) bool {
+	byID := make(map[sessotel.SpanID]sessotel.Span, len(spans))
+	for _, s := range spans {
+		byID[s.SpanID] = s
+	}
+	for _, s := range spans {
+		if len(s.Links) != 1 {
+			return false
+		}
+		p, ok := byID[s.Links[0].SpanID]
+		if !ok || p.SpanID == s.SpanID || p.TraceID != s.TraceID || p.Links[0].SpanID != s.SpanID {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTracerSerialZero(t *testing.T) {
+	exp := sessotel.NewInMemoryExporter()
+	epA, epB := sess.NewWithOptions(sess.WithSerial(0), sess.WithTracer(sessotel.NewTracer(exp)))
+	advance(t, epA, sess.ExprSendThen(1, sess.ExprCloseDone(struct{}{})))
+	advance(t, epB, sess.ExprRecvBind(func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) }))
+
+	spans := exp.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(spans))
+	}
+	for _, s := range spans {
+		if s.SpanID == (sessotel.SpanID{}) {
+			t.Fatalf("span of side %v has the invalid zero ID", s.Attr(sessotel.AttrSide))
+		}
+	}
+	if !linked(spans) {
+		t.Fatalf("spans %v are not linked", spans)
+	}
+}
+
+func TestTracerMulti(t *testing.T) {
+	skipRace(t)
+	exp := sessotel.NewInMemoryExporter()
+	sess.SetTracer(sessotel.NewTracer(exp))
+	defer sess.SetTracer(nil)
+
+	sess.RunMulti(map[sess.Role]kont.Eff[int]{
+		"a": sess.SendToThen("b", 1, sess.CloseDone(0)),
+		"b": sess.RecvFromBind("a", func(n int) kont.Eff[int] { return sess.SendToThen("c", n, sess.CloseDone(0)) }),
+		"c": sess.RecvFromBind("b", func(n int) kont.Eff[int] { return sess.CloseDone(n) }),
+	})
+	spans := exp.Spans()
+	// Each role closes its channel to both others.
+	if len(spans) != 6 {
+		t.Fatalf("exported %d spans, want 6", len(spans))
+	}
+	ends := make(map[[2]any]bool)
+	ids := make(map[sessotel.SpanID]bool)
+	for _, s := range spans {
+		ends[[2]any{s.Attr(sessotel.AttrRole), s.Attr(sessotel.AttrPeer)}] = true
+		ids[s.SpanID] = true
+		if s.TraceID != spans[0].TraceID {
+			t.Fatal("channels of one session in different traces")
+		}
+	}
+	if len(ends) != 6 || len(ids) != 6 {
+		t.Fatalf("%d channel ends and %d span IDs among 6 spans", len(ends), len(ids))
+	}
+	if !linked(spans) {
+		t.Fatal("spans of a channel's ends are not linked")
+	}
+}
+
+func TestTracerConn(t *testing.T) {
+	skipRace(t)
+	// Each side traced by a Tracer of its own, as in separate processes.
+	expA, expB := sessotel.NewInMemoryExporter(), sessotel.NewInMemoryExporter()
+	c1, c2 := net.Pipe()
+	epA, epB := sess.DialConn(c1, nil), sess.AcceptConn(c2, nil)
+	epA.SetTracer(sessotel.NewTracer(expA))
+	epB.SetTracer(sessotel.NewTracer(expB))
+
+	done := make(chan int)
+	go func() {
+		done <- sess.Exec(epB, sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) }))
+	}()
+	sess.Exec(epA, sess.SendThen(7, sess.CloseDone(struct{}{})))
+	<-done
+
+	a, b := expA.Spans(), expB.Spans()
+	if len(a) != 1 || len(b) != 1 {
+		t.Fatalf("exported %d and %d spans, want 1 each", len(a), len(b))
+	}
+	if a[0].Attr(sessotel.AttrSession) == nil || a[0].Attr(sessotel.AttrSession) != b[0].Attr(sessotel.AttrSession) {
+		t.Fatalf("sides report sessions %v and %v", a[0].Attr(sessotel.AttrSession), b[0].Attr(sessotel.AttrSession))
+	}
+	if !linked(append(a, b...)) {
+		t.Fatalf("spans %v and %v are not linked in one trace", a, b)
+	}
+}
+
+func TestIDStrings(t *testing.T) {
+	tr := sessotel.NewTracer(sessotel.NewInMemoryExporter())
+	if s := tr.SpanID(sess.TraceEvent{Serial: 1, Side: 1}).String(); len(s) != 16 {
+		t.Fatalf("span ID %s", s)
+	}
+	if s := tr.TraceID(sess.TraceEvent{Serial: 1}).String(); len(s) != 32 || s[16:] != "0000000000000001" {
+		t.Fatalf("trace ID %s", s)
+	}
+}
+
+// advance runs e on ep until it completes.
+func advance[R any](t *testing.T, ep *sess.Endpoint, e kont.Expr[R]) {
+	t.Helper()
+	for susp := stepSusp(e); susp != nil; {
+		_, next, err := sess.Advance(ep, susp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		susp = next
+	}
+}
+
+func stepSusp[R any](e kont.Expr[R]) *kont.Suspension[R] {
+	_, susp := sess.Step(e)
+	return susp
+}
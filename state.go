@@ -0,0 +1,175 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"code.hybscloud.com/atomix"
+)
+
+// ErrPeerClosed is returned by Recv and Offer once the peer has closed and
+// everything it sent has been consumed, and by Send, SelectL and SelectR
+// once the peer has closed.
+var ErrPeerClosed = errors.New("sess: peer closed")
+
+// ErrSessionAborted matches, under errors.Is, the *AbortError returned by
+// every operation once either endpoint has aborted the session.
+var ErrSessionAborted = errors.New("sess: session aborted")
+
+// AbortError reports that a session was aborted. It matches
+// ErrSessionAborted and unwraps to Reason.
+type AbortError struct {
+	Serial Serial
+	Reason error // as passed to Abort; nil if none was given
+}
+
+// Error implements error.
+func (e *AbortError) Error() string {
+	msg := "sess: session " + strconv.FormatUint(uint64(e.Serial), 10) + " aborted"
+	if e.Reason != nil {
+		msg += ": " + e.Reason.Error()
+	}
+	return msg
+}
+
+// Is reports whether target is ErrSessionAborted.
+func (e *AbortError) Is(target error) bool { return target == ErrSessionAborted }
+
+// Unwrap returns the abort reason.
+func (e *AbortError) Unwrap() error { return e.Reason }
+
+// State is the lifecycle state of a session as seen from an endpoint.
+type State uint8
+
+const (
+	// StateOpen means neither endpoint has closed.
+	StateOpen State = iota
+	// StateHalfClosed means exactly one endpoint has closed.
+	StateHalfClosed
+	// StateClosed means both endpoints have closed.
+	StateClosed
+	// StateAborted means either endpoint has aborted the session.
+	StateAborted
+)
+
+var stateNames = [...]string{
+	StateOpen:       "Open",
+	StateHalfClosed: "HalfClosed",
+	StateClosed:     "Closed",
+	StateAborted:    "Aborted",
+}
+
+// String returns the name of the state.
+func (s State) String() string {
+	if int(s) < len(stateNames) {
+		return stateNames[s]
+	}
+	return "State(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Session state bits. Each endpoint sets its own close bit on Close;
+// stateAborted is set once the abort record has been published.
+const (
+	stateAborted uint32 = 1 << iota
+	stateClosedA
+	stateClosedB
+)
+
+// sessionState is the lifecycle state shared by both endpoints of a pair,
+// with the readiness callbacks of each side. The abort record is held by a
+// sync/atomic pointer, whose stores carry GC write barriers: it is the
+// only reference to the record.
+type sessionState struct {
+	bits  atomix.Uint32
+	abort atomic.Pointer[AbortError]
+	ready [2]readiness // by side; a network endpoint uses its own only
+}
+
+// peerCloseBit returns the peer's close bit in state.
+func (ctx *sessionContext) peerCloseBit() uint32 {
+	return ctx.closeBit ^ (stateClosedA | stateClosedB)
+}
+
+// abort marks the session as aborted with reason. The first abort wins;
+// later calls keep the original reason.
+func (ctx *sessionContext) abort(reason error) {
+	if ctx.state.abort.CompareAndSwap(nil, &AbortError{Serial: ctx.serial, Reason: reason}) {
+		ctx.state.bits.Or(stateAborted)
+		if ctx.ext.wire != nil {
+			close(ctx.ext.wire.aborted)
+		}
+		ctx.state.ready[0].notifyAll()
+		ctx.state.ready[1].notifyAll()
+	}
+}
+
+// checkState reports the error sop fails with before touching the
+// transport, given the state bits st: every operation fails once the
+// session is aborted, sending operations fail once the peer has closed,
+// and, with linearity checks on, every operation fails after Close.
+func (ctx *sessionContext) checkState(st uint32, sop sessionDispatcher) error {
+	if st&stateAborted != 0 {
+		return ctx.state.abort.Load()
+	}
+	if st&ctx.closeBit != 0 && ctx.ext.owner != nil {
+		return ErrEndpointClosed
+	}
+	if st&ctx.peerCloseBit() != 0 {
+		switch kind, _ := sop.opInfo(); kind {
+		case OpSend, OpSelectL, OpSelectR, OpSelect:
+			return ErrPeerClosed
+		}
+	}
+	return nil
+}
+
+// abortOnPanic is deferred by the blocking executors: if the protocol
+// running on ctx panics, the session is aborted so that the peer observes
+// ErrSessionAborted instead of waiting forever. The panic continues.
+func abortOnPanic(ctx *sessionContext) {
+	if r := recover(); r != nil {
+		ctx.abort(panicReason(r))
+		panic(r)
+	}
+}
+
+// panicReason converts a recovered panic value into an abort reason.
+func panicReason(r any) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return errors.New("sess: protocol panicked: " + fmt.Sprint(r))
+}
+
+// Abort abandons the session. Every subsequent operation on either
+// endpoint, including one the peer is currently waiting on, fails with an
+// *AbortError carrying reason. Only the first Abort is recorded.
+func (ep *Endpoint) Abort(reason error) {
+	ep.ctx.abort(reason)
+}
+
+// Aborted reports whether the session was abandoned mid-protocol by either
+// endpoint, for example because a context-aware execution was canceled.
+func (ep *Endpoint) Aborted() bool {
+	return ep.ctx.state.bits.LoadAcquire()&stateAborted != 0
+}
+
+// State reports the lifecycle state of the session.
+func (ep *Endpoint) State() State {
+	st := ep.ctx.state.bits.LoadAcquire()
+	switch {
+	case st&stateAborted != 0:
+		return StateAborted
+	case st&(stateClosedA|stateClosedB) == stateClosedA|stateClosedB:
+		return StateClosed
+	case st&(stateClosedA|stateClosedB) != 0:
+		return StateHalfClosed
+	}
+	return StateOpen
+}
@@ -0,0 +1,155 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+func TestRecvDrainsBeforePeerClosed(t *testing.T) {
+	skipRace(t)
+	epA, epB := sess.New()
+	if got := epA.State(); got != sess.StateOpen {
+		t.Fatalf("State got %v, want Open", got)
+	}
+
+	_, susp := sess.Step[struct{}](sess.ExprSendThen(7, sess.ExprCloseDone(struct{}{})))
+	for susp != nil {
+		var err error
+		if _, susp, err = sess.Advance(epA, susp); err != nil {
+			t.Fatalf("A: %v", err)
+		}
+	}
+	if got := epB.State(); got != sess.StateHalfClosed {
+		t.Fatalf("State got %v, want HalfClosed", got)
+	}
+
+	// The value sent before Close is still delivered.
+	recv := func() *kont.Suspension[int] {
+		_, s := sess.Step[int](sess.ExprRecvBind(func(n int) kont.Expr[int] { return kont.ExprReturn(n) }))
+		return s
+	}
+	n, _, err := sess.Advance(epB, recv())
+	if err != nil || n != 7 {
+		t.Fatalf("Recv got %d, %v; want 7", n, err)
+	}
+	// Then the queue is drained and the peer is gone.
+	r := recv()
+	if _, _, err := sess.Advance(epB, r); err != sess.ErrPeerClosed {
+		t.Fatalf("Recv got %v, want ErrPeerClosed", err)
+	}
+	r.Discard()
+
+	_, susp = sess.Step[struct{}](sess.ExprCloseDone(struct{}{}))
+	if _, _, err := sess.Advance(epB, susp); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := epA.State(); got != sess.StateClosed {
+		t.Fatalf("State got %v, want Closed", got)
+	}
+}
+
+func TestSendAfterPeerClosed(t *testing.T) {
+	epA, epB := sess.New()
+	_, susp := sess.Step[struct{}](sess.ExprCloseDone(struct{}{}))
+	if _, _, err := sess.Advance(epA, susp); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	for name, p := range map[string]kont.Expr[struct{}]{
+		"Send":    sess.ExprSendThen(1, kont.ExprReturn(struct{}{})),
+		"SelectL": sess.ExprSelectLThen(kont.ExprReturn(struct{}{})),
+		"SelectR": sess.ExprSelectRThen(kont.ExprReturn(struct{}{})),
+	} {
+		_, susp := sess.Step[struct{}](p)
+		if _, _, err := sess.Advance(epB, susp); err != sess.ErrPeerClosed {
+			t.Fatalf("%s got %v, want ErrPeerClosed", name, err)
+		}
+		susp.Discard()
+	}
+}
+
+func TestOfferAfterPeerClosed(t *testing.T) {
+	skipRace(t)
+	a := sess.CloseDone(struct{}{})
+	b := sess.OfferBranch(
+		func() kont.Eff[int] { return sess.CloseDone(1) },
+		func() kont.Eff[int] { return sess.CloseDone(2) },
+	)
+	if _, _, err := sess.TryRun(a, b); err != sess.ErrPeerClosed {
+		t.Fatalf("expected ErrPeerClosed, got %v", err)
+	}
+}
+
+func TestAbortWakesWaitingPeer(t *testing.T) {
+	skipRace(t)
+	epA, epB := sess.New()
+	reason := errors.New("shutting down")
+
+	done := make(chan error)
+	go func() {
+		_, err := sess.ExecExprContext(context.Background(), epB,
+			sess.ExprRecvBind(func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) }))
+		done <- err
+	}()
+	epA.Abort(reason)
+	epA.Abort(errors.New("ignored"))
+
+	err := <-done
+	if !errors.Is(err, sess.ErrSessionAborted) || !errors.Is(err, reason) {
+		t.Fatalf("got %v, want ErrSessionAborted wrapping reason", err)
+	}
+	var ae *sess.AbortError
+	if !errors.As(err, &ae) || ae.Serial != epA.Serial() {
+		t.Fatalf("got %v, want *AbortError for session %d", err, epA.Serial())
+	}
+	if !strings.HasSuffix(err.Error(), "aborted: shutting down") {
+		t.Fatalf("unexpected message: %v", err)
+	}
+	if epB.State() != sess.StateAborted || !epB.Aborted() {
+		t.Fatalf("State got %v, want Aborted", epB.State())
+	}
+}
+
+func TestExecPanicAbortsSession(t *testing.T) {
+	skipRace(t)
+	epA, epB := sess.New()
+
+	go func() {
+		defer func() { recover() }()
+		sess.Exec(epA, sess.RecvBind(func(n int) kont.Eff[struct{}] {
+			panic("boom")
+		}))
+	}()
+
+	_, err := sess.ExecContext(context.Background(), epB, sess.SendThen(1,
+		sess.RecvBind(func(s string) kont.Eff[string] { return sess.CloseDone(s) })))
+	if !errors.Is(err, sess.ErrSessionAborted) {
+		t.Fatalf("got %v, want ErrSessionAborted", err)
+	}
+	if !strings.Contains(err.Error(), "protocol panicked: boom") {
+		t.Fatalf("unexpected message: %v", err)
+	}
+}
+
+func TestStateString(t *testing.T) {
+	for s, want := range map[sess.State]string{
+		sess.StateOpen:       "Open",
+		sess.StateHalfClosed: "HalfClosed",
+		sess.StateClosed:     "Closed",
+		sess.StateAborted:    "Aborted",
+		sess.State(9):        "State(9)",
+	} {
+		if got := s.String(); got != want {
+			t.Fatalf("String got %q, want %q", got, want)
+		}
+	}
+}
@@ -14,6 +14,19 @@ func Step[R any](protocol kont.Expr[R]) (R, *kont.Suspension[R]) {
 	return kont.StepExpr(protocol)
 }
 
+// StepEff is Step for a Cont-world protocol, reified to an Expr.
+// Advance the returned suspension with Advance.
+func StepEff[R any](protocol kont.Eff[R]) (R, *kont.Suspension[R]) {
+	return kont.StepExpr(Reify(protocol))
+}
+
+// StepEffError is StepError for a Cont-world protocol, reified to an Expr
+// and wrapped in Either as StepError does. Advance the returned
+// suspension with AdvanceError.
+func StepEffError[E, R any](protocol kont.Eff[R]) (kont.Either[E, R], *kont.Suspension[kont.Either[E, R]]) {
+	return StepError[E](Reify(protocol))
+}
+
 // Advance dispatches the suspended session operation on the endpoint.
 // DispatchSession is non-blocking: returns iox.ErrWouldBlock when the
 // bounded SPSC queue cannot make progress (the I/O boundary).
@@ -21,13 +34,14 @@ func Step[R any](protocol kont.Expr[R]) (R, *kont.Suspension[R]) {
 // On success (nil error), the suspension is consumed and the protocol
 // advances to the next effect or completion.
 // On iox.ErrWouldBlock, the suspension is unconsumed and may be retried
-// after the peer makes progress.
+// after the peer makes progress. Any other error (e.g. *ProtocolViolation)
+// is terminal: the suspension is returned unconsumed for the caller to discard.
 func Advance[R any](ep *Endpoint, susp *kont.Suspension[R]) (R, *kont.Suspension[R], error) {
 	sop, ok := susp.Op().(sessionDispatcher)
 	if !ok {
 		panic("sess: unhandled effect in Advance")
 	}
-	v, err := sop.DispatchSession(&ep.ctx)
+	v, err := ep.ctx.dispatch(sop)
 	if err != nil {
 		var zero R
 		return zero, susp, err
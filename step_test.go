@@ -5,6 +5,7 @@
 package sess_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -507,7 +508,8 @@ func TestAdvanceAffine(t *testing.T) {
 		t.Fatal("expected suspension")
 	}
 
-	ep, _ := sess.New()
+	// Without linearity checks, which would reject the second Close first.
+	ep, _ := sess.NewWithOptions(sess.WithLinearityChecks(false))
 	_, _, err := sess.Advance(ep, susp)
 	if err != nil {
 		t.Fatalf("first Advance error: %v", err)
@@ -526,3 +528,158 @@ func TestAdvanceAffine(t *testing.T) {
 	}()
 	sess.Advance(ep, susp)
 }
+
+func TestStepEff(t *testing.T) {
+	skipRace(t)
+	epA, epB := sess.New()
+	_, suspA := sess.StepEff(sess.SendThen(42, sess.CloseDone("sent")))
+	_, suspB := sess.StepEff(sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) }))
+
+	var resultA string
+	var resultB int
+	var err error
+	for suspA != nil || suspB != nil {
+		if suspA != nil {
+			if resultA, suspA, err = sess.Advance(epA, suspA); err != nil && err != iox.ErrWouldBlock {
+				t.Fatal(err)
+			}
+		}
+		if suspB != nil {
+			if resultB, suspB, err = sess.Advance(epB, suspB); err != nil && err != iox.ErrWouldBlock {
+				t.Fatal(err)
+			}
+		}
+	}
+	if resultA != "sent" || resultB != 42 {
+		t.Fatalf("got %q, %d, want sent, 42", resultA, resultB)
+	}
+}
+
+func TestStepEffError(t *testing.T) {
+	skipRace(t)
+	ep, _ := sess.New()
+	_, susp := sess.StepEffError[string](sess.SendThen(1, kont.ThrowError[string, int]("boom")))
+	r, next, err := sess.AdvanceError[string](ep, susp)
+	if err != nil || next == nil {
+		t.Fatalf("send got %v, %v", next, err)
+	}
+	r, next, err = sess.AdvanceError[string](ep, next)
+	if e, _ := r.GetLeft(); err != nil || next != nil || e != "boom" {
+		t.Fatalf("throw got %v, %v, %v, want Left(boom)", r, next, err)
+	}
+}
+
+// loopConn is the per-session state of an event loop embedding a Stepper.
+type loopConn struct {
+	sess.Stepper[int]
+	id int
+}
+
+func TestStepperPoll(t *testing.T) {
+	skipRace(t)
+	epA, epB := sess.New()
+	var client loopConn
+	server := loopConn{id: 1}
+	client.Start(epA, sess.SendThen(20, sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })))
+	server.StartExpr(epB, sess.ExprRecvBind(func(n int) kont.Expr[int] {
+		return sess.ExprSendThen(n+1, sess.ExprCloseDone(n))
+	}))
+
+	// The client sends and waits on the reply.
+	if done, err := client.Poll(); done || err != nil {
+		t.Fatalf("client Poll got %v, %v, want pending", done, err)
+	}
+	if _, ok := client.Pending().(sess.Recv[int]); !ok {
+		t.Fatalf("client pending on %T, want Recv[int]", client.Pending())
+	}
+	if done, err := server.Poll(); !done || err != nil {
+		t.Fatalf("server Poll got %v, %v, want done", done, err)
+	}
+	if done, err := client.Poll(); !done || err != nil {
+		t.Fatalf("client Poll got %v, %v, want done", done, err)
+	}
+	if r, err := client.Result(); r != 21 || err != nil {
+		t.Fatalf("client result %d, %v, want 21", r, err)
+	}
+	if r, _ := server.Result(); r != 20 || client.Pending() != nil {
+		t.Fatalf("server result %d, want 20", r)
+	}
+
+	// Reused on a fresh pair.
+	epA, epB = sess.New()
+	client.Start(epA, sess.CloseDone(7))
+	if done, err := client.Poll(); !done || err != nil || client.Endpoint() != epA {
+		t.Fatalf("restarted client Poll got %v, %v", done, err)
+	}
+	if r, _ := client.Result(); r != 7 {
+		t.Fatalf("restarted client result %d, want 7", r)
+	}
+}
+
+func TestStepperFailure(t *testing.T) {
+	skipRace(t)
+	epA, epB := sess.New()
+	var s sess.Stepper[int]
+	if done, err := s.Poll(); !done || err != nil {
+		t.Fatalf("idle Poll got %v, %v, want done", done, err)
+	}
+	s.Start(epA, sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) }))
+	sess.Exec(epB, sess.SendThen("not an int", sess.CloseDone(struct{}{})))
+
+	done, err := s.Poll()
+	var pv *sess.ProtocolViolation
+	if !done || !errors.As(err, &pv) {
+		t.Fatalf("Poll got %v, %v, want *ProtocolViolation", done, err)
+	}
+	if _, again := s.Poll(); again != err || !epB.Aborted() {
+		t.Fatalf("second Poll got %v; aborted %v", again, epB.Aborted())
+	}
+}
+
+func TestErrorStepperCatch(t *testing.T) {
+	skipRace(t)
+	epA, epB := sess.New()
+	var client sess.ErrorStepper[string, string]
+	var server sess.Stepper[string]
+	client.Start(epA, sess.CatchError(
+		sess.RecvBind(func(n int) kont.Eff[string] { return kont.ThrowError[string, string]("declined") }),
+		func(e string) kont.Eff[string] { return sess.SendThen("cancel", sess.CloseDone("compensated: "+e)) },
+	))
+	server.Start(epB, sess.SendThen(1, sess.RecvBind(func(s string) kont.Eff[string] { return sess.CloseDone(s) })))
+
+	// The Catch body waits on the server without blocking Poll.
+	if done, err := client.Poll(); done || err != nil {
+		t.Fatalf("client Poll got %v, %v, want pending", done, err)
+	}
+	if _, ok := client.Pending().(sess.Recv[int]); !ok {
+		t.Fatalf("client pending on %T, want Recv[int]", client.Pending())
+	}
+	if done, err := server.Poll(); done || err != nil {
+		t.Fatalf("server Poll got %v, %v, want pending", done, err)
+	}
+	if done, err := client.Poll(); !done || err != nil {
+		t.Fatalf("client Poll got %v, %v, want done", done, err)
+	}
+	if r, _ := client.Result(); r != kont.Right[string]("compensated: declined") {
+		t.Fatalf("client result %v", r)
+	}
+	if done, err := server.Poll(); !done || err != nil {
+		t.Fatalf("server Poll got %v, %v, want done", done, err)
+	}
+	if r, _ := server.Result(); r != "cancel" {
+		t.Fatalf("server result %q, want cancel", r)
+	}
+}
+
+func TestErrorStepperThrow(t *testing.T) {
+	skipRace(t)
+	epA, epB := sess.New()
+	var s sess.ErrorStepper[string, int]
+	s.Start(epA, sess.SendThen(1, kont.ThrowError[string, int]("boom")))
+	if done, err := s.Poll(); !done || err != nil {
+		t.Fatalf("Poll got %v, %v, want done", done, err)
+	}
+	if r, _ := s.Result(); r != kont.Left[string, int]("boom") || !epB.Aborted() {
+		t.Fatalf("result %v, aborted %v, want Left(boom) and an aborted session", r, epB.Aborted())
+	}
+}
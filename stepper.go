@@ -0,0 +1,191 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+)
+
+// Stepper drives one protocol on one endpoint through Step and Advance,
+// holding the pending suspension and the result between polls. It is meant
+// to be embedded in the per-connection state of an event loop, which calls
+// Poll whenever the endpoint may be ready, for example from the callbacks
+// registered with Endpoint.OnReadable and Endpoint.OnWritable.
+//
+// The zero Stepper is idle; Start begins a protocol. A Stepper is reusable:
+// Start may be called again once the protocol is done, or earlier to
+// abandon it. A Stepper must not be copied after Start, nor used from more
+// than one goroutine at a time.
+//
+// A Stepper handles session operations only; a protocol that throws or
+// catches errors runs on an ErrorStepper.
+type Stepper[R any] struct {
+	ep     *Endpoint
+	susp   *kont.Suspension[R]
+	result R
+	err    error
+	done   bool
+}
+
+// Start begins running the Cont-world protocol on ep, evaluating it to its
+// first operation. Any protocol the Stepper was running is abandoned.
+func (s *Stepper[R]) Start(ep *Endpoint, protocol kont.Eff[R]) {
+	s.StartExpr(ep, Reify(protocol))
+}
+
+// StartExpr begins running the Expr-world protocol on ep, evaluating it to
+// its first operation. Any protocol the Stepper was running is abandoned.
+func (s *Stepper[R]) StartExpr(ep *Endpoint, protocol kont.Expr[R]) {
+	s.Reset()
+	s.ep = ep
+	s.result, s.susp = Step(protocol)
+	s.done = s.susp == nil
+}
+
+// Poll performs operations until the protocol completes, fails, or would
+// block. It returns false with a nil error when the protocol is waiting on
+// the peer, and true once it is done, with the error that failed it, if
+// any. As with Spawn, an operation failing with an error other than
+// ErrPeerClosed aborts the session so that the peer observes it. Polling an
+// idle or done Stepper returns true and the same error again.
+func (s *Stepper[R]) Poll() (done bool, err error) {
+	for !s.done {
+		if s.susp == nil {
+			s.done = true
+			break
+		}
+		result, next, err := Advance(s.ep, s.susp)
+		if err == iox.ErrWouldBlock {
+			return false, nil
+		}
+		if err != nil {
+			if err != ErrPeerClosed {
+				s.ep.ctx.abort(err)
+			}
+			s.susp.Discard()
+			s.susp, s.err, s.done = nil, err, true
+			break
+		}
+		s.result, s.susp = result, next
+	}
+	return true, s.err
+}
+
+// Result returns the protocol's result and the error that failed it.
+// The result is the zero value until the protocol completes.
+func (s *Stepper[R]) Result() (R, error) {
+	return s.result, s.err
+}
+
+// Endpoint returns the endpoint the Stepper runs on, or nil if idle.
+func (s *Stepper[R]) Endpoint() *Endpoint {
+	return s.ep
+}
+
+// Pending returns the operation the protocol is suspended on, or nil if
+// the Stepper is idle or done.
+func (s *Stepper[R]) Pending() kont.Operation {
+	if s.susp == nil {
+		return nil
+	}
+	return s.susp.Op()
+}
+
+// Reset abandons the running protocol, if any, and leaves the Stepper idle.
+// The session is not aborted.
+func (s *Stepper[R]) Reset() {
+	discard(s.susp)
+	*s = Stepper[R]{}
+}
+
+// ErrorStepper is a Stepper for protocols with error handling, driven
+// through StepError and AdvanceError. Its result is Right on success and
+// Left on a Throw or when the peer failed (see PeerFailed); a Left aborts
+// the session as ExecError does. Catch is stepped with the rest of the
+// protocol, so Poll never blocks.
+type ErrorStepper[E, R any] struct {
+	ep     *Endpoint
+	susp   *kont.Suspension[kont.Either[E, R]]
+	result kont.Either[E, R]
+	err    error
+	done   bool
+}
+
+// Start begins running the Cont-world protocol on ep, evaluating it to its
+// first operation. Any protocol the ErrorStepper was running is abandoned.
+func (s *ErrorStepper[E, R]) Start(ep *Endpoint, protocol kont.Eff[R]) {
+	s.StartExpr(ep, Reify(protocol))
+}
+
+// StartExpr begins running the Expr-world protocol on ep, evaluating it to
+// its first operation. Any protocol the ErrorStepper was running is
+// abandoned.
+func (s *ErrorStepper[E, R]) StartExpr(ep *Endpoint, protocol kont.Expr[R]) {
+	s.Reset()
+	s.ep = ep
+	s.result, s.susp = StepError[E](protocol)
+	s.done = s.susp == nil
+	if s.done {
+		failed(&ep.ctx, s.result)
+	}
+}
+
+// Poll performs operations until the protocol completes, fails, or would
+// block, as Stepper.Poll does. A protocol that ends with Left is done
+// without an error; its Left is the result.
+func (s *ErrorStepper[E, R]) Poll() (done bool, err error) {
+	for !s.done {
+		if s.susp == nil {
+			s.done = true
+			break
+		}
+		result, next, err := AdvanceError[E](s.ep, s.susp)
+		if err == iox.ErrWouldBlock {
+			return false, nil
+		}
+		if err != nil {
+			if err != ErrPeerClosed {
+				s.ep.ctx.abort(err)
+			}
+			s.susp.Discard()
+			s.susp, s.err, s.done = nil, err, true
+			break
+		}
+		s.result, s.susp = result, next
+	}
+	return true, s.err
+}
+
+// Result returns the protocol's result and the error that failed it.
+// The result is the zero Either until the protocol completes.
+func (s *ErrorStepper[E, R]) Result() (kont.Either[E, R], error) {
+	return s.result, s.err
+}
+
+// Endpoint returns the endpoint the ErrorStepper runs on, or nil if idle.
+func (s *ErrorStepper[E, R]) Endpoint() *Endpoint {
+	return s.ep
+}
+
+// Pending returns the operation the protocol is waiting for, or nil if
+// the ErrorStepper is idle or done. Within a Catch, it is the pending
+// operation of its body or handler.
+func (s *ErrorStepper[E, R]) Pending() kont.Operation {
+	if s.susp == nil {
+		return nil
+	}
+	return s.ep.ctx.pendingOp(s.susp)
+}
+
+// Reset abandons the running protocol, if any, and leaves the
+// ErrorStepper idle. The session is not aborted.
+func (s *ErrorStepper[E, R]) Reset() {
+	if s.susp != nil {
+		s.ep.ctx.dropCatches(0)
+	}
+	discard(s.susp)
+	*s = ErrorStepper[E, R]{}
+}
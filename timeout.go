@@ -0,0 +1,162 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"reflect"
+	"time"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+)
+
+// deadline is the deadline of the timed operation numbered step.
+type deadline struct {
+	step  int
+	at    time.Time
+	armed bool
+}
+
+// expired reports whether the timed operation at the current step,
+// which has just returned err, has run out of time. The deadline is set
+// d after the operation was first found blocked. An operation that fails
+// otherwise, or whose peer has closed, is left to fail as it would
+// without a deadline.
+func (ctx *sessionContext) expired(err error, d time.Duration) bool {
+	if err != iox.ErrWouldBlock || ctx.state.bits.LoadAcquire()&ctx.peerCloseBit() != 0 {
+		return false
+	}
+	now := ctx.now()
+	if !ctx.ext.timer.armed || ctx.ext.timer.step != ctx.step {
+		ctx.extend().timer = deadline{step: ctx.step, at: now.Add(d), armed: true}
+	}
+	if now.Before(ctx.ext.timer.at) {
+		return false
+	}
+	ctx.extend().timer.armed = false
+	return true
+}
+
+// Timeout is the result of a timed operation that could not complete
+// within its duration. A timed-out operation has no effect on the
+// session; the peer may still complete its dual later.
+type Timeout struct {
+	Op     OpKind
+	Within time.Duration
+}
+
+// String renders t, e.g. "Recv timed out after 1s".
+func (t Timeout) String() string {
+	return t.Op.String() + " timed out after " + t.Within.String()
+}
+
+// timedOp is implemented by timed operations. timedOut reports whether
+// the result v is a Timeout; the monitor stays at the operation then.
+type timedOp interface {
+	timedOut(v kont.Resumed) bool
+}
+
+// isTimed reports whether sop is a timed operation.
+func isTimed(sop sessionDispatcher) bool {
+	_, ok := sop.(timedOp)
+	return ok
+}
+
+// timedSusp reports whether susp, suspended on ctx, is pending on a timed
+// operation.
+func timedSusp[R any](ctx *sessionContext, susp *kont.Suspension[R]) bool {
+	if susp == nil {
+		return false
+	}
+	_, ok := ctx.pendingOp(susp).(timedOp)
+	return ok
+}
+
+// SendWithin is the effect operation for sending a value of type T
+// within a duration: it yields Right once v is queued, or Left if the
+// send queue stayed full for Within.
+type SendWithin[T any] struct {
+	kont.Phantom[kont.Either[Timeout, struct{}]]
+	Value  T
+	Within time.Duration
+}
+
+// DispatchSession handles SendWithin on the session transport.
+// Non-blocking: returns iox.ErrWouldBlock until the value is queued or
+// the deadline has passed.
+func (s SendWithin[T]) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
+	_, err := Send[T]{Value: s.Value}.DispatchSession(ctx)
+	if err == nil {
+		return kont.Right[Timeout](struct{}{}), nil
+	}
+	if !ctx.expired(err, s.Within) {
+		return nil, err
+	}
+	return kont.Left[Timeout, struct{}](Timeout{Op: OpSend, Within: s.Within}), nil
+}
+
+func (SendWithin[T]) opInfo() (OpKind, reflect.Type) { return OpSend, reflect.TypeFor[T]() }
+func (s SendWithin[T]) payload(kont.Resumed) any     { return s.Value }
+func (SendWithin[T]) timedOut(v kont.Resumed) bool {
+	return v.(kont.Either[Timeout, struct{}]).IsLeft()
+}
+
+// RecvWithin is the effect operation for receiving a value of type T
+// within a duration: it yields Right with the value, or Left if nothing
+// arrived within Within.
+type RecvWithin[T any] struct {
+	kont.Phantom[kont.Either[Timeout, T]]
+	Within time.Duration
+}
+
+// DispatchSession handles RecvWithin on the session transport.
+// Non-blocking: returns iox.ErrWouldBlock until a value arrives or the
+// deadline has passed.
+func (r RecvWithin[T]) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
+	v, err := Recv[T]{}.DispatchSession(ctx)
+	if err == nil {
+		return kont.Right[Timeout](v.(T)), nil
+	}
+	if !ctx.expired(err, r.Within) {
+		return nil, err
+	}
+	return kont.Left[Timeout, T](Timeout{Op: OpRecv, Within: r.Within}), nil
+}
+
+func (RecvWithin[T]) opInfo() (OpKind, reflect.Type) { return OpRecv, reflect.TypeFor[T]() }
+func (RecvWithin[T]) payload(v kont.Resumed) any {
+	t, _ := v.(kont.Either[Timeout, T]).GetRight()
+	return t
+}
+func (RecvWithin[T]) timedOut(v kont.Resumed) bool {
+	return v.(kont.Either[Timeout, T]).IsLeft()
+}
+
+// OfferWithin is the effect operation for receiving a binary choice
+// within a duration: it yields Right with the choice as Offer does, or
+// Left if the peer did not choose within Within.
+type OfferWithin struct {
+	kont.Phantom[kont.Either[Timeout, kont.Either[struct{}, struct{}]]]
+	Within time.Duration
+}
+
+// DispatchSession handles OfferWithin on the session transport.
+// Non-blocking: returns iox.ErrWouldBlock until a choice arrives or the
+// deadline has passed.
+func (o OfferWithin) DispatchSession(ctx *sessionContext) (kont.Resumed, error) {
+	v, err := Offer{}.DispatchSession(ctx)
+	if err == nil {
+		return kont.Right[Timeout](v.(kont.Either[struct{}, struct{}])), nil
+	}
+	if !ctx.expired(err, o.Within) {
+		return nil, err
+	}
+	return kont.Left[Timeout, kont.Either[struct{}, struct{}]](Timeout{Op: OpOffer, Within: o.Within}), nil
+}
+
+func (OfferWithin) opInfo() (OpKind, reflect.Type) { return OpOffer, nil }
+func (OfferWithin) timedOut(v kont.Resumed) bool {
+	return v.(kont.Either[Timeout, kont.Either[struct{}, struct{}]]).IsLeft()
+}
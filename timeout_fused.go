@@ -0,0 +1,132 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"time"
+
+	"code.hybscloud.com/kont"
+)
+
+// SendWithinThen sends v within d and continues with next, or calls
+// onTimeout if the send queue stayed full for d.
+// Fuses Perform(SendWithin[T]{Value: v, Within: d}) + Bind + Either branch.
+func SendWithinThen[T, B any](d time.Duration, v T, next kont.Eff[B], onTimeout func(Timeout) kont.Eff[B]) kont.Eff[B] {
+	return kont.Bind(kont.Perform(SendWithin[T]{Value: v, Within: d}), func(e kont.Either[Timeout, struct{}]) kont.Eff[B] {
+		if t, ok := e.GetLeft(); ok {
+			return onTimeout(t)
+		}
+		return next
+	})
+}
+
+// RecvWithinBind receives a value within d and passes it to f, or calls
+// onTimeout if nothing arrived within d.
+// Fuses Perform(RecvWithin[T]{Within: d}) + Bind + Either branch.
+func RecvWithinBind[T, B any](d time.Duration, f func(T) kont.Eff[B], onTimeout func(Timeout) kont.Eff[B]) kont.Eff[B] {
+	return kont.Bind(kont.Perform(RecvWithin[T]{Within: d}), func(e kont.Either[Timeout, T]) kont.Eff[B] {
+		if t, ok := e.GetLeft(); ok {
+			return onTimeout(t)
+		}
+		v, _ := e.GetRight()
+		return f(v)
+	})
+}
+
+// OfferWithinBranch waits up to d for the peer's choice and calls onLeft
+// or onRight, or onTimeout if the peer did not choose within d.
+// Fuses Perform(OfferWithin{Within: d}) + Bind + Either branch.
+func OfferWithinBranch[A any](d time.Duration, onLeft, onRight func() kont.Eff[A], onTimeout func(Timeout) kont.Eff[A]) kont.Eff[A] {
+	return kont.Bind(kont.Perform(OfferWithin{Within: d}), func(e kont.Either[Timeout, kont.Either[struct{}, struct{}]]) kont.Eff[A] {
+		if t, ok := e.GetLeft(); ok {
+			return onTimeout(t)
+		}
+		if c, _ := e.GetRight(); c.IsLeft() {
+			return onLeft()
+		}
+		return onRight()
+	})
+}
+
+func sendWithinUnwind[B any](data, data2, _ kont.Erased, current kont.Erased) (kont.Erased, kont.Frame) {
+	var result kont.Expr[B]
+	if t, ok := current.(kont.Either[Timeout, struct{}]).GetLeft(); ok {
+		result = data2.(func(Timeout) kont.Expr[B])(t)
+	} else {
+		result = data.(kont.Expr[B])
+	}
+	return kont.Erased(result.Value), result.Frame
+}
+
+// ExprSendWithinThen sends v within d and continues with next, or calls
+// onTimeout if the send queue stayed full for d.
+// Fuses ExprPerform(SendWithin[T]{Value: v, Within: d}) + ExprBind + Either branch.
+func ExprSendWithinThen[T, B any](d time.Duration, v T, next kont.Expr[B], onTimeout func(Timeout) kont.Expr[B]) kont.Expr[B] {
+	bf := kont.AcquireUnwindFrame()
+	bf.Data1 = next
+	bf.Data2 = onTimeout
+	bf.Unwind = sendWithinUnwind[B]
+	ef := kont.AcquireEffectFrame()
+	ef.Operation = SendWithin[T]{Value: v, Within: d}
+	ef.Resume = identityResume
+	ef.Next = bf
+	return kont.ExprSuspend[B](ef)
+}
+
+func recvWithinUnwind[T, B any](data, data2, _ kont.Erased, current kont.Erased) (kont.Erased, kont.Frame) {
+	e := current.(kont.Either[Timeout, T])
+	var result kont.Expr[B]
+	if t, ok := e.GetLeft(); ok {
+		result = data2.(func(Timeout) kont.Expr[B])(t)
+	} else {
+		v, _ := e.GetRight()
+		result = data.(func(T) kont.Expr[B])(v)
+	}
+	return kont.Erased(result.Value), result.Frame
+}
+
+// ExprRecvWithinBind receives a value within d and passes it to f, or
+// calls onTimeout if nothing arrived within d.
+// Fuses ExprPerform(RecvWithin[T]{Within: d}) + ExprBind + Either branch.
+func ExprRecvWithinBind[T, B any](d time.Duration, f func(T) kont.Expr[B], onTimeout func(Timeout) kont.Expr[B]) kont.Expr[B] {
+	bf := kont.AcquireUnwindFrame()
+	bf.Data1 = f
+	bf.Data2 = onTimeout
+	bf.Unwind = recvWithinUnwind[T, B]
+	ef := kont.AcquireEffectFrame()
+	ef.Operation = RecvWithin[T]{Within: d}
+	ef.Resume = identityResume
+	ef.Next = bf
+	return kont.ExprSuspend[B](ef)
+}
+
+func offerWithinUnwind[A any](data, data2, data3 kont.Erased, current kont.Erased) (kont.Erased, kont.Frame) {
+	e := current.(kont.Either[Timeout, kont.Either[struct{}, struct{}]])
+	var result kont.Expr[A]
+	if t, ok := e.GetLeft(); ok {
+		result = data3.(func(Timeout) kont.Expr[A])(t)
+	} else if c, _ := e.GetRight(); c.IsLeft() {
+		result = data.(func() kont.Expr[A])()
+	} else {
+		result = data2.(func() kont.Expr[A])()
+	}
+	return kont.Erased(result.Value), result.Frame
+}
+
+// ExprOfferWithinBranch waits up to d for the peer's choice and calls
+// onLeft or onRight, or onTimeout if the peer did not choose within d.
+// Fuses ExprPerform(OfferWithin{Within: d}) + ExprBind + Either branch.
+func ExprOfferWithinBranch[A any](d time.Duration, onLeft, onRight func() kont.Expr[A], onTimeout func(Timeout) kont.Expr[A]) kont.Expr[A] {
+	bf := kont.AcquireUnwindFrame()
+	bf.Data1 = onLeft
+	bf.Data2 = onRight
+	bf.Data3 = onTimeout
+	bf.Unwind = offerWithinUnwind[A]
+	ef := kont.AcquireEffectFrame()
+	ef.Operation = OfferWithin{Within: d}
+	ef.Resume = identityResume
+	ef.Next = bf
+	return kont.ExprSuspend[A](ef)
+}
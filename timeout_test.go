@@ -0,0 +1,220 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"testing"
+	"time"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+func timeoutResult(t sess.Timeout) kont.Expr[string] {
+	return kont.ExprReturn(t.String())
+}
+
+func TestRecvWithinTimesOut(t *testing.T) {
+	_, epB := sess.New()
+	clock := sess.NewVirtualClock(time.Unix(0, 0))
+	epB.SetClock(clock)
+
+	_, susp := sess.Step(sess.ExprRecvWithinBind(time.Second, func(n int) kont.Expr[string] {
+		return kont.ExprReturn("received")
+	}, timeoutResult))
+	if _, _, err := sess.Advance(epB, susp); err != iox.ErrWouldBlock {
+		t.Fatalf("got %v, want ErrWouldBlock", err)
+	}
+	clock.Advance(time.Second - 1)
+	if _, _, err := sess.Advance(epB, susp); err != iox.ErrWouldBlock {
+		t.Fatalf("got %v before the deadline, want ErrWouldBlock", err)
+	}
+	clock.Advance(1)
+	got, next, err := sess.Advance(epB, susp)
+	if err != nil || next != nil {
+		t.Fatalf("got %v, %v, want completion", next, err)
+	}
+	if got != "Recv timed out after 1s" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestRecvWithinReceives(t *testing.T) {
+	epA, epB := sess.New()
+	clock := sess.NewVirtualClock(time.Unix(0, 0))
+	epB.SetClock(clock)
+
+	_, susp := sess.Step(sess.ExprRecvWithinBind(time.Second, func(n int) kont.Expr[int] {
+		return kont.ExprReturn(n)
+	}, func(sess.Timeout) kont.Expr[int] { return kont.ExprReturn(-1) }))
+	if _, _, err := sess.Advance(epB, susp); err != iox.ErrWouldBlock {
+		t.Fatalf("got %v, want ErrWouldBlock", err)
+	}
+	clock.Advance(time.Second / 2)
+	if _, _, err := sess.Advance(epA, stepSusp(sess.ExprSendThen(8, kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+	// The value arrived: it is received even though the deadline has passed.
+	if got, _, err := sess.Advance(epB, susp); err != nil || got != 8 {
+		t.Fatalf("got %d, %v, want 8", got, err)
+	}
+}
+
+func TestOfferWithin(t *testing.T) {
+	epA, epB := sess.New()
+	clock := sess.NewVirtualClock(time.Unix(0, 0))
+	epB.SetClock(clock)
+	offer := func() kont.Expr[string] {
+		return sess.ExprOfferWithinBranch(time.Millisecond,
+			func() kont.Expr[string] { return kont.ExprReturn("left") },
+			func() kont.Expr[string] { return kont.ExprReturn("right") },
+			timeoutResult,
+		)
+	}
+
+	_, susp := sess.Step(offer())
+	sess.Advance(epB, susp)
+	clock.Advance(time.Millisecond)
+	if got, _, err := sess.Advance(epB, susp); err != nil || got != "Offer timed out after 1ms" {
+		t.Fatalf("got %q, %v, want timeout", got, err)
+	}
+
+	// A new offer has a deadline of its own.
+	_, susp = sess.Step(offer())
+	if _, _, err := sess.Advance(epB, susp); err != iox.ErrWouldBlock {
+		t.Fatalf("got %v, want ErrWouldBlock", err)
+	}
+	if _, _, err := sess.Advance(epA, stepSusp(sess.ExprSelectRThen(kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := sess.Advance(epB, susp); err != nil || got != "right" {
+		t.Fatalf("got %q, %v, want right", got, err)
+	}
+}
+
+func TestSendWithinTimesOut(t *testing.T) {
+	epA, _ := sess.New()
+	clock := sess.NewVirtualClock(time.Unix(0, 0))
+	epA.SetClock(clock)
+
+	var timedOut bool
+	var send func(i int) kont.Eff[int]
+	send = func(i int) kont.Eff[int] {
+		return sess.SendWithinThen(time.Second, i, kont.Pure(i+1), func(sess.Timeout) kont.Eff[int] {
+			timedOut = true
+			return kont.Pure(i)
+		})
+	}
+	sent := 0
+	for !timedOut {
+		_, susp := sess.Step(sess.Reify(send(sent)))
+		var err error
+		for {
+			var n int
+			n, susp, err = sess.Advance(epA, susp)
+			if err != iox.ErrWouldBlock {
+				sent = n
+				break
+			}
+			clock.Advance(time.Second)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sent == 0 {
+		t.Fatal("timed out before the queue was full")
+	}
+}
+
+func TestExecRecvWithin(t *testing.T) {
+	_, epB := sess.New()
+	got := sess.Exec(epB, sess.RecvWithinBind(time.Millisecond, func(n int) kont.Eff[string] {
+		return kont.Pure("received")
+	}, func(t sess.Timeout) kont.Eff[string] {
+		return kont.Pure(t.String())
+	}))
+	if got != "Recv timed out after 1ms" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestRunWaitsForTimedOperation(t *testing.T) {
+	// The client times out waiting for a greeting, then sends first:
+	// the round without progress is not a deadlock.
+	client := sess.RecvWithinBind(time.Millisecond, func(s string) kont.Eff[string] {
+		return sess.CloseDone(s)
+	}, func(sess.Timeout) kont.Eff[string] {
+		return sess.SendThen(1, sess.CloseDone("timed out"))
+	})
+	server := sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })
+	a, b, err := sess.TryRun(client, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != "timed out" || b != 1 {
+		t.Fatalf("got %q/%d", a, b)
+	}
+}
+
+func TestTryRunErrorWaitsForTimedOperation(t *testing.T) {
+	client := sess.RecvWithinBind(time.Millisecond, func(s string) kont.Eff[int] {
+		return sess.CloseDone(0)
+	}, func(sess.Timeout) kont.Eff[int] {
+		return sess.SendThen(7, sess.CloseDone(-1))
+	})
+	server := sess.RecvBind(func(n int) kont.Eff[int] { return sess.CloseDone(n) })
+	a, b, err := sess.TryRunError[string](client, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if va, _ := a.GetRight(); va != -1 {
+		t.Fatalf("client got %v, want Right(-1)", a)
+	}
+	if vb, _ := b.GetRight(); vb != 7 {
+		t.Fatalf("server got %v, want Right(7)", b)
+	}
+}
+
+func TestMonitorStaysAfterTimeout(t *testing.T) {
+	epA, epB := sess.NewMonitored(sess.SpecSend[int](sess.SpecEnd()))
+	clock := sess.NewVirtualClock(time.Unix(0, 0))
+	epB.SetClock(clock)
+
+	_, susp := sess.Step(sess.ExprRecvWithinBind(0, func(n int) kont.Expr[int] {
+		return kont.ExprReturn(n)
+	}, func(sess.Timeout) kont.Expr[int] {
+		return sess.ExprRecvBind(func(n int) kont.Expr[int] { return kont.ExprReturn(n) })
+	}))
+	_, susp, err := sess.Advance(epB, susp)
+	if err != nil || susp == nil {
+		t.Fatalf("got %v, %v, want a timeout continuing with Recv", susp, err)
+	}
+	if _, _, err := sess.Advance(epA, stepSusp(sess.ExprSendThen(3, kont.ExprReturn(struct{}{})))); err != nil {
+		t.Fatal(err)
+	}
+	// The monitor still expects the Recv that timed out.
+	if got, _, err := sess.Advance(epB, susp); err != nil || got != 3 {
+		t.Fatalf("got %d, %v, want 3", got, err)
+	}
+}
+
+func TestSchedulerTimedOperation(t *testing.T) {
+	s := sess.NewScheduler()
+	epA, epB := sess.New()
+	var got string
+	sess.Spawn(s, epB, sess.RecvWithinBind(time.Millisecond, func(n int) kont.Eff[string] {
+		return kont.Pure("received")
+	}, func(t sess.Timeout) kont.Eff[string] {
+		return sess.CloseDone(t.String())
+	}), func(r string, err error) { got = r })
+	sess.Spawn(s, epA, sess.RecvBind(func(int) kont.Eff[int] { return sess.CloseDone(0) }), nil)
+	s.RunUntilIdle()
+	if got != "Recv timed out after 1ms" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess
+
+import (
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"code.hybscloud.com/kont"
+)
+
+// TraceEvent describes an operation dispatched on an endpoint. The two
+// sides of a connection have serials of their own in their processes, but
+// report the same Session; the channels of a multiparty session share its
+// Serial and are told apart by Role and Peer.
+type TraceEvent struct {
+	Serial  Serial
+	Session uint64       // random ID shared by both sides of a connection; 0 in process
+	Role    Role         // own role on a multiparty channel; empty otherwise
+	Peer    Role         // role at the other end of a multiparty channel
+	Side    uint8        // 0 for the first endpoint returned by New or the dialing side, 1 for its peer
+	Step    int          // number of operations completed on the endpoint before this one
+	Op      OpKind       // OpSend and OpSelect* go out to the peer; OpRecv and OpOffer come in
+	Type    reflect.Type // payload type; nil for choices and Close
+	Time    time.Time    // read from the endpoint's Clock
+}
+
+// Tracer observes the operations dispatched on endpoints. OnSend, OnRecv,
+// OnSelect, OnOffer and OnClose are called once an operation of that kind
+// has completed; OnWouldBlock is called for every attempt that returned
+// iox.ErrWouldBlock. A timed operation that times out reports only its
+// blocked attempts. Methods are called synchronously on the goroutine
+// performing the operation and must not perform operations themselves.
+// An event passed by value does not escape unless the Tracer retains it,
+// so tracing adds no allocations to the Expr-world path.
+type Tracer interface {
+	OnSend(TraceEvent)
+	OnRecv(TraceEvent)
+	OnSelect(TraceEvent)
+	OnOffer(TraceEvent)
+	OnClose(TraceEvent)
+	OnWouldBlock(TraceEvent)
+}
+
+// tracerRef boxes a Tracer for atomic.Pointer.
+type tracerRef struct {
+	t Tracer
+}
+
+// globalTracer traces endpoints without a tracer of their own.
+var globalTracer atomic.Pointer[tracerRef]
+
+// SetTracer installs t as the tracer of every endpoint that has none of
+// its own (see Endpoint.SetTracer); nil disables global tracing. It is
+// safe to call concurrently with session operations.
+func SetTracer(t Tracer) {
+	if t == nil {
+		globalTracer.Store(nil)
+		return
+	}
+	globalTracer.Store(&tracerRef{t: t})
+}
+
+// SetTracer makes t trace the operations of ep instead of the global
+// tracer; nil reverts to the global tracer. It must not be called while
+// an operation on ep is pending.
+func (ep *Endpoint) SetTracer(t Tracer) {
+	ep.ctx.extend().tracer = t
+}
+
+// side returns 0 for the first endpoint of a pair or the dialing side of
+// a connection, and 1 for its peer.
+func (ctx *sessionContext) side() uint8 {
+	if ctx.closeBit == stateClosedB {
+		return 1
+	}
+	return 0
+}
+
+// tracing returns the tracer of ctx, or nil if tracing is disabled.
+func (ctx *sessionContext) tracing() Tracer {
+	if ctx.ext.tracer != nil {
+		return ctx.ext.tracer
+	}
+	if r := globalTracer.Load(); r != nil {
+		return r.t
+	}
+	return nil
+}
+
+// trace reports sop, performed on ctx with result v, to tr. A nil v with
+// blocked set reports an attempt that returned iox.ErrWouldBlock.
+func (ctx *sessionContext) trace(tr Tracer, sop sessionDispatcher, v kont.Resumed, blocked bool) {
+	if t, ok := sop.(timedOp); ok && !blocked && t.timedOut(v) {
+		return
+	}
+	kind, typ := sop.opInfo()
+	ev := TraceEvent{Serial: ctx.serial, Role: ctx.ext.role, Peer: ctx.ext.peer, Side: ctx.side(), Step: ctx.step, Op: kind, Type: typ, Time: ctx.now()}
+	if ctx.ext.wire != nil {
+		ev.Session = ctx.ext.wire.session.Load()
+	}
+	switch {
+	case blocked:
+		tr.OnWouldBlock(ev)
+	case kind == OpSend:
+		tr.OnSend(ev)
+	case kind == OpRecv:
+		tr.OnRecv(ev)
+	case kind == OpSelectL || kind == OpSelectR || kind == OpSelect:
+		tr.OnSelect(ev)
+	case kind == OpOffer:
+		tr.OnOffer(ev)
+	case kind == OpClose:
+		tr.OnClose(ev)
+	}
+}
@@ -0,0 +1,184 @@
+// ©Hayabusa Cloud Co., Ltd. 2026. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sess_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"code.hybscloud.com/iox"
+	"code.hybscloud.com/kont"
+	"code.hybscloud.com/sess"
+)
+
+// traceRecord is an event together with the Tracer method that got it.
+type traceRecord struct {
+	on string
+	ev sess.TraceEvent
+}
+
+// recordingTracer records every event.
+type recordingTracer struct {
+	records []traceRecord
+}
+
+func (r *recordingTracer) add(on string, ev sess.TraceEvent) {
+	r.records = append(r.records, traceRecord{on, ev})
+}
+
+func (r *recordingTracer) OnSend(ev sess.TraceEvent)       { r.add("send", ev) }
+func (r *recordingTracer) OnRecv(ev sess.TraceEvent)       { r.add("recv", ev) }
+func (r *recordingTracer) OnSelect(ev sess.TraceEvent)     { r.add("select", ev) }
+func (r *recordingTracer) OnOffer(ev sess.TraceEvent)      { r.add("offer", ev) }
+func (r *recordingTracer) OnClose(ev sess.TraceEvent)      { r.add("close", ev) }
+func (r *recordingTracer) OnWouldBlock(ev sess.TraceEvent) { r.add("block", ev) }
+
+func (r *recordingTracer) methods() []string {
+	var on []string
+	for _, rec := range r.records {
+		on = append(on, rec.on)
+	}
+	return on
+}
+
+// countingTracer counts events without retaining them.
+type countingTracer struct {
+	n int
+}
+
+func (c *countingTracer) OnSend(sess.TraceEvent)       { c.n++ }
+func (c *countingTracer) OnRecv(sess.TraceEvent)       { c.n++ }
+func (c *countingTracer) OnSelect(sess.TraceEvent)     { c.n++ }
+func (c *countingTracer) OnOffer(sess.TraceEvent)      { c.n++ }
+func (c *countingTracer) OnClose(sess.TraceEvent)      { c.n++ }
+func (c *countingTracer) OnWouldBlock(sess.TraceEvent) { c.n++ }
+
+func TestEndpointTracer(t *testing.T) {
+	epA, epB := sess.New()
+	clock := sess.NewVirtualClock(time.Unix(5, 0))
+	epB.SetClock(clock)
+	var tr recordingTracer
+	epB.SetTracer(&tr)
+
+	_, recv := sess.Step(sess.ExprRecvBind(func(n int) kont.Expr[int] {
+		return sess.ExprOfferBranch(
+			func() kont.Expr[int] { return sess.ExprCloseDone(n) },
+			func() kont.Expr[int] { return sess.ExprCloseDone(-n) },
+		)
+	}))
+	if _, _, err := sess.Advance(epB, recv); err != iox.ErrWouldBlock {
+		t.Fatalf("got %v, want ErrWouldBlock", err)
+	}
+	_, send := sess.Step(sess.ExprSendThen(4, sess.ExprSelectLThen(sess.ExprCloseDone(struct{}{}))))
+	for send != nil {
+		var err error
+		if _, send, err = sess.Advance(epA, send); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for recv != nil {
+		var err error
+		if _, recv, err = sess.Advance(epB, recv); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"block", "recv", "offer", "close"}
+	if got := tr.methods(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i, rec := range tr.records {
+		if rec.ev.Serial != epB.Serial() || rec.ev.Side != 1 || !rec.ev.Time.Equal(time.Unix(5, 0)) {
+			t.Fatalf("event %d: %+v", i, rec.ev)
+		}
+	}
+	if ev := tr.records[1].ev; ev.Op != sess.OpRecv || ev.Type != reflect.TypeFor[int]() || ev.Step != 0 {
+		t.Fatalf("recv event %+v", ev)
+	}
+	if ev := tr.records[3].ev; ev.Op != sess.OpClose || ev.Type != nil || ev.Step != 2 {
+		t.Fatalf("close event %+v", ev)
+	}
+}
+
+func TestGlobalTracer(t *testing.T) {
+	var global, own recordingTracer
+	sess.SetTracer(&global)
+	defer sess.SetTracer(nil)
+
+	epA, epB := sess.New()
+	epB.SetTracer(&own)
+	_, send := sess.Step(sess.ExprSendThen("hi", sess.ExprCloseDone(struct{}{})))
+	_, recv := sess.Step(sess.ExprRecvBind(func(s string) kont.Expr[string] { return sess.ExprCloseDone(s) }))
+	for send != nil {
+		_, send, _ = sess.Advance(epA, send)
+	}
+	for recv != nil {
+		_, recv, _ = sess.Advance(epB, recv)
+	}
+
+	if got, want := global.methods(), []string{"send", "close"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("global tracer got %v, want %v", got, want)
+	}
+	if got, want := own.methods(), []string{"recv", "close"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("endpoint tracer got %v, want %v", got, want)
+	}
+	if ev := global.records[0].ev; ev.Op != sess.OpSend || ev.Type != reflect.TypeFor[string]() || ev.Serial != epA.Serial() {
+		t.Fatalf("send event %+v", ev)
+	}
+
+	sess.SetTracer(nil)
+	sess.RunExpr[struct{}, string](
+		sess.ExprSendThen("again", sess.ExprCloseDone(struct{}{})),
+		sess.ExprRecvBind(func(s string) kont.Expr[string] { return sess.ExprCloseDone(s) }),
+	)
+	if len(global.records) != 2 {
+		t.Fatalf("disabled tracer got %d more events", len(global.records)-2)
+	}
+}
+
+func TestTracerTimedOut(t *testing.T) {
+	_, epB := sess.New()
+	clock := sess.NewVirtualClock(time.Unix(0, 0))
+	epB.SetClock(clock)
+	var tr recordingTracer
+	epB.SetTracer(&tr)
+
+	_, susp := sess.Step(sess.ExprRecvWithinBind(time.Second, func(n int) kont.Expr[int] {
+		return kont.ExprReturn(n)
+	}, func(sess.Timeout) kont.Expr[int] { return kont.ExprReturn(-1) }))
+	sess.Advance(epB, susp)
+	clock.Advance(time.Second)
+	if got, _, err := sess.Advance(epB, susp); err != nil || got != -1 {
+		t.Fatalf("got %d, %v, want a timeout", got, err)
+	}
+	if got, want := tr.methods(), []string{"block"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestTracerAllocationFree(t *testing.T) {
+	skipRace(t)
+	var tr countingTracer
+	sess.SetTracer(&tr)
+	defer sess.SetTracer(nil)
+	run := func() {
+		sess.RunExpr[struct{}, int](
+			sess.ExprSendThen(42, sess.ExprCloseDone(struct{}{})),
+			sess.ExprRecvBind(func(n int) kont.Expr[int] { return sess.ExprCloseDone(n) }),
+		)
+	}
+	run()
+	sess.SetTracer(nil)
+	untraced := testing.AllocsPerRun(100, run)
+	sess.SetTracer(&tr)
+	traced := testing.AllocsPerRun(100, run)
+	if traced > untraced {
+		t.Fatalf("tracing allocates: %v allocs per run, %v without", traced, untraced)
+	}
+	if tr.n == 0 {
+		t.Fatal("tracer saw no events")
+	}
+}